@@ -10,6 +10,8 @@ import (
 
 var (
 	create = auth.Permissions{Create: true}
+	read   = auth.Permissions{Read: true}
+	update = auth.Permissions{Update: true}
 	delete = auth.Permissions{Delete: true}
 )
 
@@ -18,15 +20,21 @@ type API struct {
 	Router *mux.Router
 }
 
-func Setup(ctx context.Context, r *mux.Router, permissions AuthHandler, cache Cache) *API {
+func Setup(ctx context.Context, r *mux.Router, permissions AuthHandler, cache Cache, publisher EventPublisher) *API {
 	api := &API{
 		Router: r,
 	}
 
-	r.HandleFunc("/sessions", permissions.Require(create, CreateSessionHandlerFunc(cache))).Methods("POST")
-	r.HandleFunc("/sessions/{ID}", GetByIDSessionHandlerFunc(cache, mux.Vars)).Methods("GET")
-	r.HandleFunc("/sessions/{Email}", GetByEmailSessionHandlerFunc(cache, mux.Vars)).Methods("GET")
+	r.HandleFunc("/sessions", permissions.Require(create, CreateSessionHandlerFunc(cache, publisher))).Methods("POST")
+	r.HandleFunc("/sessions/{ID}", permissions.Require(read, GetByIDSessionHandlerFunc(cache, mux.Vars))).Methods("GET")
+	r.HandleFunc("/sessions/{ID}/refresh", permissions.Require(update, RefreshSessionHandlerFunc(cache, mux.Vars))).Methods("PUT")
+	r.HandleFunc("/sessions/{ID}/attributes", permissions.Require(update, UpdateAttributesHandlerFunc(cache, mux.Vars))).Methods("PATCH")
+	r.HandleFunc("/users/{Email}/session", permissions.Require(read, GetByEmailSessionHandlerFunc(cache, mux.Vars))).Methods("GET")
+	r.HandleFunc("/users/{Email}/sessions", permissions.Require(read, ListByEmailSessionHandlerFunc(cache, mux.Vars))).Methods("GET")
 	r.HandleFunc("/sessions", permissions.Require(delete, DeleteAllSessionsHandlerFunc(cache))).Methods("DELETE")
+	r.HandleFunc("/sessions/{ID}", permissions.Require(delete, DeleteByIDSessionHandlerFunc(cache, mux.Vars))).Methods("DELETE")
+	r.HandleFunc("/users/{Email}/session", permissions.Require(delete, DeleteByEmailSessionHandlerFunc(cache, mux.Vars))).Methods("DELETE")
+	r.HandleFunc("/users/{Email}/sessions", permissions.Require(delete, RevokeByEmailSessionsHandlerFunc(cache, mux.Vars))).Methods("DELETE")
 	return api
 }
 
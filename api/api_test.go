@@ -2,8 +2,10 @@ package api_test
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/ONSdigital/dp-sessions-api/cache"
+	"github.com/ONSdigital/dp-sessions-api/events"
 
 	"net/http"
 	"net/http/httptest"
@@ -13,6 +15,7 @@ import (
 	"github.com/ONSdigital/dp-authorisation/auth"
 	"github.com/ONSdigital/dp-sessions-api/api"
 	apiMock "github.com/ONSdigital/dp-sessions-api/api/mock"
+	"github.com/ONSdigital/dp-sessions-api/session"
 	"github.com/gorilla/mux"
 	. "github.com/smartystreets/goconvey/convey"
 )
@@ -32,7 +35,121 @@ func TestSetup(t *testing.T) {
 			// Replace the check below with any newly added api endpoints
 			So(hasRoute(a.Router, "/sessions", "POST"), ShouldBeTrue)
 			So(hasRoute(a.Router, "/sessions/{id}", "GET"), ShouldBeTrue)
+			So(hasRoute(a.Router, "/sessions/{id}/refresh", "PUT"), ShouldBeTrue)
+			So(hasRoute(a.Router, "/sessions/{id}/attributes", "PATCH"), ShouldBeTrue)
+			So(hasRoute(a.Router, "/users/{email}/session", "GET"), ShouldBeTrue)
+			So(hasRoute(a.Router, "/users/{email}/sessions", "GET"), ShouldBeTrue)
 			So(hasRoute(a.Router, "/sessions", "DELETE"), ShouldBeTrue)
+			So(hasRoute(a.Router, "/sessions/{id}", "DELETE"), ShouldBeTrue)
+			So(hasRoute(a.Router, "/users/{email}/session", "DELETE"), ShouldBeTrue)
+			So(hasRoute(a.Router, "/users/{email}/sessions", "DELETE"), ShouldBeTrue)
+		})
+	})
+}
+
+func TestSetup_GetByEmailRoute(t *testing.T) {
+	Convey("Given an API instance", t, func() {
+		mockCache := &apiMock.CacheMock{
+			GetByIDFunc: func(ctx context.Context, ID string) (*session.Session, error) {
+				return &session.Session{ID: ID}, nil
+			},
+			GetByEmailFunc: func(ctx context.Context, email string) (*session.Session, error) {
+				return &session.Session{ID: "123", Email: email}, nil
+			},
+		}
+		a := api.Setup(testContext, mux.NewRouter(), &auth.NopHandler{}, mockCache, &events.InMemoryPublisher{})
+
+		Convey("When a session is requested by ID", func() {
+			resp := httptest.NewRecorder()
+			a.Router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/sessions/123", nil))
+
+			Convey("Then the request is routed to the get by ID handler", func() {
+				So(resp.Code, ShouldEqual, http.StatusOK)
+				So(mockCache.GetByIDCalls(), ShouldHaveLength, 1)
+				So(mockCache.GetByEmailCalls(), ShouldHaveLength, 0)
+			})
+		})
+
+		Convey("When a session is requested by an email containing a plus sign", func() {
+			resp := httptest.NewRecorder()
+			a.Router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/users/first+last@test.com/session", nil))
+
+			Convey("Then the plus sign is preserved", func() {
+				So(resp.Code, ShouldEqual, http.StatusOK)
+				So(mockCache.GetByEmailCalls(), ShouldHaveLength, 1)
+				So(mockCache.GetByEmailCalls()[0].Email, ShouldEqual, "first+last@test.com")
+				So(mockCache.GetByIDCalls(), ShouldHaveLength, 0)
+			})
+		})
+
+		Convey("When a session is requested by a percent-encoded email", func() {
+			resp := httptest.NewRecorder()
+			a.Router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/users/first%2Blast%25x%40test.com/session", nil))
+
+			Convey("Then the email is decoded before the cache is queried", func() {
+				So(resp.Code, ShouldEqual, http.StatusOK)
+				So(mockCache.GetByEmailCalls(), ShouldHaveLength, 1)
+				So(mockCache.GetByEmailCalls()[0].Email, ShouldEqual, "first+last%x@test.com")
+			})
+		})
+	})
+}
+
+func TestSetup_ReadPermissions(t *testing.T) {
+	Convey("Given an API instance where the caller is not authenticated", t, func() {
+		p := &apiMock.AuthHandlerMock{
+			RequireFunc: func(required auth.Permissions, handler http.HandlerFunc) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusUnauthorized)
+				}
+			},
+		}
+		mockCache := &apiMock.CacheMock{}
+		a := api.Setup(testContext, mux.NewRouter(), p, mockCache, &events.InMemoryPublisher{})
+
+		reads := []struct {
+			method string
+			path   string
+		}{
+			{http.MethodGet, "/sessions/123"},
+			{http.MethodGet, "/users/user@test.com/session"},
+			{http.MethodGet, "/users/user@test.com/sessions"},
+		}
+
+		for _, read := range reads {
+			read := read
+
+			Convey(fmt.Sprintf("When %s %s is requested", read.method, read.path), func() {
+				resp := httptest.NewRecorder()
+				a.Router.ServeHTTP(resp, httptest.NewRequest(read.method, read.path, nil))
+
+				Convey("Then the request is rejected and the cache is not queried", func() {
+					So(resp.Code, ShouldEqual, http.StatusUnauthorized)
+					So(mockCache.GetByIDCalls(), ShouldHaveLength, 0)
+					So(mockCache.GetByEmailCalls(), ShouldHaveLength, 0)
+					So(mockCache.ListByEmailCalls(), ShouldHaveLength, 0)
+				})
+			})
+		}
+
+		Convey("Then read permission is required for each read route", func() {
+			readRequired := 0
+			for _, call := range p.RequireCalls() {
+				if call.Required == (auth.Permissions{Read: true}) {
+					readRequired++
+				}
+			}
+			So(readRequired, ShouldEqual, len(reads))
+		})
+
+		Convey("Then update permission is required to refresh a session or update its attributes", func() {
+			updateRequired := 0
+			for _, call := range p.RequireCalls() {
+				if call.Required == (auth.Permissions{Update: true}) {
+					updateRequired++
+				}
+			}
+			So(updateRequired, ShouldEqual, 2)
 		})
 	})
 }
@@ -61,7 +178,7 @@ func TestClose(t *testing.T) {
 func GetAPIWithMocks(authMock api.AuthHandler, elasticacheClient *cache.ElasticacheClient) *api.API {
 	mu.Lock()
 	defer mu.Unlock()
-	return api.Setup(testContext, mux.NewRouter(), authMock, elasticacheClient)
+	return api.Setup(testContext, mux.NewRouter(), authMock, elasticacheClient, &events.InMemoryPublisher{})
 }
 
 func hasRoute(r *mux.Router, path, method string) bool {
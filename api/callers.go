@@ -0,0 +1,69 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/ONSdigital/dp-authorisation/auth"
+	"github.com/ONSdigital/dp-net/request"
+	"github.com/ONSdigital/log.go/log"
+	"github.com/pkg/errors"
+)
+
+const callerNotAllowedMsg = "caller is not permitted to read or update sessions"
+
+var callerNotAllowedErr = errors.New("caller identity is not in the allow-list")
+
+// CallerAllowList is an AuthHandler that, in addition to the permissions checked by the AuthHandler it wraps, only
+// allows the calling services it lists to use routes that require read or update permission. Updating a session's
+// attributes returns the session, and refreshing one keeps it alive, so both are as sensitive as reading it. The caller
+// is identified by the identify middleware, which must add the caller's identity to the request context.
+type CallerAllowList struct {
+	AuthHandler
+	identify func(http.Handler) http.Handler
+	callers  map[string]bool
+}
+
+// NewCallerAllowList returns an AuthHandler restricting reads and updates to callers. If callers is empty every caller
+// with the required permission is allowed and permissions is returned unchanged.
+func NewCallerAllowList(permissions AuthHandler, identify func(http.Handler) http.Handler, callers []string) AuthHandler {
+	if len(callers) == 0 {
+		return permissions
+	}
+
+	allowed := make(map[string]bool, len(callers))
+	for _, c := range callers {
+		allowed[c] = true
+	}
+
+	return &CallerAllowList{
+		AuthHandler: permissions,
+		identify:    identify,
+		callers:     allowed,
+	}
+}
+
+// Require wraps handler with the wrapped AuthHandler's permission check and, if read or update permission is required, a
+// check that the caller is in the allow-list
+func (l *CallerAllowList) Require(required auth.Permissions, handler http.HandlerFunc) http.HandlerFunc {
+	if !required.Read && !required.Update {
+		return l.AuthHandler.Require(required, handler)
+	}
+
+	return l.AuthHandler.Require(required, l.identify(l.allow(handler)).ServeHTTP)
+}
+
+// allow returns a handler that only calls handler if the caller identified in the request context is in the allow-list
+func (l *CallerAllowList) allow(handler http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		caller := request.Caller(ctx)
+
+		if !l.callers[caller] {
+			log.Event(ctx, "caller not in allow-list", log.WARN, log.Data{"caller": caller})
+			writeErrorResponse(ctx, w, callerNotAllowedMsg, callerNotAllowedErr, http.StatusForbidden)
+			return
+		}
+
+		handler.ServeHTTP(w, r)
+	})
+}
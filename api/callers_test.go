@@ -0,0 +1,109 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ONSdigital/dp-authorisation/auth"
+	"github.com/ONSdigital/dp-net/request"
+	"github.com/ONSdigital/dp-sessions-api/api"
+	apiMock "github.com/ONSdigital/dp-sessions-api/api/mock"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCallerAllowList(t *testing.T) {
+	Convey("Given an allow-list of calling services", t, func() {
+		permissions := &apiMock.AuthHandlerMock{
+			RequireFunc: func(required auth.Permissions, handler http.HandlerFunc) http.HandlerFunc {
+				return handler
+			},
+		}
+
+		caller := ""
+		identified := 0
+		identify := func(h http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				identified++
+				h.ServeHTTP(w, r.WithContext(request.SetCaller(r.Context(), caller)))
+			})
+		}
+
+		allowList := api.NewCallerAllowList(permissions, identify, []string{"dp-frontend-router", "zebedee"})
+
+		called := false
+		handler := func(w http.ResponseWriter, r *http.Request) {
+			called = true
+			w.WriteHeader(http.StatusOK)
+		}
+
+		Convey("When a caller in the allow-list reads a session", func() {
+			caller = "zebedee"
+			resp := httptest.NewRecorder()
+			allowList.Require(auth.Permissions{Read: true}, handler)(resp, httptest.NewRequest(http.MethodGet, "/sessions/123", nil))
+
+			Convey("Then the read permission is checked and the request is allowed", func() {
+				So(permissions.RequireCalls(), ShouldHaveLength, 1)
+				So(permissions.RequireCalls()[0].Required, ShouldResemble, auth.Permissions{Read: true})
+				So(identified, ShouldEqual, 1)
+				So(called, ShouldBeTrue)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+			})
+		})
+
+		Convey("When a caller not in the allow-list reads a session", func() {
+			caller = "dp-dataset-api"
+			resp := httptest.NewRecorder()
+			allowList.Require(auth.Permissions{Read: true}, handler)(resp, httptest.NewRequest(http.MethodGet, "/sessions/123", nil))
+
+			Convey("Then the request is forbidden", func() {
+				So(called, ShouldBeFalse)
+				So(resp.Code, ShouldEqual, http.StatusForbidden)
+
+				var errResp api.ErrorResponse
+				So(json.NewDecoder(resp.Body).Decode(&errResp), ShouldBeNil)
+				So(errResp.Code, ShouldEqual, api.CodeCallerNotAllowed)
+			})
+		})
+
+		Convey("When a caller not in the allow-list updates a session's attributes", func() {
+			caller = "dp-dataset-api"
+			resp := httptest.NewRecorder()
+			allowList.Require(auth.Permissions{Update: true}, handler)(resp, httptest.NewRequest(http.MethodPatch, "/sessions/123/attributes", strings.NewReader("{}")))
+
+			Convey("Then the update permission is checked and the request is forbidden", func() {
+				So(permissions.RequireCalls(), ShouldHaveLength, 1)
+				So(permissions.RequireCalls()[0].Required, ShouldResemble, auth.Permissions{Update: true})
+				So(identified, ShouldEqual, 1)
+				So(called, ShouldBeFalse)
+				So(resp.Code, ShouldEqual, http.StatusForbidden)
+			})
+		})
+
+		Convey("When a request that does not require read or update permission is made", func() {
+			caller = "dp-dataset-api"
+			resp := httptest.NewRecorder()
+			allowList.Require(auth.Permissions{Delete: true}, handler)(resp, httptest.NewRequest(http.MethodDelete, "/sessions/123", nil))
+
+			Convey("Then only the wrapped permission check applies", func() {
+				So(permissions.RequireCalls(), ShouldHaveLength, 1)
+				So(identified, ShouldEqual, 0)
+				So(called, ShouldBeTrue)
+			})
+		})
+	})
+
+	Convey("Given an empty allow-list", t, func() {
+		permissions := &apiMock.AuthHandlerMock{}
+
+		Convey("When NewCallerAllowList is called", func() {
+			allowList := api.NewCallerAllowList(permissions, nil, nil)
+
+			Convey("Then the wrapped AuthHandler is returned unchanged", func() {
+				So(allowList, ShouldEqual, permissions)
+			})
+		})
+	})
+}
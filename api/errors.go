@@ -0,0 +1,158 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/ONSdigital/dp-net/request"
+	"github.com/ONSdigital/dp-sessions-api/cache"
+	"github.com/ONSdigital/dp-sessions-api/events"
+	"github.com/ONSdigital/dp-sessions-api/session"
+	"github.com/ONSdigital/go-ns/common"
+	"github.com/ONSdigital/log.go/log"
+)
+
+// Error codes returned in the code field of an ErrorResponse. These are stable and clients may branch on them.
+const (
+	CodeInternalError      = "INTERNAL_ERROR"
+	CodeCacheUnavailable   = "CACHE_UNAVAILABLE"
+	CodeCacheTimeout       = "CACHE_TIMEOUT"
+	CodeCacheMisconfigured = "CACHE_MISCONFIGURED"
+	CodeInvalidRequestBody = "INVALID_REQUEST_BODY"
+	CodeNotFound           = "NOT_FOUND"
+	CodeSessionNotFound    = "SESSION_NOT_FOUND"
+	CodeSessionExpired     = "SESSION_EXPIRED"
+	CodeSessionIDRequired  = "SESSION_ID_REQUIRED"
+	CodeEmailRequired      = "EMAIL_REQUIRED"
+	CodeSessionRequired    = "SESSION_REQUIRED"
+	CodeTooManySessions    = "TOO_MANY_SESSIONS"
+	CodeAttributeKeyEmpty  = "ATTRIBUTE_KEY_EMPTY"
+	CodeTooManyAttributes  = "TOO_MANY_ATTRIBUTES"
+	CodeAttributesTooLarge = "ATTRIBUTES_TOO_LARGE"
+	CodeIDGenerationFailed = "ID_GENERATION_FAILED"
+	CodeCallerNotAllowed   = "CALLER_NOT_ALLOWED"
+	CodeEventPublishFailed = "EVENT_PUBLISH_FAILED"
+)
+
+// ErrorResponse is the JSON body returned for every error response
+type ErrorResponse struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// errorCodes maps the errors returned by the cache and session packages to their error code. It is ordered, and an
+// error wrapping more than one of these is given the code of the first it matches, so errors caused by a dependency
+// being unavailable come first.
+var errorCodes = []struct {
+	err  error
+	code string
+}{
+	{cache.ErrCircuitOpen, CodeCacheUnavailable},
+	{events.ErrPublishFailed, CodeEventPublishFailed},
+	{cache.ErrSessionNotFound, CodeSessionNotFound},
+	{cache.ErrSessionExpired, CodeSessionExpired},
+	{cache.ErrEmptySessionID, CodeSessionIDRequired},
+	{cache.ErrEmptySessionEmail, CodeEmailRequired},
+	{cache.ErrEmptySession, CodeSessionRequired},
+	{cache.ErrTooManySessions, CodeTooManySessions},
+	{cache.ErrEmptyAddress, CodeCacheMisconfigured},
+	{cache.ErrEmptyPassword, CodeCacheMisconfigured},
+	{cache.ErrInvalidTTL, CodeCacheMisconfigured},
+	{cache.ErrInvalidLifetime, CodeCacheMisconfigured},
+	{cache.ErrInvalidMaxSessions, CodeCacheMisconfigured},
+	{cache.ErrInvalidLimitPolicy, CodeCacheMisconfigured},
+	{session.EmailEmptyErr, CodeEmailRequired},
+	{session.StartEmptyErr, CodeInternalError},
+	{session.LastAccessedEmptyErr, CodeInternalError},
+	{session.UnknownIDFormatErr, CodeIDGenerationFailed},
+	{session.EmptyAttributeKeyErr, CodeAttributeKeyEmpty},
+	{session.TooManyAttributesErr, CodeTooManyAttributes},
+	{session.AttributesTooLargeErr, CodeAttributesTooLarge},
+	{callerNotAllowedErr, CodeCallerNotAllowed},
+}
+
+// statusCodes maps a response status to the error code used when the error has no code of its own
+var statusCodes = map[int]string{
+	http.StatusBadRequest:         CodeInvalidRequestBody,
+	http.StatusNotFound:           CodeNotFound,
+	http.StatusServiceUnavailable: CodeCacheUnavailable,
+}
+
+// errorCode returns the error code for err. Errors from the cache and session packages have a code of their own, the
+// cache not responding in time is CACHE_TIMEOUT, other network errors talking to the cache are CACHE_UNAVAILABLE and
+// anything else falls back to a code for status.
+func errorCode(err error, status int) string {
+	for _, c := range errorCodes {
+		if errors.Is(err, c.err) {
+			return c.code
+		}
+	}
+
+	if cache.IsTimeout(err) {
+		return CodeCacheTimeout
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return CodeCacheUnavailable
+	}
+
+	if code, ok := statusCodes[status]; ok {
+		return code
+	}
+	return CodeInternalError
+}
+
+// requestID returns the ID of the request in ctx, set by the request ID middleware
+func requestID(ctx context.Context) string {
+	if id := common.GetRequestId(ctx); id != "" {
+		return id
+	}
+	return request.GetRequestId(ctx)
+}
+
+// unavailableCodes are the error codes caused by a dependency being unavailable
+var unavailableCodes = map[string]bool{
+	CodeCacheUnavailable:   true,
+	CodeCacheTimeout:       true,
+	CodeEventPublishFailed: true,
+}
+
+// writeErrorResponse logs err and writes a JSON ErrorResponse with msg and the error code for err. A network error or
+// timeout talking to the cache, the cache's circuit breaker being open, or a failure to publish a session event, is
+// always reported as 503 Service Unavailable, whatever status was requested. While the circuit breaker is open the
+// Retry-After header tells clients when the cache will next be tried.
+func writeErrorResponse(ctx context.Context, w http.ResponseWriter, msg string, err error, status int) {
+	log.Event(ctx, err.Error(), log.ERROR, log.Error(err))
+
+	code := errorCode(err, status)
+	if unavailableCodes[code] {
+		status = http.StatusServiceUnavailable
+	}
+
+	body, marshalErr := json.Marshal(ErrorResponse{
+		Code:      code,
+		Message:   msg,
+		RequestID: requestID(ctx),
+	})
+	if marshalErr != nil {
+		log.Event(ctx, "failed to marshal error response", log.ERROR, log.Error(marshalErr))
+		http.Error(w, msg, status)
+		return
+	}
+
+	var circuitOpenErr *cache.CircuitOpenError
+	if errors.As(err, &circuitOpenErr) {
+		w.Header().Set("Retry-After", strconv.Itoa(circuitOpenErr.RetryAfterSeconds()))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	w.Write(body)
+}
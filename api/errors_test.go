@@ -0,0 +1,157 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ONSdigital/dp-sessions-api/api"
+	apiMock "github.com/ONSdigital/dp-sessions-api/api/mock"
+	"github.com/ONSdigital/dp-sessions-api/cache"
+	"github.com/ONSdigital/dp-sessions-api/events"
+	"github.com/ONSdigital/dp-sessions-api/session"
+	"github.com/ONSdigital/go-ns/common"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// multiError wraps several errors at once
+type multiError []error
+
+func (e multiError) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, ", ")
+}
+
+func (e multiError) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestErrorResponse(t *testing.T) {
+	Convey("Given the cache returns an error", t, func() {
+		cases := []struct {
+			err    error
+			status int
+			code   string
+		}{
+			{cache.ErrSessionNotFound, http.StatusNotFound, api.CodeSessionNotFound},
+			{cache.ErrEmptySessionID, http.StatusInternalServerError, api.CodeSessionIDRequired},
+			{fmt.Errorf("elasticache client.Get returned an unexpected error: %w", cache.ErrSessionNotFound), http.StatusInternalServerError, api.CodeSessionNotFound},
+			{&net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, http.StatusServiceUnavailable, api.CodeCacheUnavailable},
+			{context.DeadlineExceeded, http.StatusServiceUnavailable, api.CodeCacheTimeout},
+			{fmt.Errorf("elasticache client.Set returned an unexpected error: %w", context.DeadlineExceeded), http.StatusServiceUnavailable, api.CodeCacheTimeout},
+			{&cache.CircuitOpenError{RetryAfter: time.Second}, http.StatusServiceUnavailable, api.CodeCacheUnavailable},
+			{fmt.Errorf("%w: broker unavailable", events.ErrPublishFailed), http.StatusServiceUnavailable, api.CodeEventPublishFailed},
+			{multiError{cache.ErrSessionNotFound, events.ErrPublishFailed}, http.StatusServiceUnavailable, api.CodeEventPublishFailed},
+			{multiError{cache.ErrTooManySessions, cache.ErrCircuitOpen}, http.StatusServiceUnavailable, api.CodeCacheUnavailable},
+			{errors.New("unexpected error"), http.StatusInternalServerError, api.CodeInternalError},
+		}
+
+		for _, c := range cases {
+			err := c.err
+			mockCache := &apiMock.CacheMock{
+				GetByIDFunc: func(ctx context.Context, ID string) (*session.Session, error) {
+					return nil, err
+				},
+			}
+			sessionHandler := api.GetByIDSessionHandlerFunc(mockCache, getVars("ID", "123"))
+
+			req := httptest.NewRequest(http.MethodGet, "/sessions/123", nil)
+			req = req.WithContext(common.WithRequestId(context.Background(), "request-123"))
+			resp := httptest.NewRecorder()
+
+			Convey(fmt.Sprintf("When the request is received and the cache returns %q", err), func() {
+				sessionHandler.ServeHTTP(resp, req)
+
+				Convey("Then a JSON error response with the expected status and code is returned", func() {
+					So(resp.Code, ShouldEqual, c.status)
+					So(resp.Header().Get("Content-Type"), ShouldEqual, "application/json")
+
+					var errResp api.ErrorResponse
+					So(json.NewDecoder(resp.Body).Decode(&errResp), ShouldBeNil)
+					So(errResp.Code, ShouldEqual, c.code)
+					So(errResp.Message, ShouldNotBeEmpty)
+					So(errResp.RequestID, ShouldEqual, "request-123")
+				})
+			})
+		}
+	})
+
+	Convey("Given the cache's circuit breaker is open", t, func() {
+		mockCache := &apiMock.CacheMock{
+			GetByIDFunc: func(ctx context.Context, ID string) (*session.Session, error) {
+				return nil, &cache.CircuitOpenError{RetryAfter: 2500 * time.Millisecond}
+			},
+		}
+		sessionHandler := api.GetByIDSessionHandlerFunc(mockCache, getVars("ID", "123"))
+
+		req := httptest.NewRequest(http.MethodGet, "/sessions/123", nil)
+		resp := httptest.NewRecorder()
+
+		Convey("When the request is received", func() {
+			sessionHandler.ServeHTTP(resp, req)
+
+			Convey("Then a 503 response is returned telling the client when to retry", func() {
+				So(resp.Code, ShouldEqual, http.StatusServiceUnavailable)
+				So(resp.Header().Get("Retry-After"), ShouldEqual, "3")
+
+				var errResp api.ErrorResponse
+				So(json.NewDecoder(resp.Body).Decode(&errResp), ShouldBeNil)
+				So(errResp.Code, ShouldEqual, api.CodeCacheUnavailable)
+			})
+		})
+	})
+
+	Convey("Given a request to create a session without an email", t, func() {
+		sessionHandler := api.CreateSessionHandlerFunc(&apiMock.CacheMock{}, &events.InMemoryPublisher{})
+
+		req := httptest.NewRequest(http.MethodPost, "/sessions", strings.NewReader(`{"email":""}`))
+		resp := httptest.NewRecorder()
+
+		Convey("When the request is received", func() {
+			sessionHandler.ServeHTTP(resp, req)
+
+			Convey("Then the EMAIL_REQUIRED code is returned", func() {
+				So(resp.Code, ShouldEqual, http.StatusBadRequest)
+
+				var errResp api.ErrorResponse
+				So(json.NewDecoder(resp.Body).Decode(&errResp), ShouldBeNil)
+				So(errResp.Code, ShouldEqual, api.CodeEmailRequired)
+				So(errResp.RequestID, ShouldBeEmpty)
+			})
+		})
+	})
+
+	Convey("Given a request to create a session with an invalid body", t, func() {
+		sessionHandler := api.CreateSessionHandlerFunc(&apiMock.CacheMock{}, &events.InMemoryPublisher{})
+
+		req := httptest.NewRequest(http.MethodPost, "/sessions", strings.NewReader("this is not json"))
+		resp := httptest.NewRecorder()
+
+		Convey("When the request is received", func() {
+			sessionHandler.ServeHTTP(resp, req)
+
+			Convey("Then the INVALID_REQUEST_BODY code is returned", func() {
+				So(resp.Code, ShouldEqual, http.StatusBadRequest)
+
+				var errResp api.ErrorResponse
+				So(json.NewDecoder(resp.Body).Decode(&errResp), ShouldBeNil)
+				So(errResp.Code, ShouldEqual, api.CodeInvalidRequestBody)
+			})
+		})
+	})
+}
@@ -1,12 +1,12 @@
 package api
 
 import (
-	"context"
 	"encoding/json"
 	"io"
 	"net/http"
 
 	"github.com/ONSdigital/dp-sessions-api/cache"
+	"github.com/ONSdigital/dp-sessions-api/events"
 	"github.com/ONSdigital/dp-sessions-api/session"
 	"github.com/ONSdigital/log.go/log"
 	"github.com/pkg/errors"
@@ -20,39 +20,73 @@ const (
 	sessionEmailEmptyErr = "session.Email required but was empty"
 	createSessionErr     = "error creating new session"
 	addSessionToCacheErr = "error adding new session to cache"
+	tooManySessionsErr   = "user has reached the maximum number of sessions"
+	invalidAttributesErr = "invalid session attributes"
+	publishEventErr      = "error publishing session created event"
 )
 
+// SessionExpiresHeader is the response header reporting when a refreshed session will expire
+const SessionExpiresHeader = "X-Session-Expires"
+
 var (
 	sessionNilErr = errors.New("expected session object but was nil")
 )
 
+// revokedSessions is the response body returned when a user's sessions are revoked
+type revokedSessions struct {
+	Revoked int `json:"revoked"`
+}
+
 // GetVarsFunc is a helper function that returns a map of request variables and parameters
 type GetVarsFunc func(r *http.Request) map[string]string
 
-// CreateSessionHandlerFunc returns HTTP HandlerFunc for handling POST requests to create sessions.
-func CreateSessionHandlerFunc(sessionCache Cache) http.HandlerFunc {
+// CreateSessionHandlerFunc returns HTTP HandlerFunc for handling POST requests to create sessions. A created event is
+// published for each new session; if it cannot be published the session is removed again and an error returned.
+func CreateSessionHandlerFunc(sessionCache Cache, publisher EventPublisher) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 
-		email, getEmailErr := getEmailForNewSession(r.Body)
-		if getEmailErr != nil {
-			writeErrorResponse(ctx, w, sessionEmailEmptyErr, getEmailErr, http.StatusBadRequest)
+		body := http.MaxBytesReader(w, r.Body, 2*session.MaxAttributesSize)
+		details, getDetailsErr := getNewSessionDetails(body)
+		if getDetailsErr != nil {
+			writeErrorResponse(ctx, w, sessionEmailEmptyErr, getDetailsErr, http.StatusBadRequest)
 			return
 		}
 
-		s, newSessErr := session.New(email)
+		s, newSessErr := session.New(details.Email)
 		if newSessErr != nil {
 			writeErrorResponse(ctx, w, createSessionErr, newSessErr, http.StatusInternalServerError)
 			return
 		}
 
-		if cacheSessErr := sessionCache.SetSession(s); cacheSessErr != nil {
+		if attrErr := s.MergeAttributes(details.Attributes); attrErr != nil {
+			writeErrorResponse(ctx, w, invalidAttributesErr, attrErr, http.StatusBadRequest)
+			return
+		}
+
+		if cacheSessErr := sessionCache.SetSession(ctx, s); cacheSessErr != nil {
+			if cacheSessErr == cache.ErrTooManySessions {
+				writeErrorResponse(ctx, w, tooManySessionsErr, cacheSessErr, http.StatusConflict)
+				return
+			}
+
 			writeErrorResponse(ctx, w, addSessionToCacheErr, cacheSessErr, http.StatusInternalServerError)
 			return
 		}
 
 		log.Event(ctx, "session was successfully added to cache", log.INFO, log.Data{"email": s.Email})
 
+		if publishErr := publisher.Publish(ctx, events.ForSession(events.Created, s)); publishErr != nil {
+			// other services must not see a session they were never told about, so it is removed before failing. It is
+			// discarded rather than deleted as a revoked event would tell them about it.
+			if delErr := sessionCache.Discard(ctx, s.ID); delErr != nil {
+				log.Event(ctx, "failed to remove session after its created event was not published", log.ERROR, log.Error(delErr))
+			}
+
+			writeErrorResponse(ctx, w, publishEventErr, publishErr, http.StatusServiceUnavailable)
+			return
+		}
+
 		sessionJSON, marshalErr := s.MarshalJSON()
 		if marshalErr != nil {
 			writeErrorResponse(ctx, w, marshallSessionErr, marshalErr, http.StatusInternalServerError)
@@ -65,17 +99,17 @@ func CreateSessionHandlerFunc(sessionCache Cache) http.HandlerFunc {
 	}
 }
 
-func getEmailForNewSession(r io.Reader) (string, error) {
+func getNewSessionDetails(r io.Reader) (*session.NewSessionDetails, error) {
 	var details session.NewSessionDetails
 	if err := json.NewDecoder(r).Decode(&details); err != nil {
-		return "", errors.WithMessage(err, unmarshallSessionErr)
+		return nil, errors.WithMessage(err, unmarshallSessionErr)
 	}
 
 	if len(details.Email) == 0 {
-		return "", errors.New(sessionEmailEmptyErr)
+		return nil, session.EmailEmptyErr
 	}
 
-	return details.Email, nil
+	return &details, nil
 }
 
 // GetByIDSessionHandlerFunc returns a HTTP HandlerFunc that attempts to retrieve an existing session by ID from the cache
@@ -84,7 +118,7 @@ func GetByIDSessionHandlerFunc(sessionCache Cache, getVarsFunc GetVarsFunc) http
 		ctx := r.Context()
 		ID := getVarsFunc(r)["ID"]
 
-		s, getSessErr := sessionCache.GetByID(ID)
+		s, getSessErr := sessionCache.GetByID(ctx, ID)
 		if getSessErr != nil {
 			if getSessErr == cache.ErrSessionNotFound {
 				writeErrorResponse(ctx, w, sessionNotFoundErr, getSessErr, http.StatusNotFound)
@@ -118,7 +152,7 @@ func GetByEmailSessionHandlerFunc(sessionCache Cache, getVarsFunc GetVarsFunc) h
 		ctx := r.Context()
 		email := getVarsFunc(r)["Email"]
 
-		s, getSessErr := sessionCache.GetByEmail(email)
+		s, getSessErr := sessionCache.GetByEmail(ctx, email)
 		if getSessErr != nil {
 			if getSessErr == cache.ErrSessionNotFound {
 				writeErrorResponse(ctx, w, sessionNotFoundErr, getSessErr, http.StatusNotFound)
@@ -146,10 +180,174 @@ func GetByEmailSessionHandlerFunc(sessionCache Cache, getVarsFunc GetVarsFunc) h
 	}
 }
 
+// RefreshSessionHandlerFunc returns a HTTP HandlerFunc that extends the expiry of an existing session by ID without
+// returning the session itself. The new expiry time is reported in the X-Session-Expires header.
+func RefreshSessionHandlerFunc(sessionCache Cache, getVarsFunc GetVarsFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		ID := getVarsFunc(r)["ID"]
+
+		expiresAt, err := sessionCache.Refresh(ctx, ID)
+		if err != nil {
+			if err == cache.ErrSessionNotFound {
+				writeErrorResponse(ctx, w, sessionNotFoundErr, err, http.StatusNotFound)
+				return
+			}
+
+			writeErrorResponse(ctx, w, internalServerErr, err, http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set(SessionExpiresHeader, expiresAt.UTC().Format(session.DateTimeFMT))
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// UpdateAttributesHandlerFunc returns a HTTP HandlerFunc that merges the attributes in the request body into the
+// attributes of an existing session by ID. Attributes with a null value are removed.
+func UpdateAttributesHandlerFunc(updater SessionUpdater, getVarsFunc GetVarsFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		ID := getVarsFunc(r)["ID"]
+
+		var attributes map[string]interface{}
+		body := http.MaxBytesReader(w, r.Body, 2*session.MaxAttributesSize)
+		if err := json.NewDecoder(body).Decode(&attributes); err != nil {
+			writeErrorResponse(ctx, w, invalidAttributesErr, err, http.StatusBadRequest)
+			return
+		}
+
+		s, err := updater.UpdateAttributes(ctx, ID, attributes)
+		if err != nil {
+			if err == cache.ErrSessionNotFound {
+				writeErrorResponse(ctx, w, sessionNotFoundErr, err, http.StatusNotFound)
+				return
+			}
+
+			if session.IsAttributesErr(err) {
+				writeErrorResponse(ctx, w, invalidAttributesErr, err, http.StatusBadRequest)
+				return
+			}
+
+			writeErrorResponse(ctx, w, internalServerErr, err, http.StatusInternalServerError)
+			return
+		}
+
+		sessionJSON, marshalErr := json.Marshal(s)
+		if marshalErr != nil {
+			writeErrorResponse(ctx, w, marshallSessionErr, marshalErr, http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(sessionJSON)
+	}
+}
+
+// ListByEmailSessionHandlerFunc returns a HTTP HandlerFunc that retrieves all active sessions for an Email from the cache
+func ListByEmailSessionHandlerFunc(sessionCache Cache, getVarsFunc GetVarsFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		email := getVarsFunc(r)["Email"]
+
+		sessions, listErr := sessionCache.ListByEmail(ctx, email)
+		if listErr != nil {
+			writeErrorResponse(ctx, w, internalServerErr, listErr, http.StatusInternalServerError)
+			return
+		}
+
+		if sessions == nil {
+			sessions = []*session.Session{}
+		}
+
+		sessionsJSON, marshalErr := json.Marshal(sessions)
+		if marshalErr != nil {
+			writeErrorResponse(ctx, w, marshallSessionErr, marshalErr, http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(sessionsJSON)
+	}
+}
+
+// DeleteByIDSessionHandlerFunc returns a HTTP HandlerFunc that attempts to remove an existing session by ID from the cache
+func DeleteByIDSessionHandlerFunc(sessionCache Cache, getVarsFunc GetVarsFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		ID := getVarsFunc(r)["ID"]
+
+		if err := sessionCache.DeleteByID(ctx, ID); err != nil {
+			if err == cache.ErrSessionNotFound {
+				writeErrorResponse(ctx, w, sessionNotFoundErr, err, http.StatusNotFound)
+				return
+			}
+
+			writeErrorResponse(ctx, w, internalServerErr, err, http.StatusInternalServerError)
+			return
+		}
+
+		log.Event(ctx, "session deleted", log.INFO, log.Data{"id": ID})
+
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// DeleteByEmailSessionHandlerFunc returns a HTTP HandlerFunc that attempts to remove an existing session by Email from the cache
+func DeleteByEmailSessionHandlerFunc(sessionCache Cache, getVarsFunc GetVarsFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		email := getVarsFunc(r)["Email"]
+
+		if err := sessionCache.DeleteByEmail(ctx, email); err != nil {
+			if err == cache.ErrSessionNotFound {
+				writeErrorResponse(ctx, w, sessionNotFoundErr, err, http.StatusNotFound)
+				return
+			}
+
+			writeErrorResponse(ctx, w, internalServerErr, err, http.StatusInternalServerError)
+			return
+		}
+
+		log.Event(ctx, "session deleted", log.INFO, log.Data{"email": email})
+
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// RevokeByEmailSessionsHandlerFunc returns a HTTP HandlerFunc that removes every session for an Email from the cache
+// and responds with the number of sessions that were revoked
+func RevokeByEmailSessionsHandlerFunc(sessionCache Cache, getVarsFunc GetVarsFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		email := getVarsFunc(r)["Email"]
+
+		revoked, err := sessionCache.RevokeByEmail(ctx, email)
+		if err != nil {
+			writeErrorResponse(ctx, w, internalServerErr, err, http.StatusInternalServerError)
+			return
+		}
+
+		log.Event(ctx, "user sessions revoked", log.INFO, log.Data{"email": email, "revoked": revoked})
+
+		respJSON, marshalErr := json.Marshal(revokedSessions{Revoked: revoked})
+		if marshalErr != nil {
+			writeErrorResponse(ctx, w, internalServerErr, marshalErr, http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(respJSON)
+	}
+}
+
 func DeleteAllSessionsHandlerFunc(cache Cache) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
-		if err := cache.DeleteAll(); err != nil {
+		if err := cache.DeleteAll(ctx); err != nil {
 			writeErrorResponse(ctx, w, "no sessions to delete", err, http.StatusNotFound)
 			return
 		}
@@ -159,8 +357,3 @@ func DeleteAllSessionsHandlerFunc(cache Cache) http.HandlerFunc {
 		w.WriteHeader(http.StatusOK)
 	}
 }
-
-func writeErrorResponse(ctx context.Context, w http.ResponseWriter, msg string, err error, status int) {
-	log.Event(ctx, err.Error(), log.ERROR, log.Error(err))
-	http.Error(w, msg, status)
-}
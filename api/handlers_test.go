@@ -1,22 +1,26 @@
 package api_test
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 
 	"github.com/ONSdigital/dp-sessions-api/api"
 	"github.com/ONSdigital/dp-sessions-api/cache"
+	"github.com/ONSdigital/dp-sessions-api/events"
 
 	"io"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
 
 	apiMock "github.com/ONSdigital/dp-sessions-api/api/mock"
 	"github.com/ONSdigital/dp-sessions-api/session"
+	"github.com/ONSdigital/go-ns/common"
 	. "github.com/smartystreets/goconvey/convey"
 )
 
@@ -26,7 +30,7 @@ func TestCreateSessionHandlerFunc(t *testing.T) {
 	Convey("Given a valid request", t, func() {
 		mockSession := &apiMock.SessionUpdaterMock{}
 		mockCache := &apiMock.CacheMock{}
-		sessionHandler := api.CreateSessionHandlerFunc(mockCache)
+		sessionHandler := api.CreateSessionHandlerFunc(mockCache, &events.InMemoryPublisher{})
 
 		req := httptest.NewRequest(http.MethodPost, "http://localhost:24400/session", nil)
 		resp := httptest.NewRecorder()
@@ -36,18 +40,18 @@ func TestCreateSessionHandlerFunc(t *testing.T) {
 
 			Convey("Then return an error response", func() {
 				So(resp.Code, ShouldEqual, http.StatusBadRequest)
-				So(mockSession.UpdateCalls(), ShouldHaveLength, 0)
+				So(mockSession.UpdateAttributesCalls(), ShouldHaveLength, 0)
 			})
 		})
 	})
 
 	Convey("Given a valid request", t, func() {
 		mockCache := &apiMock.CacheMock{
-			SetSessionFunc: func(s *session.Session) error {
+			SetSessionFunc: func(ctx context.Context, s *session.Session) error {
 				return nil
 			},
 		}
-		sessionHandler := api.CreateSessionHandlerFunc(mockCache)
+		sessionHandler := api.CreateSessionHandlerFunc(mockCache, &events.InMemoryPublisher{})
 
 		sessJSON, err := newSessionDetailsAndMarshal("test@test.com")
 		So(err, ShouldBeNil)
@@ -77,10 +81,32 @@ func TestCreateSessionHandlerFunc(t *testing.T) {
 		})
 	})
 
+	Convey("Given a request body larger than the attributes allow", t, func() {
+		mockCache := &apiMock.CacheMock{
+			SetSessionFunc: func(ctx context.Context, s *session.Session) error {
+				return nil
+			},
+		}
+		sessionHandler := api.CreateSessionHandlerFunc(mockCache, &events.InMemoryPublisher{})
+
+		body := `{"email":"test@test.com","padding":"` + strings.Repeat("a", 2*session.MaxAttributesSize) + `"}`
+		req := httptest.NewRequest(http.MethodPost, "/session", strings.NewReader(body))
+		resp := httptest.NewRecorder()
+
+		Convey("When the request is received", func() {
+			sessionHandler.ServeHTTP(resp, req)
+
+			Convey("Then the body is not read beyond the limit and an error response is returned", func() {
+				So(resp.Code, ShouldEqual, http.StatusBadRequest)
+				So(mockCache.SetSessionCalls(), ShouldHaveLength, 0)
+			})
+		})
+	})
+
 	Convey("Given a bad request", t, func() {
 		mockSession := &apiMock.SessionUpdaterMock{}
 		mockCache := &apiMock.CacheMock{}
-		sessionHandler := api.CreateSessionHandlerFunc(mockCache)
+		sessionHandler := api.CreateSessionHandlerFunc(mockCache, &events.InMemoryPublisher{})
 
 		req := httptest.NewRequest(http.MethodPost, "/session", strings.NewReader("this is not json"))
 		resp := httptest.NewRecorder()
@@ -90,7 +116,7 @@ func TestCreateSessionHandlerFunc(t *testing.T) {
 
 			Convey("Then return an error response", func() {
 				So(resp.Code, ShouldEqual, http.StatusBadRequest)
-				So(mockSession.UpdateCalls(), ShouldHaveLength, 0)
+				So(mockSession.UpdateAttributesCalls(), ShouldHaveLength, 0)
 			})
 		})
 	})
@@ -98,7 +124,7 @@ func TestCreateSessionHandlerFunc(t *testing.T) {
 	Convey("Given a bad request", t, func() {
 		mockSession := &apiMock.SessionUpdaterMock{}
 		mockCache := &apiMock.CacheMock{}
-		sessionHandler := api.CreateSessionHandlerFunc(mockCache)
+		sessionHandler := api.CreateSessionHandlerFunc(mockCache, &events.InMemoryPublisher{})
 
 		sessJSON, err := newSessionDetailsAndMarshal("")
 		So(err, ShouldBeNil)
@@ -111,17 +137,17 @@ func TestCreateSessionHandlerFunc(t *testing.T) {
 
 			Convey("Then return an error response", func() {
 				So(resp.Code, ShouldEqual, http.StatusBadRequest)
-				So(mockSession.UpdateCalls(), ShouldHaveLength, 0)
+				So(mockSession.UpdateAttributesCalls(), ShouldHaveLength, 0)
 			})
 		})
 	})
 
 	Convey("Given a new session", t, func() {
 		mockCache := &apiMock.CacheMock{
-			SetSessionFunc: func(s *session.Session) error {
+			SetSessionFunc: func(ctx context.Context, s *session.Session) error {
 				return errors.New("unable to store session in cache")
 			}}
-		sessionHandler := api.CreateSessionHandlerFunc(mockCache)
+		sessionHandler := api.CreateSessionHandlerFunc(mockCache, &events.InMemoryPublisher{})
 
 		sessJSON, err := newSessionDetailsAndMarshal("test@test.com")
 		So(err, ShouldBeNil)
@@ -140,11 +166,11 @@ func TestCreateSessionHandlerFunc(t *testing.T) {
 
 	Convey("Given a valid request", t, func() {
 		mockCache := &apiMock.CacheMock{
-			SetSessionFunc: func(s *session.Session) error {
+			SetSessionFunc: func(ctx context.Context, s *session.Session) error {
 				return errors.New("unable to add session to cache")
 			},
 		}
-		sessionHandler := api.CreateSessionHandlerFunc(mockCache)
+		sessionHandler := api.CreateSessionHandlerFunc(mockCache, &events.InMemoryPublisher{})
 
 		sessJSON, err := newSessionDetailsAndMarshal("test@test.com")
 		So(err, ShouldBeNil)
@@ -161,6 +187,171 @@ func TestCreateSessionHandlerFunc(t *testing.T) {
 			})
 		})
 	})
+
+	Convey("Given a valid request with attributes", t, func() {
+		mockCache := &apiMock.CacheMock{
+			SetSessionFunc: func(ctx context.Context, s *session.Session) error {
+				return nil
+			},
+		}
+		sessionHandler := api.CreateSessionHandlerFunc(mockCache, &events.InMemoryPublisher{})
+
+		body := `{"email":"test@test.com","attributes":{"name":"Test User","roles":["publisher"]}}`
+		req := httptest.NewRequest(http.MethodPost, "/session", strings.NewReader(body))
+		resp := httptest.NewRecorder()
+
+		Convey("When the request is received", func() {
+			sessionHandler.ServeHTTP(resp, req)
+
+			Convey("Then the session is created with the attributes", func() {
+				So(resp.Code, ShouldEqual, http.StatusCreated)
+				So(mockCache.SetSessionCalls(), ShouldHaveLength, 1)
+				So(mockCache.SetSessionCalls()[0].S.Attributes, ShouldResemble, map[string]interface{}{
+					"name":  "Test User",
+					"roles": []interface{}{"publisher"},
+				})
+
+				sessionResp, err := unmarshalJSON(resp.Body)
+				So(err, ShouldBeNil)
+				So(sessionResp.Attributes["name"], ShouldEqual, "Test User")
+			})
+		})
+	})
+
+	Convey("Given a request with too many attributes", t, func() {
+		mockCache := &apiMock.CacheMock{}
+		sessionHandler := api.CreateSessionHandlerFunc(mockCache, &events.InMemoryPublisher{})
+
+		attributes := make(map[string]interface{})
+		for i := 0; i <= session.MaxAttributes; i++ {
+			attributes[strconv.Itoa(i)] = i
+		}
+		body, err := json.Marshal(session.NewSessionDetails{Email: "test@test.com", Attributes: attributes})
+		So(err, ShouldBeNil)
+
+		req := httptest.NewRequest(http.MethodPost, "/session", strings.NewReader(string(body)))
+		resp := httptest.NewRecorder()
+
+		Convey("When the request is received", func() {
+			sessionHandler.ServeHTTP(resp, req)
+
+			Convey("Then a bad request response is returned and no session is created", func() {
+				So(resp.Code, ShouldEqual, http.StatusBadRequest)
+				So(mockCache.SetSessionCalls(), ShouldHaveLength, 0)
+			})
+		})
+	})
+
+	Convey("Given the user already has the maximum number of sessions", t, func() {
+		mockCache := &apiMock.CacheMock{
+			SetSessionFunc: func(ctx context.Context, s *session.Session) error {
+				return cache.ErrTooManySessions
+			},
+		}
+		sessionHandler := api.CreateSessionHandlerFunc(mockCache, &events.InMemoryPublisher{})
+
+		sessJSON, err := newSessionDetailsAndMarshal("test@test.com")
+		So(err, ShouldBeNil)
+
+		req := httptest.NewRequest(http.MethodPost, "/session", strings.NewReader(string(sessJSON)))
+		resp := httptest.NewRecorder()
+
+		Convey("When the request is received", func() {
+			sessionHandler.ServeHTTP(resp, req)
+
+			Convey("Then a conflict response is returned", func() {
+				So(resp.Code, ShouldEqual, http.StatusConflict)
+				So(mockCache.SetSessionCalls(), ShouldHaveLength, 1)
+			})
+		})
+	})
+
+	Convey("Given a new session", t, func() {
+		mockCache := &apiMock.CacheMock{
+			SetSessionFunc: func(ctx context.Context, s *session.Session) error {
+				return nil
+			},
+		}
+		publisher := &events.InMemoryPublisher{}
+		sessionHandler := api.CreateSessionHandlerFunc(mockCache, publisher)
+
+		sessJSON, err := newSessionDetailsAndMarshal("test@test.com")
+		So(err, ShouldBeNil)
+
+		req := httptest.NewRequest(http.MethodPost, "/session", strings.NewReader(string(sessJSON)))
+		resp := httptest.NewRecorder()
+
+		Convey("When the request is received", func() {
+			sessionHandler.ServeHTTP(resp, req)
+
+			Convey("Then a created event is published for the session", func() {
+				So(resp.Code, ShouldEqual, http.StatusCreated)
+				So(publisher.Events(), ShouldHaveLength, 1)
+				e := publisher.Events()[0]
+				So(e.Reason, ShouldEqual, events.Created)
+				So(e.SessionID, ShouldEqual, mockCache.SetSessionCalls()[0].S.ID)
+				So(e.EmailHash, ShouldEqual, events.HashEmail("test@test.com"))
+			})
+		})
+	})
+
+	Convey("Given the created event cannot be published and publishing fails closed", t, func() {
+		mockCache := &apiMock.CacheMock{
+			SetSessionFunc: func(ctx context.Context, s *session.Session) error {
+				return nil
+			},
+			DiscardFunc: func(ctx context.Context, ID string) error {
+				return nil
+			},
+		}
+		publisher, err := events.WithFailurePolicy(&events.InMemoryPublisher{Err: errors.New("broker unavailable")}, events.FailClosed)
+		So(err, ShouldBeNil)
+		sessionHandler := api.CreateSessionHandlerFunc(mockCache, publisher)
+
+		sessJSON, err := newSessionDetailsAndMarshal("test@test.com")
+		So(err, ShouldBeNil)
+
+		req := httptest.NewRequest(http.MethodPost, "/session", strings.NewReader(string(sessJSON)))
+		resp := httptest.NewRecorder()
+
+		Convey("When the request is received", func() {
+			sessionHandler.ServeHTTP(resp, req)
+
+			Convey("Then the session is removed and a service unavailable response is returned", func() {
+				So(resp.Code, ShouldEqual, http.StatusServiceUnavailable)
+				So(resp.Body.String(), ShouldContainSubstring, api.CodeEventPublishFailed)
+				So(mockCache.DiscardCalls(), ShouldHaveLength, 1)
+				So(mockCache.DiscardCalls()[0].ID, ShouldEqual, mockCache.SetSessionCalls()[0].S.ID)
+				So(mockCache.DeleteByIDCalls(), ShouldHaveLength, 0)
+			})
+		})
+	})
+
+	Convey("Given the created event cannot be published and publishing fails open", t, func() {
+		mockCache := &apiMock.CacheMock{
+			SetSessionFunc: func(ctx context.Context, s *session.Session) error {
+				return nil
+			},
+		}
+		publisher, err := events.WithFailurePolicy(&events.InMemoryPublisher{Err: errors.New("broker unavailable")}, events.FailOpen)
+		So(err, ShouldBeNil)
+		sessionHandler := api.CreateSessionHandlerFunc(mockCache, publisher)
+
+		sessJSON, err := newSessionDetailsAndMarshal("test@test.com")
+		So(err, ShouldBeNil)
+
+		req := httptest.NewRequest(http.MethodPost, "/session", strings.NewReader(string(sessJSON)))
+		resp := httptest.NewRecorder()
+
+		Convey("When the request is received", func() {
+			sessionHandler.ServeHTTP(resp, req)
+
+			Convey("Then the session is created", func() {
+				So(resp.Code, ShouldEqual, http.StatusCreated)
+				So(mockCache.DiscardCalls(), ShouldHaveLength, 0)
+			})
+		})
+	})
 }
 
 func TestGetByIDSessionHandlerFunc(t *testing.T) {
@@ -169,7 +360,7 @@ func TestGetByIDSessionHandlerFunc(t *testing.T) {
 		sessionID := "123"
 
 		mockCache := &apiMock.CacheMock{
-			GetByIDFunc: func(id string) (*session.Session, error) {
+			GetByIDFunc: func(ctx context.Context, id string) (*session.Session, error) {
 				return &session.Session{
 					ID:    id,
 					Email: "test@email.com",
@@ -186,6 +377,7 @@ func TestGetByIDSessionHandlerFunc(t *testing.T) {
 		sessionHandler := api.GetByIDSessionHandlerFunc(mockCache, getVars)
 
 		req := httptest.NewRequest(http.MethodGet, "/session/123", nil)
+		req = req.WithContext(common.WithRequestId(req.Context(), "request-123"))
 		resp := httptest.NewRecorder()
 
 		Convey("When the request is received", func() {
@@ -195,6 +387,7 @@ func TestGetByIDSessionHandlerFunc(t *testing.T) {
 				So(resp.Code, ShouldEqual, http.StatusOK)
 				So(mockCache.GetByIDCalls(), ShouldHaveLength, 1)
 				So(mockCache.GetByIDCalls()[0].ID, ShouldEqual, "123")
+				So(common.GetRequestId(mockCache.GetByIDCalls()[0].Ctx), ShouldEqual, "request-123")
 
 				var actual session.Session
 				err := json.Unmarshal(resp.Body.Bytes(), &actual)
@@ -208,7 +401,7 @@ func TestGetByIDSessionHandlerFunc(t *testing.T) {
 
 	Convey("Given a session does not exist for the provided ID", t, func() {
 		mockCache := &apiMock.CacheMock{
-			GetByIDFunc: func(ID string) (*session.Session, error) {
+			GetByIDFunc: func(ctx context.Context, ID string) (*session.Session, error) {
 				return nil, cache.ErrSessionNotFound
 			},
 		}
@@ -231,7 +424,7 @@ func TestGetByIDSessionHandlerFunc(t *testing.T) {
 
 	Convey("Given sessionCache.GetByID returns a nil session", t, func() {
 		mockCache := &apiMock.CacheMock{
-			GetByIDFunc: func(ID string) (*session.Session, error) {
+			GetByIDFunc: func(ctx context.Context, ID string) (*session.Session, error) {
 				return nil, nil
 			},
 		}
@@ -254,7 +447,7 @@ func TestGetByIDSessionHandlerFunc(t *testing.T) {
 
 	Convey("Given sessionCache.GetByID returns any other error", t, func() {
 		mockCache := &apiMock.CacheMock{
-			GetByIDFunc: func(ID string) (*session.Session, error) {
+			GetByIDFunc: func(ctx context.Context, ID string) (*session.Session, error) {
 				return nil, errors.New("unexpected error")
 			},
 		}
@@ -274,6 +467,34 @@ func TestGetByIDSessionHandlerFunc(t *testing.T) {
 			})
 		})
 	})
+
+	Convey("Given the cache does not respond before the request's deadline", t, func() {
+		mockCache := &apiMock.CacheMock{
+			GetByIDFunc: func(ctx context.Context, ID string) (*session.Session, error) {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			},
+		}
+
+		sessionHandler := api.GetByIDSessionHandlerFunc(mockCache, getVars("ID", "123"))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		req := httptest.NewRequest(http.MethodGet, "/session/123", nil).WithContext(ctx)
+		resp := httptest.NewRecorder()
+
+		Convey("When the request is received", func() {
+			sessionHandler.ServeHTTP(resp, req)
+
+			Convey("Then the handler gives up and a 503 response with the CACHE_TIMEOUT code is returned", func() {
+				So(resp.Code, ShouldEqual, http.StatusServiceUnavailable)
+
+				var errResp api.ErrorResponse
+				So(json.NewDecoder(resp.Body).Decode(&errResp), ShouldBeNil)
+				So(errResp.Code, ShouldEqual, api.CodeCacheTimeout)
+			})
+		})
+	})
 }
 
 func TestGetByEmailSessionHandlerFunc(t *testing.T) {
@@ -281,7 +502,7 @@ func TestGetByEmailSessionHandlerFunc(t *testing.T) {
 		sessionEmail := "user@test.com"
 		currentTime := time.Now()
 		mockCache := &apiMock.CacheMock{
-			GetByEmailFunc: func(email string) (*session.Session, error) {
+			GetByEmailFunc: func(ctx context.Context, email string) (*session.Session, error) {
 				return &session.Session{
 					ID:    "123",
 					Email: email,
@@ -314,7 +535,7 @@ func TestGetByEmailSessionHandlerFunc(t *testing.T) {
 
 	Convey("Given a session does not exist for the provided email", t, func() {
 		mockCache := &apiMock.CacheMock{
-			GetByEmailFunc: func(email string) (*session.Session, error) {
+			GetByEmailFunc: func(ctx context.Context, email string) (*session.Session, error) {
 				return nil, cache.ErrSessionNotFound
 			},
 		}
@@ -337,7 +558,7 @@ func TestGetByEmailSessionHandlerFunc(t *testing.T) {
 
 	Convey("Given sessionCache.GetByEmail returns a nil session", t, func() {
 		mockCache := &apiMock.CacheMock{
-			GetByEmailFunc: func(email string) (*session.Session, error) {
+			GetByEmailFunc: func(ctx context.Context, email string) (*session.Session, error) {
 				return nil, nil
 			},
 		}
@@ -360,7 +581,7 @@ func TestGetByEmailSessionHandlerFunc(t *testing.T) {
 
 	Convey("Given a valid request", t, func() {
 		mockCache := &apiMock.CacheMock{
-			GetByEmailFunc: func(email string) (*session.Session, error) {
+			GetByEmailFunc: func(ctx context.Context, email string) (*session.Session, error) {
 				return nil, errors.New("unexpected error")
 			},
 		}
@@ -382,9 +603,491 @@ func TestGetByEmailSessionHandlerFunc(t *testing.T) {
 	})
 }
 
+func TestRefreshSessionHandlerFunc(t *testing.T) {
+	Convey("Given a session exists for the ID", t, func() {
+		expiresAt := time.Date(2020, 6, 1, 12, 30, 0, 0, time.UTC)
+		mockCache := &apiMock.CacheMock{
+			RefreshFunc: func(ctx context.Context, ID string) (time.Time, error) {
+				return expiresAt, nil
+			},
+		}
+
+		sessionHandler := api.RefreshSessionHandlerFunc(mockCache, getVars("ID", "123"))
+
+		req := httptest.NewRequest(http.MethodPut, "/sessions/123/refresh", nil)
+		resp := httptest.NewRecorder()
+
+		Convey("When the request is received", func() {
+			sessionHandler.ServeHTTP(resp, req)
+
+			Convey("Then the session is refreshed and no content is returned", func() {
+				So(resp.Code, ShouldEqual, http.StatusNoContent)
+				So(resp.Body.Len(), ShouldEqual, 0)
+				So(mockCache.RefreshCalls(), ShouldHaveLength, 1)
+				So(mockCache.RefreshCalls()[0].ID, ShouldEqual, "123")
+			})
+
+			Convey("And the new expiry time is returned in the response header", func() {
+				So(resp.Header().Get(api.SessionExpiresHeader), ShouldEqual, "2020-06-01T12:30:00.000Z")
+			})
+		})
+	})
+
+	Convey("Given the session does not exist or has expired", t, func() {
+		mockCache := &apiMock.CacheMock{
+			RefreshFunc: func(ctx context.Context, ID string) (time.Time, error) {
+				return time.Time{}, cache.ErrSessionNotFound
+			},
+		}
+
+		sessionHandler := api.RefreshSessionHandlerFunc(mockCache, getVars("ID", "123"))
+
+		req := httptest.NewRequest(http.MethodPut, "/sessions/123/refresh", nil)
+		resp := httptest.NewRecorder()
+
+		Convey("When the request is received", func() {
+			sessionHandler.ServeHTTP(resp, req)
+
+			Convey("Then a not found error response is returned", func() {
+				So(resp.Code, ShouldEqual, http.StatusNotFound)
+				So(resp.Header().Get(api.SessionExpiresHeader), ShouldBeEmpty)
+			})
+		})
+	})
+
+	Convey("Given sessionCache.Refresh returns an error", t, func() {
+		mockCache := &apiMock.CacheMock{
+			RefreshFunc: func(ctx context.Context, ID string) (time.Time, error) {
+				return time.Time{}, errors.New("unexpected error")
+			},
+		}
+
+		sessionHandler := api.RefreshSessionHandlerFunc(mockCache, getVars("ID", "123"))
+
+		req := httptest.NewRequest(http.MethodPut, "/sessions/123/refresh", nil)
+		resp := httptest.NewRecorder()
+
+		Convey("When the request is received", func() {
+			sessionHandler.ServeHTTP(resp, req)
+
+			Convey("Then an internal server error response is returned", func() {
+				So(resp.Code, ShouldEqual, http.StatusInternalServerError)
+			})
+		})
+	})
+}
+
+func TestUpdateAttributesHandlerFunc(t *testing.T) {
+	Convey("Given a session exists for the ID", t, func() {
+		currentTime := time.Now()
+		mockSession := &apiMock.SessionUpdaterMock{
+			UpdateAttributesFunc: func(ctx context.Context, ID string, attributes map[string]interface{}) (*session.Session, error) {
+				return &session.Session{
+					ID:           ID,
+					Email:        "user@test.com",
+					Start:        currentTime,
+					LastAccessed: currentTime,
+					Attributes:   map[string]interface{}{"name": "Test User", "collection": "abc"},
+				}, nil
+			},
+		}
+
+		sessionHandler := api.UpdateAttributesHandlerFunc(mockSession, getVars("ID", "123"))
+
+		req := httptest.NewRequest(http.MethodPatch, "/sessions/123/attributes", strings.NewReader(`{"collection":"abc","csrf":null}`))
+		resp := httptest.NewRecorder()
+
+		Convey("When the request is received", func() {
+			sessionHandler.ServeHTTP(resp, req)
+
+			Convey("Then the attributes are merged into the session", func() {
+				So(mockSession.UpdateAttributesCalls(), ShouldHaveLength, 1)
+				So(mockSession.UpdateAttributesCalls()[0].ID, ShouldEqual, "123")
+				So(mockSession.UpdateAttributesCalls()[0].Attributes, ShouldResemble, map[string]interface{}{
+					"collection": "abc",
+					"csrf":       nil,
+				})
+			})
+
+			Convey("And the updated session is returned", func() {
+				So(resp.Code, ShouldEqual, http.StatusOK)
+
+				sessionResp, err := unmarshalJSON(resp.Body)
+				So(err, ShouldBeNil)
+				So(sessionResp.ID, ShouldEqual, "123")
+				So(sessionResp.Attributes, ShouldResemble, map[string]interface{}{"name": "Test User", "collection": "abc"})
+			})
+		})
+	})
+
+	Convey("Given the request body is not a JSON object", t, func() {
+		mockSession := &apiMock.SessionUpdaterMock{}
+		sessionHandler := api.UpdateAttributesHandlerFunc(mockSession, getVars("ID", "123"))
+
+		req := httptest.NewRequest(http.MethodPatch, "/sessions/123/attributes", strings.NewReader(`["not", "an", "object"]`))
+		resp := httptest.NewRecorder()
+
+		Convey("When the request is received", func() {
+			sessionHandler.ServeHTTP(resp, req)
+
+			Convey("Then a bad request response is returned", func() {
+				So(resp.Code, ShouldEqual, http.StatusBadRequest)
+				So(mockSession.UpdateAttributesCalls(), ShouldHaveLength, 0)
+			})
+		})
+	})
+
+	Convey("Given the merged attributes are invalid", t, func() {
+		mockSession := &apiMock.SessionUpdaterMock{
+			UpdateAttributesFunc: func(ctx context.Context, ID string, attributes map[string]interface{}) (*session.Session, error) {
+				return nil, session.AttributesTooLargeErr
+			},
+		}
+		sessionHandler := api.UpdateAttributesHandlerFunc(mockSession, getVars("ID", "123"))
+
+		req := httptest.NewRequest(http.MethodPatch, "/sessions/123/attributes", strings.NewReader(`{"csrf":"token"}`))
+		resp := httptest.NewRecorder()
+
+		Convey("When the request is received", func() {
+			sessionHandler.ServeHTTP(resp, req)
+
+			Convey("Then a bad request response is returned", func() {
+				So(resp.Code, ShouldEqual, http.StatusBadRequest)
+				So(mockSession.UpdateAttributesCalls(), ShouldHaveLength, 1)
+			})
+		})
+	})
+
+	Convey("Given the session does not exist", t, func() {
+		mockSession := &apiMock.SessionUpdaterMock{
+			UpdateAttributesFunc: func(ctx context.Context, ID string, attributes map[string]interface{}) (*session.Session, error) {
+				return nil, cache.ErrSessionNotFound
+			},
+		}
+		sessionHandler := api.UpdateAttributesHandlerFunc(mockSession, getVars("ID", "123"))
+
+		req := httptest.NewRequest(http.MethodPatch, "/sessions/123/attributes", strings.NewReader(`{"csrf":"token"}`))
+		resp := httptest.NewRecorder()
+
+		Convey("When the request is received", func() {
+			sessionHandler.ServeHTTP(resp, req)
+
+			Convey("Then a not found response is returned", func() {
+				So(resp.Code, ShouldEqual, http.StatusNotFound)
+			})
+		})
+	})
+
+	Convey("Given updater.UpdateAttributes returns an error", t, func() {
+		mockSession := &apiMock.SessionUpdaterMock{
+			UpdateAttributesFunc: func(ctx context.Context, ID string, attributes map[string]interface{}) (*session.Session, error) {
+				return nil, errors.New("unexpected error")
+			},
+		}
+		sessionHandler := api.UpdateAttributesHandlerFunc(mockSession, getVars("ID", "123"))
+
+		req := httptest.NewRequest(http.MethodPatch, "/sessions/123/attributes", strings.NewReader(`{"csrf":"token"}`))
+		resp := httptest.NewRecorder()
+
+		Convey("When the request is received", func() {
+			sessionHandler.ServeHTTP(resp, req)
+
+			Convey("Then an internal server error response is returned", func() {
+				So(resp.Code, ShouldEqual, http.StatusInternalServerError)
+			})
+		})
+	})
+}
+
+func TestListByEmailSessionHandlerFunc(t *testing.T) {
+	Convey("Given the user has several sessions", t, func() {
+		currentTime := time.Now()
+		mockCache := &apiMock.CacheMock{
+			ListByEmailFunc: func(ctx context.Context, email string) ([]*session.Session, error) {
+				return []*session.Session{
+					{ID: "123", Email: email, Start: currentTime, LastAccessed: currentTime},
+					{ID: "456", Email: email, Start: currentTime, LastAccessed: currentTime},
+				}, nil
+			},
+		}
+
+		sessionHandler := api.ListByEmailSessionHandlerFunc(mockCache, getVars("Email", "user@test.com"))
+
+		req := httptest.NewRequest(http.MethodGet, "/users/user@test.com/sessions", nil)
+		resp := httptest.NewRecorder()
+
+		Convey("When the request is received", func() {
+			sessionHandler.ServeHTTP(resp, req)
+
+			Convey("Then the sessions are returned", func() {
+				So(resp.Code, ShouldEqual, http.StatusOK)
+				So(mockCache.ListByEmailCalls(), ShouldHaveLength, 1)
+				So(mockCache.ListByEmailCalls()[0].Email, ShouldEqual, "user@test.com")
+
+				var sessions []*session.Session
+				So(json.NewDecoder(resp.Body).Decode(&sessions), ShouldBeNil)
+				So(sessions, ShouldHaveLength, 2)
+				So(sessions[0].ID, ShouldEqual, "123")
+				So(sessions[1].ID, ShouldEqual, "456")
+			})
+		})
+	})
+
+	Convey("Given the user has no sessions", t, func() {
+		mockCache := &apiMock.CacheMock{
+			ListByEmailFunc: func(ctx context.Context, email string) ([]*session.Session, error) {
+				return nil, nil
+			},
+		}
+
+		sessionHandler := api.ListByEmailSessionHandlerFunc(mockCache, getVars("Email", "user@test.com"))
+
+		req := httptest.NewRequest(http.MethodGet, "/users/user@test.com/sessions", nil)
+		resp := httptest.NewRecorder()
+
+		Convey("When the request is received", func() {
+			sessionHandler.ServeHTTP(resp, req)
+
+			Convey("Then an empty list is returned", func() {
+				So(resp.Code, ShouldEqual, http.StatusOK)
+				So(resp.Body.String(), ShouldEqual, "[]")
+			})
+		})
+	})
+
+	Convey("Given sessionCache.ListByEmail returns an error", t, func() {
+		mockCache := &apiMock.CacheMock{
+			ListByEmailFunc: func(ctx context.Context, email string) ([]*session.Session, error) {
+				return nil, errors.New("unexpected error")
+			},
+		}
+
+		sessionHandler := api.ListByEmailSessionHandlerFunc(mockCache, getVars("Email", "user@test.com"))
+
+		req := httptest.NewRequest(http.MethodGet, "/users/user@test.com/sessions", nil)
+		resp := httptest.NewRecorder()
+
+		Convey("When the request is received", func() {
+			sessionHandler.ServeHTTP(resp, req)
+
+			Convey("Then an internal server error response is returned", func() {
+				So(resp.Code, ShouldEqual, http.StatusInternalServerError)
+				So(mockCache.ListByEmailCalls(), ShouldHaveLength, 1)
+			})
+		})
+	})
+}
+
+func TestDeleteByIDSessionHandlerFunc(t *testing.T) {
+	Convey("Given a session exists for the provided ID", t, func() {
+		mockCache := &apiMock.CacheMock{
+			DeleteByIDFunc: func(ctx context.Context, ID string) error {
+				return nil
+			},
+		}
+
+		sessionHandler := api.DeleteByIDSessionHandlerFunc(mockCache, getVars("ID", "123"))
+
+		req := httptest.NewRequest(http.MethodDelete, "/sessions/123", nil)
+		resp := httptest.NewRecorder()
+
+		Convey("When the request is received", func() {
+			sessionHandler.ServeHTTP(resp, req)
+
+			Convey("Then the session is deleted and a success response is returned", func() {
+				So(resp.Code, ShouldEqual, http.StatusOK)
+				So(mockCache.DeleteByIDCalls(), ShouldHaveLength, 1)
+				So(mockCache.DeleteByIDCalls()[0].ID, ShouldEqual, "123")
+			})
+		})
+	})
+
+	Convey("Given a session does not exist for the provided ID", t, func() {
+		mockCache := &apiMock.CacheMock{
+			DeleteByIDFunc: func(ctx context.Context, ID string) error {
+				return cache.ErrSessionNotFound
+			},
+		}
+
+		sessionHandler := api.DeleteByIDSessionHandlerFunc(mockCache, getVars("ID", "123"))
+
+		req := httptest.NewRequest(http.MethodDelete, "/sessions/123", nil)
+		resp := httptest.NewRecorder()
+
+		Convey("When the request is received", func() {
+			sessionHandler.ServeHTTP(resp, req)
+
+			Convey("Then a not found error response is returned", func() {
+				So(resp.Code, ShouldEqual, http.StatusNotFound)
+				So(mockCache.DeleteByIDCalls(), ShouldHaveLength, 1)
+			})
+		})
+	})
+
+	Convey("Given sessionCache.DeleteByID returns any other error", t, func() {
+		mockCache := &apiMock.CacheMock{
+			DeleteByIDFunc: func(ctx context.Context, ID string) error {
+				return errors.New("unexpected error")
+			},
+		}
+
+		sessionHandler := api.DeleteByIDSessionHandlerFunc(mockCache, getVars("ID", "123"))
+
+		req := httptest.NewRequest(http.MethodDelete, "/sessions/123", nil)
+		resp := httptest.NewRecorder()
+
+		Convey("When the request is received", func() {
+			sessionHandler.ServeHTTP(resp, req)
+
+			Convey("Then an internal server error response is returned", func() {
+				So(resp.Code, ShouldEqual, http.StatusInternalServerError)
+				So(mockCache.DeleteByIDCalls(), ShouldHaveLength, 1)
+			})
+		})
+	})
+}
+
+func TestDeleteByEmailSessionHandlerFunc(t *testing.T) {
+	Convey("Given a session exists for the provided email", t, func() {
+		mockCache := &apiMock.CacheMock{
+			DeleteByEmailFunc: func(ctx context.Context, email string) error {
+				return nil
+			},
+		}
+
+		sessionHandler := api.DeleteByEmailSessionHandlerFunc(mockCache, getVars("Email", "user@test.com"))
+
+		req := httptest.NewRequest(http.MethodDelete, "/users/user@test.com/session", nil)
+		resp := httptest.NewRecorder()
+
+		Convey("When the request is received", func() {
+			sessionHandler.ServeHTTP(resp, req)
+
+			Convey("Then the session is deleted and a success response is returned", func() {
+				So(resp.Code, ShouldEqual, http.StatusOK)
+				So(mockCache.DeleteByEmailCalls(), ShouldHaveLength, 1)
+				So(mockCache.DeleteByEmailCalls()[0].Email, ShouldEqual, "user@test.com")
+			})
+		})
+	})
+
+	Convey("Given a session does not exist for the provided email", t, func() {
+		mockCache := &apiMock.CacheMock{
+			DeleteByEmailFunc: func(ctx context.Context, email string) error {
+				return cache.ErrSessionNotFound
+			},
+		}
+
+		sessionHandler := api.DeleteByEmailSessionHandlerFunc(mockCache, getVars("Email", "user@test.com"))
+
+		req := httptest.NewRequest(http.MethodDelete, "/users/user@test.com/session", nil)
+		resp := httptest.NewRecorder()
+
+		Convey("When the request is received", func() {
+			sessionHandler.ServeHTTP(resp, req)
+
+			Convey("Then a not found error response is returned", func() {
+				So(resp.Code, ShouldEqual, http.StatusNotFound)
+				So(mockCache.DeleteByEmailCalls(), ShouldHaveLength, 1)
+			})
+		})
+	})
+
+	Convey("Given sessionCache.DeleteByEmail returns any other error", t, func() {
+		mockCache := &apiMock.CacheMock{
+			DeleteByEmailFunc: func(ctx context.Context, email string) error {
+				return errors.New("unexpected error")
+			},
+		}
+
+		sessionHandler := api.DeleteByEmailSessionHandlerFunc(mockCache, getVars("Email", "user@test.com"))
+
+		req := httptest.NewRequest(http.MethodDelete, "/users/user@test.com/session", nil)
+		resp := httptest.NewRecorder()
+
+		Convey("When the request is received", func() {
+			sessionHandler.ServeHTTP(resp, req)
+
+			Convey("Then an internal server error response is returned", func() {
+				So(resp.Code, ShouldEqual, http.StatusInternalServerError)
+				So(mockCache.DeleteByEmailCalls(), ShouldHaveLength, 1)
+			})
+		})
+	})
+}
+
+func TestRevokeByEmailSessionsHandlerFunc(t *testing.T) {
+	Convey("Given the user has several sessions", t, func() {
+		mockCache := &apiMock.CacheMock{
+			RevokeByEmailFunc: func(ctx context.Context, email string) (int, error) {
+				return 3, nil
+			},
+		}
+
+		sessionHandler := api.RevokeByEmailSessionsHandlerFunc(mockCache, getVars("Email", "user@test.com"))
+
+		req := httptest.NewRequest(http.MethodDelete, "/users/user@test.com/sessions", nil)
+		resp := httptest.NewRecorder()
+
+		Convey("When the request is received", func() {
+			sessionHandler.ServeHTTP(resp, req)
+
+			Convey("Then the user's sessions are revoked and the number revoked is returned", func() {
+				So(resp.Code, ShouldEqual, http.StatusOK)
+				So(mockCache.RevokeByEmailCalls(), ShouldHaveLength, 1)
+				So(mockCache.RevokeByEmailCalls()[0].Email, ShouldEqual, "user@test.com")
+				So(resp.Body.String(), ShouldEqual, `{"revoked":3}`)
+			})
+		})
+	})
+
+	Convey("Given the user has no sessions", t, func() {
+		mockCache := &apiMock.CacheMock{
+			RevokeByEmailFunc: func(ctx context.Context, email string) (int, error) {
+				return 0, nil
+			},
+		}
+
+		sessionHandler := api.RevokeByEmailSessionsHandlerFunc(mockCache, getVars("Email", "user@test.com"))
+
+		req := httptest.NewRequest(http.MethodDelete, "/users/user@test.com/sessions", nil)
+		resp := httptest.NewRecorder()
+
+		Convey("When the request is received", func() {
+			sessionHandler.ServeHTTP(resp, req)
+
+			Convey("Then a success response is returned with a count of zero", func() {
+				So(resp.Code, ShouldEqual, http.StatusOK)
+				So(resp.Body.String(), ShouldEqual, `{"revoked":0}`)
+			})
+		})
+	})
+
+	Convey("Given sessionCache.RevokeByEmail returns an error", t, func() {
+		mockCache := &apiMock.CacheMock{
+			RevokeByEmailFunc: func(ctx context.Context, email string) (int, error) {
+				return 0, errors.New("unexpected error")
+			},
+		}
+
+		sessionHandler := api.RevokeByEmailSessionsHandlerFunc(mockCache, getVars("Email", "user@test.com"))
+
+		req := httptest.NewRequest(http.MethodDelete, "/users/user@test.com/sessions", nil)
+		resp := httptest.NewRecorder()
+
+		Convey("When the request is received", func() {
+			sessionHandler.ServeHTTP(resp, req)
+
+			Convey("Then an internal server error response is returned", func() {
+				So(resp.Code, ShouldEqual, http.StatusInternalServerError)
+			})
+		})
+	})
+}
+
 func TestDeleteAllSessionsHandlerFunc(t *testing.T) {
 	Convey("Give a valid request", t, func() {
-		mockCache := &apiMock.CacheMock{DeleteAllFunc: func() error {
+		mockCache := &apiMock.CacheMock{DeleteAllFunc: func(ctx context.Context) error {
 			return nil
 		}}
 
@@ -404,7 +1107,7 @@ func TestDeleteAllSessionsHandlerFunc(t *testing.T) {
 	})
 
 	Convey("Give a valid request", t, func() {
-		mockCache := &apiMock.CacheMock{DeleteAllFunc: func() error {
+		mockCache := &apiMock.CacheMock{DeleteAllFunc: func(ctx context.Context) error {
 			return errors.New("no sessions to delete")
 		}}
 
@@ -5,10 +5,12 @@ package api
 //go:generate moq -out mock/mockcache.go -pkg mock . Cache
 
 import (
+	"context"
 	"net/http"
 
 	"github.com/ONSdigital/dp-authorisation/auth"
 	"github.com/ONSdigital/dp-sessions-api/cache"
+	"github.com/ONSdigital/dp-sessions-api/events"
 	"github.com/ONSdigital/dp-sessions-api/session"
 )
 
@@ -19,15 +21,11 @@ type AuthHandler interface {
 
 // SessionUpdater interface for updating a session
 type SessionUpdater interface {
-	Update(email string) (*session.Session, error)
+	UpdateAttributes(ctx context.Context, ID string, attributes map[string]interface{}) (*session.Session, error)
 }
 
+// Cache interface for storing and retrieving sessions
 type Cache cache.SessionCache
 
-// Cache interface for storing and retrieving sessions
-/*type Cache interface {
-	SetSession(s *session.Session) error
-	GetByID(ID string) (*session.Session, error)
-	GetByEmail(email string) (*session.Session, error)
-	DeleteAll() error
-}*/
+// EventPublisher interface for publishing session lifecycle events
+type EventPublisher events.EventPublisher
@@ -4,16 +4,25 @@
 package mock
 
 import (
+	"context"
 	"github.com/ONSdigital/dp-sessions-api/api"
 	"github.com/ONSdigital/dp-sessions-api/session"
 	"sync"
+	"time"
 )
 
 var (
-	lockCacheMockDeleteAll  sync.RWMutex
-	lockCacheMockGetByEmail sync.RWMutex
-	lockCacheMockGetByID    sync.RWMutex
-	lockCacheMockSetSession sync.RWMutex
+	lockCacheMockDeleteAll        sync.RWMutex
+	lockCacheMockDeleteByEmail    sync.RWMutex
+	lockCacheMockDeleteByID       sync.RWMutex
+	lockCacheMockDiscard          sync.RWMutex
+	lockCacheMockGetByEmail       sync.RWMutex
+	lockCacheMockGetByID          sync.RWMutex
+	lockCacheMockListByEmail      sync.RWMutex
+	lockCacheMockRefresh          sync.RWMutex
+	lockCacheMockRevokeByEmail    sync.RWMutex
+	lockCacheMockSetSession       sync.RWMutex
+	lockCacheMockUpdateAttributes sync.RWMutex
 )
 
 // Ensure, that CacheMock does implement Cache.
@@ -26,18 +35,39 @@ var _ api.Cache = &CacheMock{}
 //
 //         // make and configure a mocked api.Cache
 //         mockedCache := &CacheMock{
-//             DeleteAllFunc: func() error {
+//             DeleteAllFunc: func(ctx context.Context) error {
 // 	               panic("mock out the DeleteAll method")
 //             },
-//             GetByEmailFunc: func(email string) (*session.Session, error) {
+//             DeleteByEmailFunc: func(ctx context.Context, email string) error {
+// 	               panic("mock out the DeleteByEmail method")
+//             },
+//             DeleteByIDFunc: func(ctx context.Context, ID string) error {
+// 	               panic("mock out the DeleteByID method")
+//             },
+//             DiscardFunc: func(ctx context.Context, ID string) error {
+// 	               panic("mock out the Discard method")
+//             },
+//             GetByEmailFunc: func(ctx context.Context, email string) (*session.Session, error) {
 // 	               panic("mock out the GetByEmail method")
 //             },
-//             GetByIDFunc: func(ID string) (*session.Session, error) {
+//             GetByIDFunc: func(ctx context.Context, ID string) (*session.Session, error) {
 // 	               panic("mock out the GetByID method")
 //             },
-//             SetSessionFunc: func(s *session.Session) error {
+//             ListByEmailFunc: func(ctx context.Context, email string) ([]*session.Session, error) {
+// 	               panic("mock out the ListByEmail method")
+//             },
+//             RefreshFunc: func(ctx context.Context, ID string) (time.Time, error) {
+// 	               panic("mock out the Refresh method")
+//             },
+//             RevokeByEmailFunc: func(ctx context.Context, email string) (int, error) {
+// 	               panic("mock out the RevokeByEmail method")
+//             },
+//             SetSessionFunc: func(ctx context.Context, s *session.Session) error {
 // 	               panic("mock out the SetSession method")
 //             },
+//             UpdateAttributesFunc: func(ctx context.Context, ID string, attributes map[string]interface{}) (*session.Session, error) {
+// 	               panic("mock out the UpdateAttributes method")
+//             },
 //         }
 //
 //         // use mockedCache in code that requires api.Cache
@@ -46,59 +76,144 @@ var _ api.Cache = &CacheMock{}
 //     }
 type CacheMock struct {
 	// DeleteAllFunc mocks the DeleteAll method.
-	DeleteAllFunc func() error
+	DeleteAllFunc func(ctx context.Context) error
+
+	// DeleteByEmailFunc mocks the DeleteByEmail method.
+	DeleteByEmailFunc func(ctx context.Context, email string) error
+
+	// DeleteByIDFunc mocks the DeleteByID method.
+	DeleteByIDFunc func(ctx context.Context, ID string) error
+
+	// DiscardFunc mocks the Discard method.
+	DiscardFunc func(ctx context.Context, ID string) error
 
 	// GetByEmailFunc mocks the GetByEmail method.
-	GetByEmailFunc func(email string) (*session.Session, error)
+	GetByEmailFunc func(ctx context.Context, email string) (*session.Session, error)
 
 	// GetByIDFunc mocks the GetByID method.
-	GetByIDFunc func(ID string) (*session.Session, error)
+	GetByIDFunc func(ctx context.Context, ID string) (*session.Session, error)
+
+	// ListByEmailFunc mocks the ListByEmail method.
+	ListByEmailFunc func(ctx context.Context, email string) ([]*session.Session, error)
+
+	// RefreshFunc mocks the Refresh method.
+	RefreshFunc func(ctx context.Context, ID string) (time.Time, error)
+
+	// RevokeByEmailFunc mocks the RevokeByEmail method.
+	RevokeByEmailFunc func(ctx context.Context, email string) (int, error)
 
 	// SetSessionFunc mocks the SetSession method.
-	SetSessionFunc func(s *session.Session) error
+	SetSessionFunc func(ctx context.Context, s *session.Session) error
+
+	// UpdateAttributesFunc mocks the UpdateAttributes method.
+	UpdateAttributesFunc func(ctx context.Context, ID string, attributes map[string]interface{}) (*session.Session, error)
 
 	// calls tracks calls to the methods.
 	calls struct {
 		// DeleteAll holds details about calls to the DeleteAll method.
 		DeleteAll []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+		}
+		// DeleteByEmail holds details about calls to the DeleteByEmail method.
+		DeleteByEmail []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Email is the email argument value.
+			Email string
+		}
+		// DeleteByID holds details about calls to the DeleteByID method.
+		DeleteByID []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the ID argument value.
+			ID string
+		}
+		// Discard holds details about calls to the Discard method.
+		Discard []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the ID argument value.
+			ID string
 		}
 		// GetByEmail holds details about calls to the GetByEmail method.
 		GetByEmail []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
 			// Email is the email argument value.
 			Email string
 		}
 		// GetByID holds details about calls to the GetByID method.
 		GetByID []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
 			// ID is the ID argument value.
 			ID string
 		}
+		// ListByEmail holds details about calls to the ListByEmail method.
+		ListByEmail []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Email is the email argument value.
+			Email string
+		}
+		// Refresh holds details about calls to the Refresh method.
+		Refresh []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the ID argument value.
+			ID string
+		}
+		// RevokeByEmail holds details about calls to the RevokeByEmail method.
+		RevokeByEmail []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Email is the email argument value.
+			Email string
+		}
 		// SetSession holds details about calls to the SetSession method.
 		SetSession []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
 			// S is the s argument value.
 			S *session.Session
 		}
+		// UpdateAttributes holds details about calls to the UpdateAttributes method.
+		UpdateAttributes []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the ID argument value.
+			ID string
+			// Attributes is the attributes argument value.
+			Attributes map[string]interface{}
+		}
 	}
 }
 
 // DeleteAll calls DeleteAllFunc.
-func (mock *CacheMock) DeleteAll() error {
+func (mock *CacheMock) DeleteAll(ctx context.Context) error {
 	if mock.DeleteAllFunc == nil {
 		panic("CacheMock.DeleteAllFunc: method is nil but Cache.DeleteAll was just called")
 	}
 	callInfo := struct {
-	}{}
+		Ctx context.Context
+	}{
+		Ctx: ctx,
+	}
 	lockCacheMockDeleteAll.Lock()
 	mock.calls.DeleteAll = append(mock.calls.DeleteAll, callInfo)
 	lockCacheMockDeleteAll.Unlock()
-	return mock.DeleteAllFunc()
+	return mock.DeleteAllFunc(ctx)
 }
 
 // DeleteAllCalls gets all the calls that were made to DeleteAll.
 // Check the length with:
 //     len(mockedCache.DeleteAllCalls())
 func (mock *CacheMock) DeleteAllCalls() []struct {
+	Ctx context.Context
 } {
 	var calls []struct {
+		Ctx context.Context
 	}
 	lockCacheMockDeleteAll.RLock()
 	calls = mock.calls.DeleteAll
@@ -106,29 +221,138 @@ func (mock *CacheMock) DeleteAllCalls() []struct {
 	return calls
 }
 
+// DeleteByEmail calls DeleteByEmailFunc.
+func (mock *CacheMock) DeleteByEmail(ctx context.Context, email string) error {
+	if mock.DeleteByEmailFunc == nil {
+		panic("CacheMock.DeleteByEmailFunc: method is nil but Cache.DeleteByEmail was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		Email string
+	}{
+		Ctx:   ctx,
+		Email: email,
+	}
+	lockCacheMockDeleteByEmail.Lock()
+	mock.calls.DeleteByEmail = append(mock.calls.DeleteByEmail, callInfo)
+	lockCacheMockDeleteByEmail.Unlock()
+	return mock.DeleteByEmailFunc(ctx, email)
+}
+
+// DeleteByEmailCalls gets all the calls that were made to DeleteByEmail.
+// Check the length with:
+//     len(mockedCache.DeleteByEmailCalls())
+func (mock *CacheMock) DeleteByEmailCalls() []struct {
+	Ctx   context.Context
+	Email string
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Email string
+	}
+	lockCacheMockDeleteByEmail.RLock()
+	calls = mock.calls.DeleteByEmail
+	lockCacheMockDeleteByEmail.RUnlock()
+	return calls
+}
+
+// DeleteByID calls DeleteByIDFunc.
+func (mock *CacheMock) DeleteByID(ctx context.Context, ID string) error {
+	if mock.DeleteByIDFunc == nil {
+		panic("CacheMock.DeleteByIDFunc: method is nil but Cache.DeleteByID was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		ID  string
+	}{
+		Ctx: ctx,
+		ID:  ID,
+	}
+	lockCacheMockDeleteByID.Lock()
+	mock.calls.DeleteByID = append(mock.calls.DeleteByID, callInfo)
+	lockCacheMockDeleteByID.Unlock()
+	return mock.DeleteByIDFunc(ctx, ID)
+}
+
+// DeleteByIDCalls gets all the calls that were made to DeleteByID.
+// Check the length with:
+//     len(mockedCache.DeleteByIDCalls())
+func (mock *CacheMock) DeleteByIDCalls() []struct {
+	Ctx context.Context
+	ID  string
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  string
+	}
+	lockCacheMockDeleteByID.RLock()
+	calls = mock.calls.DeleteByID
+	lockCacheMockDeleteByID.RUnlock()
+	return calls
+}
+
+// Discard calls DiscardFunc.
+func (mock *CacheMock) Discard(ctx context.Context, ID string) error {
+	if mock.DiscardFunc == nil {
+		panic("CacheMock.DiscardFunc: method is nil but Cache.Discard was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		ID  string
+	}{
+		Ctx: ctx,
+		ID:  ID,
+	}
+	lockCacheMockDiscard.Lock()
+	mock.calls.Discard = append(mock.calls.Discard, callInfo)
+	lockCacheMockDiscard.Unlock()
+	return mock.DiscardFunc(ctx, ID)
+}
+
+// DiscardCalls gets all the calls that were made to Discard.
+// Check the length with:
+//     len(mockedCache.DiscardCalls())
+func (mock *CacheMock) DiscardCalls() []struct {
+	Ctx context.Context
+	ID  string
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  string
+	}
+	lockCacheMockDiscard.RLock()
+	calls = mock.calls.Discard
+	lockCacheMockDiscard.RUnlock()
+	return calls
+}
+
 // GetByEmail calls GetByEmailFunc.
-func (mock *CacheMock) GetByEmail(email string) (*session.Session, error) {
+func (mock *CacheMock) GetByEmail(ctx context.Context, email string) (*session.Session, error) {
 	if mock.GetByEmailFunc == nil {
 		panic("CacheMock.GetByEmailFunc: method is nil but Cache.GetByEmail was just called")
 	}
 	callInfo := struct {
+		Ctx   context.Context
 		Email string
 	}{
+		Ctx:   ctx,
 		Email: email,
 	}
 	lockCacheMockGetByEmail.Lock()
 	mock.calls.GetByEmail = append(mock.calls.GetByEmail, callInfo)
 	lockCacheMockGetByEmail.Unlock()
-	return mock.GetByEmailFunc(email)
+	return mock.GetByEmailFunc(ctx, email)
 }
 
 // GetByEmailCalls gets all the calls that were made to GetByEmail.
 // Check the length with:
 //     len(mockedCache.GetByEmailCalls())
 func (mock *CacheMock) GetByEmailCalls() []struct {
+	Ctx   context.Context
 	Email string
 } {
 	var calls []struct {
+		Ctx   context.Context
 		Email string
 	}
 	lockCacheMockGetByEmail.RLock()
@@ -138,29 +362,33 @@ func (mock *CacheMock) GetByEmailCalls() []struct {
 }
 
 // GetByID calls GetByIDFunc.
-func (mock *CacheMock) GetByID(ID string) (*session.Session, error) {
+func (mock *CacheMock) GetByID(ctx context.Context, ID string) (*session.Session, error) {
 	if mock.GetByIDFunc == nil {
 		panic("CacheMock.GetByIDFunc: method is nil but Cache.GetByID was just called")
 	}
 	callInfo := struct {
-		ID string
+		Ctx context.Context
+		ID  string
 	}{
-		ID: ID,
+		Ctx: ctx,
+		ID:  ID,
 	}
 	lockCacheMockGetByID.Lock()
 	mock.calls.GetByID = append(mock.calls.GetByID, callInfo)
 	lockCacheMockGetByID.Unlock()
-	return mock.GetByIDFunc(ID)
+	return mock.GetByIDFunc(ctx, ID)
 }
 
 // GetByIDCalls gets all the calls that were made to GetByID.
 // Check the length with:
 //     len(mockedCache.GetByIDCalls())
 func (mock *CacheMock) GetByIDCalls() []struct {
-	ID string
+	Ctx context.Context
+	ID  string
 } {
 	var calls []struct {
-		ID string
+		Ctx context.Context
+		ID  string
 	}
 	lockCacheMockGetByID.RLock()
 	calls = mock.calls.GetByID
@@ -168,33 +396,181 @@ func (mock *CacheMock) GetByIDCalls() []struct {
 	return calls
 }
 
+// ListByEmail calls ListByEmailFunc.
+func (mock *CacheMock) ListByEmail(ctx context.Context, email string) ([]*session.Session, error) {
+	if mock.ListByEmailFunc == nil {
+		panic("CacheMock.ListByEmailFunc: method is nil but Cache.ListByEmail was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		Email string
+	}{
+		Ctx:   ctx,
+		Email: email,
+	}
+	lockCacheMockListByEmail.Lock()
+	mock.calls.ListByEmail = append(mock.calls.ListByEmail, callInfo)
+	lockCacheMockListByEmail.Unlock()
+	return mock.ListByEmailFunc(ctx, email)
+}
+
+// ListByEmailCalls gets all the calls that were made to ListByEmail.
+// Check the length with:
+//     len(mockedCache.ListByEmailCalls())
+func (mock *CacheMock) ListByEmailCalls() []struct {
+	Ctx   context.Context
+	Email string
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Email string
+	}
+	lockCacheMockListByEmail.RLock()
+	calls = mock.calls.ListByEmail
+	lockCacheMockListByEmail.RUnlock()
+	return calls
+}
+
+// Refresh calls RefreshFunc.
+func (mock *CacheMock) Refresh(ctx context.Context, ID string) (time.Time, error) {
+	if mock.RefreshFunc == nil {
+		panic("CacheMock.RefreshFunc: method is nil but Cache.Refresh was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		ID  string
+	}{
+		Ctx: ctx,
+		ID:  ID,
+	}
+	lockCacheMockRefresh.Lock()
+	mock.calls.Refresh = append(mock.calls.Refresh, callInfo)
+	lockCacheMockRefresh.Unlock()
+	return mock.RefreshFunc(ctx, ID)
+}
+
+// RefreshCalls gets all the calls that were made to Refresh.
+// Check the length with:
+//     len(mockedCache.RefreshCalls())
+func (mock *CacheMock) RefreshCalls() []struct {
+	Ctx context.Context
+	ID  string
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  string
+	}
+	lockCacheMockRefresh.RLock()
+	calls = mock.calls.Refresh
+	lockCacheMockRefresh.RUnlock()
+	return calls
+}
+
+// RevokeByEmail calls RevokeByEmailFunc.
+func (mock *CacheMock) RevokeByEmail(ctx context.Context, email string) (int, error) {
+	if mock.RevokeByEmailFunc == nil {
+		panic("CacheMock.RevokeByEmailFunc: method is nil but Cache.RevokeByEmail was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		Email string
+	}{
+		Ctx:   ctx,
+		Email: email,
+	}
+	lockCacheMockRevokeByEmail.Lock()
+	mock.calls.RevokeByEmail = append(mock.calls.RevokeByEmail, callInfo)
+	lockCacheMockRevokeByEmail.Unlock()
+	return mock.RevokeByEmailFunc(ctx, email)
+}
+
+// RevokeByEmailCalls gets all the calls that were made to RevokeByEmail.
+// Check the length with:
+//     len(mockedCache.RevokeByEmailCalls())
+func (mock *CacheMock) RevokeByEmailCalls() []struct {
+	Ctx   context.Context
+	Email string
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Email string
+	}
+	lockCacheMockRevokeByEmail.RLock()
+	calls = mock.calls.RevokeByEmail
+	lockCacheMockRevokeByEmail.RUnlock()
+	return calls
+}
+
 // SetSession calls SetSessionFunc.
-func (mock *CacheMock) SetSession(s *session.Session) error {
+func (mock *CacheMock) SetSession(ctx context.Context, s *session.Session) error {
 	if mock.SetSessionFunc == nil {
 		panic("CacheMock.SetSessionFunc: method is nil but Cache.SetSession was just called")
 	}
 	callInfo := struct {
-		S *session.Session
+		Ctx context.Context
+		S   *session.Session
 	}{
-		S: s,
+		Ctx: ctx,
+		S:   s,
 	}
 	lockCacheMockSetSession.Lock()
 	mock.calls.SetSession = append(mock.calls.SetSession, callInfo)
 	lockCacheMockSetSession.Unlock()
-	return mock.SetSessionFunc(s)
+	return mock.SetSessionFunc(ctx, s)
 }
 
 // SetSessionCalls gets all the calls that were made to SetSession.
 // Check the length with:
 //     len(mockedCache.SetSessionCalls())
 func (mock *CacheMock) SetSessionCalls() []struct {
-	S *session.Session
+	Ctx context.Context
+	S   *session.Session
 } {
 	var calls []struct {
-		S *session.Session
+		Ctx context.Context
+		S   *session.Session
 	}
 	lockCacheMockSetSession.RLock()
 	calls = mock.calls.SetSession
 	lockCacheMockSetSession.RUnlock()
 	return calls
 }
+
+// UpdateAttributes calls UpdateAttributesFunc.
+func (mock *CacheMock) UpdateAttributes(ctx context.Context, ID string, attributes map[string]interface{}) (*session.Session, error) {
+	if mock.UpdateAttributesFunc == nil {
+		panic("CacheMock.UpdateAttributesFunc: method is nil but Cache.UpdateAttributes was just called")
+	}
+	callInfo := struct {
+		Ctx        context.Context
+		ID         string
+		Attributes map[string]interface{}
+	}{
+		Ctx:        ctx,
+		ID:         ID,
+		Attributes: attributes,
+	}
+	lockCacheMockUpdateAttributes.Lock()
+	mock.calls.UpdateAttributes = append(mock.calls.UpdateAttributes, callInfo)
+	lockCacheMockUpdateAttributes.Unlock()
+	return mock.UpdateAttributesFunc(ctx, ID, attributes)
+}
+
+// UpdateAttributesCalls gets all the calls that were made to UpdateAttributes.
+// Check the length with:
+//     len(mockedCache.UpdateAttributesCalls())
+func (mock *CacheMock) UpdateAttributesCalls() []struct {
+	Ctx        context.Context
+	ID         string
+	Attributes map[string]interface{}
+} {
+	var calls []struct {
+		Ctx        context.Context
+		ID         string
+		Attributes map[string]interface{}
+	}
+	lockCacheMockUpdateAttributes.RLock()
+	calls = mock.calls.UpdateAttributes
+	lockCacheMockUpdateAttributes.RUnlock()
+	return calls
+}
@@ -4,13 +4,14 @@
 package mock
 
 import (
+	"context"
 	"github.com/ONSdigital/dp-sessions-api/api"
 	"github.com/ONSdigital/dp-sessions-api/session"
 	"sync"
 )
 
 var (
-	lockSessionUpdaterMockUpdate sync.RWMutex
+	lockSessionUpdaterMockUpdateAttributes sync.RWMutex
 )
 
 // Ensure, that SessionUpdaterMock does implement SessionUpdater.
@@ -23,8 +24,8 @@ var _ api.SessionUpdater = &SessionUpdaterMock{}
 //
 //         // make and configure a mocked api.SessionUpdater
 //         mockedSessionUpdater := &SessionUpdaterMock{
-//             UpdateFunc: func(email string) (*session.Session, error) {
-// 	               panic("mock out the Update method")
+//             UpdateAttributesFunc: func(ctx context.Context, ID string, attributes map[string]interface{}) (*session.Session, error) {
+// 	               panic("mock out the UpdateAttributes method")
 //             },
 //         }
 //
@@ -33,46 +34,58 @@ var _ api.SessionUpdater = &SessionUpdaterMock{}
 //
 //     }
 type SessionUpdaterMock struct {
-	// UpdateFunc mocks the Update method.
-	UpdateFunc func(email string) (*session.Session, error)
+	// UpdateAttributesFunc mocks the UpdateAttributes method.
+	UpdateAttributesFunc func(ctx context.Context, ID string, attributes map[string]interface{}) (*session.Session, error)
 
 	// calls tracks calls to the methods.
 	calls struct {
-		// Update holds details about calls to the Update method.
-		Update []struct {
-			// Email is the email argument value.
-			Email string
+		// UpdateAttributes holds details about calls to the UpdateAttributes method.
+		UpdateAttributes []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the ID argument value.
+			ID string
+			// Attributes is the attributes argument value.
+			Attributes map[string]interface{}
 		}
 	}
 }
 
-// Update calls UpdateFunc.
-func (mock *SessionUpdaterMock) Update(email string) (*session.Session, error) {
-	if mock.UpdateFunc == nil {
-		panic("SessionUpdaterMock.UpdateFunc: method is nil but SessionUpdater.Update was just called")
+// UpdateAttributes calls UpdateAttributesFunc.
+func (mock *SessionUpdaterMock) UpdateAttributes(ctx context.Context, ID string, attributes map[string]interface{}) (*session.Session, error) {
+	if mock.UpdateAttributesFunc == nil {
+		panic("SessionUpdaterMock.UpdateAttributesFunc: method is nil but SessionUpdater.UpdateAttributes was just called")
 	}
 	callInfo := struct {
-		Email string
+		Ctx        context.Context
+		ID         string
+		Attributes map[string]interface{}
 	}{
-		Email: email,
+		Ctx:        ctx,
+		ID:         ID,
+		Attributes: attributes,
 	}
-	lockSessionUpdaterMockUpdate.Lock()
-	mock.calls.Update = append(mock.calls.Update, callInfo)
-	lockSessionUpdaterMockUpdate.Unlock()
-	return mock.UpdateFunc(email)
+	lockSessionUpdaterMockUpdateAttributes.Lock()
+	mock.calls.UpdateAttributes = append(mock.calls.UpdateAttributes, callInfo)
+	lockSessionUpdaterMockUpdateAttributes.Unlock()
+	return mock.UpdateAttributesFunc(ctx, ID, attributes)
 }
 
-// UpdateCalls gets all the calls that were made to Update.
+// UpdateAttributesCalls gets all the calls that were made to UpdateAttributes.
 // Check the length with:
-//     len(mockedSessionUpdater.UpdateCalls())
-func (mock *SessionUpdaterMock) UpdateCalls() []struct {
-	Email string
+//     len(mockedSessionUpdater.UpdateAttributesCalls())
+func (mock *SessionUpdaterMock) UpdateAttributesCalls() []struct {
+	Ctx        context.Context
+	ID         string
+	Attributes map[string]interface{}
 } {
 	var calls []struct {
-		Email string
+		Ctx        context.Context
+		ID         string
+		Attributes map[string]interface{}
 	}
-	lockSessionUpdaterMockUpdate.RLock()
-	calls = mock.calls.Update
-	lockSessionUpdaterMockUpdate.RUnlock()
+	lockSessionUpdaterMockUpdateAttributes.RLock()
+	calls = mock.calls.UpdateAttributes
+	lockSessionUpdaterMockUpdateAttributes.RUnlock()
 	return calls
 }
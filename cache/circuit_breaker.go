@@ -0,0 +1,321 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ErrCircuitOpen is matched, using errors.Is, by the CircuitOpenError returned while the circuit breaker is open
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitOpenError - returned without redis being contacted while the circuit breaker is open
+type CircuitOpenError struct {
+	// RetryAfter is how long until the circuit breaker next lets a command through to redis
+	RetryAfter time.Duration
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("%s, retry after %s", ErrCircuitOpen, e.RetryAfter)
+}
+
+// Is - reports whether target is ErrCircuitOpen
+func (e *CircuitOpenError) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
+
+// RetryAfterSeconds - returns RetryAfter rounded up to a whole number of seconds, as used in a Retry-After header
+func (e *CircuitOpenError) RetryAfterSeconds() int {
+	return int(math.Max(1, math.Ceil(e.RetryAfter.Seconds())))
+}
+
+type breakerState string
+
+const (
+	breakerClosed   breakerState = "closed"
+	breakerOpen     breakerState = "open"
+	breakerHalfOpen breakerState = "half-open"
+)
+
+// circuitBreaker - stops commands being sent to redis once threshold consecutive commands have failed. After
+// openTimeout the breaker is half-open and lets a single probe command through at a time: if it succeeds the breaker
+// closes, if it fails the breaker opens again.
+type circuitBreaker struct {
+	threshold   int
+	openTimeout time.Duration
+	now         func() time.Time
+
+	mu       sync.Mutex
+	state    breakerState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+func newCircuitBreaker(threshold int, openTimeout time.Duration) *circuitBreaker {
+	return &circuitBreaker{
+		threshold:   threshold,
+		openTimeout: openTimeout,
+		now:         time.Now,
+		state:       breakerClosed,
+	}
+}
+
+// run - calls command unless the breaker is open, in which case a *CircuitOpenError is returned, and records the
+// command's outcome
+func (b *circuitBreaker) run(command func() error) error {
+	probe, err := b.allow()
+	if err != nil {
+		return err
+	}
+
+	err = command()
+	b.record(probe, err)
+	return err
+}
+
+// allow - returns a *CircuitOpenError if a command should not be sent to redis, or whether the command is the probe
+// that decides if a half-open breaker closes
+func (b *circuitBreaker) allow() (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.currentState() {
+	case breakerOpen:
+		return false, &CircuitOpenError{RetryAfter: b.openedAt.Add(b.openTimeout).Sub(b.now())}
+	case breakerHalfOpen:
+		if b.probing {
+			return false, &CircuitOpenError{RetryAfter: time.Second}
+		}
+		b.state = breakerHalfOpen
+		b.probing = true
+		return true, nil
+	}
+	return false, nil
+}
+
+// record - updates the breaker with the outcome of a command that was allowed. A missing key is a successful command,
+// and a command abandoned because its caller went away says nothing about redis so is not counted either way. Any
+// other error reply, such as NOSCRIPT or WRONGTYPE, shows redis is up and answering so is counted as a success too,
+// unless it is one of the replies redis gives while failing over.
+func (b *circuitBreaker) record(probe bool, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if probe {
+		b.probing = false
+	}
+
+	var redisErr redis.Error
+	switch {
+	case errors.Is(err, context.Canceled):
+		return
+	case err == nil || errors.As(err, &redisErr) && !failingOver(redisErr):
+		b.failures = 0
+		if probe {
+			b.state = breakerClosed
+		}
+	default:
+		b.failures++
+		if probe || b.failures >= b.threshold {
+			b.state = breakerOpen
+			b.openedAt = b.now()
+		}
+	}
+}
+
+// currentState - returns the breaker's state, which becomes half-open once it has been open for openTimeout
+func (b *circuitBreaker) currentState() breakerState {
+	if b.state == breakerOpen && !b.now().Before(b.openedAt.Add(b.openTimeout)) {
+		return breakerHalfOpen
+	}
+	return b.state
+}
+
+// State - returns the breaker's current state
+func (b *circuitBreaker) State() breakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.currentState()
+}
+
+// breakerClient - a RedisClienter that fails commands with a *CircuitOpenError, without sending them, while its
+// circuit breaker is open. Subscriptions are held open for long periods so are not guarded by the breaker.
+type breakerClient struct {
+	client  RedisClienter
+	breaker *circuitBreaker
+}
+
+func (c *breakerClient) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd {
+	var cmd *redis.StatusCmd
+	err := c.breaker.run(func() error {
+		cmd = c.client.Set(ctx, key, value, expiration)
+		return cmd.Err()
+	})
+	if cmd == nil {
+		return redis.NewStatusResult("", err)
+	}
+	return cmd
+}
+
+func (c *breakerClient) SetXX(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.BoolCmd {
+	var cmd *redis.BoolCmd
+	err := c.breaker.run(func() error {
+		cmd = c.client.SetXX(ctx, key, value, expiration)
+		return cmd.Err()
+	})
+	if cmd == nil {
+		return redis.NewBoolResult(false, err)
+	}
+	return cmd
+}
+
+func (c *breakerClient) Get(ctx context.Context, key string) *redis.StringCmd {
+	var cmd *redis.StringCmd
+	err := c.breaker.run(func() error {
+		cmd = c.client.Get(ctx, key)
+		return cmd.Err()
+	})
+	if cmd == nil {
+		return redis.NewStringResult("", err)
+	}
+	return cmd
+}
+
+func (c *breakerClient) Expire(ctx context.Context, key string, expiration time.Duration) *redis.BoolCmd {
+	var cmd *redis.BoolCmd
+	err := c.breaker.run(func() error {
+		cmd = c.client.Expire(ctx, key, expiration)
+		return cmd.Err()
+	})
+	if cmd == nil {
+		return redis.NewBoolResult(false, err)
+	}
+	return cmd
+}
+
+func (c *breakerClient) Del(ctx context.Context, keys ...string) *redis.IntCmd {
+	var cmd *redis.IntCmd
+	err := c.breaker.run(func() error {
+		cmd = c.client.Del(ctx, keys...)
+		return cmd.Err()
+	})
+	if cmd == nil {
+		return redis.NewIntResult(0, err)
+	}
+	return cmd
+}
+
+func (c *breakerClient) ZAdd(ctx context.Context, key string, members ...*redis.Z) *redis.IntCmd {
+	var cmd *redis.IntCmd
+	err := c.breaker.run(func() error {
+		cmd = c.client.ZAdd(ctx, key, members...)
+		return cmd.Err()
+	})
+	if cmd == nil {
+		return redis.NewIntResult(0, err)
+	}
+	return cmd
+}
+
+func (c *breakerClient) ZRange(ctx context.Context, key string, start, stop int64) *redis.StringSliceCmd {
+	var cmd *redis.StringSliceCmd
+	err := c.breaker.run(func() error {
+		cmd = c.client.ZRange(ctx, key, start, stop)
+		return cmd.Err()
+	})
+	if cmd == nil {
+		return redis.NewStringSliceResult(nil, err)
+	}
+	return cmd
+}
+
+func (c *breakerClient) ZRem(ctx context.Context, key string, members ...interface{}) *redis.IntCmd {
+	var cmd *redis.IntCmd
+	err := c.breaker.run(func() error {
+		cmd = c.client.ZRem(ctx, key, members...)
+		return cmd.Err()
+	})
+	if cmd == nil {
+		return redis.NewIntResult(0, err)
+	}
+	return cmd
+}
+
+func (c *breakerClient) TxPipelined(ctx context.Context, fn func(redis.Pipeliner) error) ([]redis.Cmder, error) {
+	var cmds []redis.Cmder
+	err := c.breaker.run(func() (err error) {
+		cmds, err = c.client.TxPipelined(ctx, fn)
+		return err
+	})
+	return cmds, err
+}
+
+func (c *breakerClient) Eval(ctx context.Context, script string, keys []string, args ...interface{}) *redis.Cmd {
+	var cmd *redis.Cmd
+	err := c.breaker.run(func() error {
+		cmd = c.client.Eval(ctx, script, keys, args...)
+		return cmd.Err()
+	})
+	if cmd == nil {
+		return redis.NewCmdResult(nil, err)
+	}
+	return cmd
+}
+
+func (c *breakerClient) EvalSha(ctx context.Context, sha1 string, keys []string, args ...interface{}) *redis.Cmd {
+	var cmd *redis.Cmd
+	err := c.breaker.run(func() error {
+		cmd = c.client.EvalSha(ctx, sha1, keys, args...)
+		return cmd.Err()
+	})
+	if cmd == nil {
+		return redis.NewCmdResult(nil, err)
+	}
+	return cmd
+}
+
+func (c *breakerClient) Scan(ctx context.Context, cursor uint64, match string, count int64) *redis.ScanCmd {
+	var cmd *redis.ScanCmd
+	err := c.breaker.run(func() error {
+		cmd = c.client.Scan(ctx, cursor, match, count)
+		return cmd.Err()
+	})
+	if cmd == nil {
+		return redis.NewScanCmdResult(nil, 0, err)
+	}
+	return cmd
+}
+
+func (c *breakerClient) Ping(ctx context.Context) *redis.StatusCmd {
+	var cmd *redis.StatusCmd
+	err := c.breaker.run(func() error {
+		cmd = c.client.Ping(ctx)
+		return cmd.Err()
+	})
+	if cmd == nil {
+		return redis.NewStatusResult("", err)
+	}
+	return cmd
+}
+
+func (c *breakerClient) Info(ctx context.Context, section ...string) *redis.StringCmd {
+	var cmd *redis.StringCmd
+	err := c.breaker.run(func() error {
+		cmd = c.client.Info(ctx, section...)
+		return cmd.Err()
+	})
+	if cmd == nil {
+		return redis.NewStringResult("", err)
+	}
+	return cmd
+}
+
+func (c *breakerClient) Subscribe(ctx context.Context, channels ...string) *redis.PubSub {
+	return c.client.Subscribe(ctx, channels...)
+}
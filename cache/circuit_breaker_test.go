@@ -0,0 +1,231 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	health "github.com/ONSdigital/dp-healthcheck/healthcheck"
+	"github.com/ONSdigital/dp-sessions-api/events"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	Convey("Given a circuit breaker that opens after 3 failures for 10 seconds", t, func() {
+		now := time.Date(2020, 8, 13, 8, 40, 0, 0, time.UTC)
+		breaker := newCircuitBreaker(3, 10*time.Second)
+		breaker.now = func() time.Time { return now }
+
+		fail := func() error { return io.EOF }
+		succeed := func() error { return nil }
+
+		Convey("When fewer commands than the threshold fail in a row", func() {
+			breaker.run(fail)
+			breaker.run(fail)
+			breaker.run(succeed)
+			breaker.run(fail)
+
+			Convey("Then the breaker stays closed", func() {
+				So(breaker.State(), ShouldEqual, breakerClosed)
+			})
+		})
+
+		Convey("When a key is missing or a caller gives up", func() {
+			breaker.run(fail)
+			breaker.run(fail)
+			breaker.run(func() error { return redis.Nil })
+			breaker.run(fail)
+			breaker.run(func() error { return context.Canceled })
+
+			Convey("Then neither is counted as a failure", func() {
+				So(breaker.State(), ShouldEqual, breakerClosed)
+			})
+		})
+
+		Convey("When redis replies with an error", func() {
+			breaker.run(fail)
+			breaker.run(fail)
+			breaker.run(func() error { return replyErr("NOSCRIPT No matching script. Please use EVAL.") })
+			breaker.run(fail)
+
+			Convey("Then it is not counted as a failure as redis is up", func() {
+				So(breaker.State(), ShouldEqual, breakerClosed)
+			})
+		})
+
+		Convey("When redis replies that it is failing over", func() {
+			breaker.run(fail)
+			breaker.run(fail)
+			breaker.run(func() error { return replyErr("LOADING Redis is loading the dataset in memory") })
+
+			Convey("Then it is counted as a failure", func() {
+				So(breaker.State(), ShouldEqual, breakerOpen)
+			})
+		})
+
+		Convey("When the threshold is reached", func() {
+			breaker.run(fail)
+			breaker.run(fail)
+			breaker.run(fail)
+
+			Convey("Then the breaker opens and commands fail without being run", func() {
+				So(breaker.State(), ShouldEqual, breakerOpen)
+
+				now = now.Add(7500 * time.Millisecond)
+				called := false
+				err := breaker.run(func() error {
+					called = true
+					return nil
+				})
+				So(called, ShouldBeFalse)
+				So(errors.Is(err, ErrCircuitOpen), ShouldBeTrue)
+
+				var circuitOpenErr *CircuitOpenError
+				So(errors.As(err, &circuitOpenErr), ShouldBeTrue)
+				So(circuitOpenErr.RetryAfter, ShouldEqual, 2500*time.Millisecond)
+				So(circuitOpenErr.RetryAfterSeconds(), ShouldEqual, 3)
+			})
+
+			Convey("And once the open timeout passes the breaker is half-open", func() {
+				now = now.Add(10 * time.Second)
+				So(breaker.State(), ShouldEqual, breakerHalfOpen)
+
+				Convey("Then only one probe is let through at a time", func() {
+					probe, err := breaker.allow()
+					So(probe, ShouldBeTrue)
+					So(err, ShouldBeNil)
+
+					_, err = breaker.allow()
+					So(errors.Is(err, ErrCircuitOpen), ShouldBeTrue)
+				})
+
+				Convey("Then the breaker closes when the probe succeeds", func() {
+					So(breaker.run(succeed), ShouldBeNil)
+					So(breaker.State(), ShouldEqual, breakerClosed)
+				})
+
+				Convey("Then the breaker opens again when the probe fails", func() {
+					So(breaker.run(fail), ShouldEqual, io.EOF)
+					So(breaker.State(), ShouldEqual, breakerOpen)
+				})
+			})
+		})
+	})
+}
+
+func TestBreakerClient(t *testing.T) {
+	Convey("Given a redis client guarded by an open circuit breaker", t, func() {
+		mockRedisClient, _ := setUpMocks(nil, redis.NewStringResult(string(resp), nil), nil, nil)
+		breaker := newCircuitBreaker(1, time.Minute)
+		breaker.run(func() error { return io.EOF })
+
+		client := &ElasticacheClient{
+			lifetime:        lifetime{ttl: testTTL},
+			client:          &breakerClient{client: mockRedisClient, breaker: breaker},
+			keyPrefix:       DefaultKeyPrefix,
+			publisher:       events.NopPublisher{},
+			accessPublisher: events.NopPublisher{},
+			codec:           plainCodec{},
+			breaker:         breaker,
+		}
+
+		Convey("When a session is read", func() {
+			_, err := client.GetByID(testCtx, testSessionID)
+
+			Convey("Then a circuit open error is returned without redis being contacted", func() {
+				So(errors.Is(err, ErrCircuitOpen), ShouldBeTrue)
+				So(mockRedisClient.GetCalls(), ShouldHaveLength, 0)
+			})
+		})
+
+		Convey("When the health check runs", func() {
+			state := health.NewCheckState("elasticache")
+			err := client.Checker(testCtx, state)
+
+			Convey("Then the status is CRITICAL and redis is not pinged", func() {
+				So(err, ShouldBeNil)
+				So(state.Status(), ShouldEqual, health.StatusCritical)
+				So(state.Message(), ShouldEqual, CircuitOpenMessage)
+				So(mockRedisClient.PingCalls(), ShouldHaveLength, 0)
+			})
+		})
+
+		Convey("When the breaker is half-open and the health check runs", func() {
+			breaker.now = func() time.Time { return time.Now().Add(time.Minute) }
+			state := health.NewCheckState("elasticache")
+			err := client.Checker(testCtx, state)
+
+			Convey("Then the status is WARNING", func() {
+				So(err, ShouldBeNil)
+				So(state.Status(), ShouldEqual, health.StatusWarning)
+				So(state.Message(), ShouldEqual, CircuitHalfOpenMessage)
+				So(mockRedisClient.PingCalls(), ShouldHaveLength, 0)
+			})
+		})
+	})
+
+	Convey("Given a client whose circuit breaker opens after a single failure", t, func() {
+		m, err := miniredis.Run()
+		So(err, ShouldBeNil)
+		defer m.Close()
+		m.RequireAuth("password")
+
+		c, err := New(Config{
+			Addr:                    m.Addr(),
+			Password:                "password",
+			TTL:                     testTTL,
+			BreakerFailureThreshold: 1,
+		})
+		So(err, ShouldBeNil)
+		So(c.SetSession(testCtx, newTestSession(testSessionID, time.Now())), ShouldBeNil)
+		_, err = c.Refresh(testCtx, testSessionID)
+		So(err, ShouldBeNil)
+
+		Convey("When the script cache is flushed and the session is refreshed", func() {
+			admin := redis.NewClient(&redis.Options{Addr: m.Addr(), Password: "password"})
+			defer admin.Close()
+			So(admin.ScriptFlush(testCtx).Err(), ShouldBeNil)
+
+			_, err := c.Refresh(testCtx, testSessionID)
+
+			Convey("Then the script is reloaded and the breaker stays closed", func() {
+				So(err, ShouldBeNil)
+				So(c.breaker.State(), ShouldEqual, breakerClosed)
+
+				_, err = c.Refresh(testCtx, testSessionID)
+				So(err, ShouldBeNil)
+			})
+		})
+	})
+
+	Convey("Given a client configured with a circuit breaker and retries", t, func() {
+		c, err := New(Config{
+			Addr:                    "123.0.0.1",
+			Password:                testSessionID,
+			TTL:                     testTTL,
+			MaxRetries:              2,
+			BreakerFailureThreshold: 5,
+		})
+		So(err, ShouldBeNil)
+
+		Convey("Then the breaker guards the retrying, instrumented client", func() {
+			So(c.breaker, ShouldNotBeNil)
+			So(c.breaker.openTimeout, ShouldEqual, DefaultBreakerOpenTimeout)
+			So(c.client, ShouldHaveSameTypeAs, &breakerClient{})
+			So(c.client.(*breakerClient).client, ShouldHaveSameTypeAs, &retryingClient{})
+		})
+	})
+
+	Convey("Given a negative circuit breaker threshold", t, func() {
+		c, err := New(Config{Addr: "123.0.0.1", Password: testSessionID, TTL: testTTL, BreakerFailureThreshold: -1})
+
+		Convey("Then the client will not be created and the invalid breaker error is returned", func() {
+			So(c, ShouldBeNil)
+			So(err, ShouldEqual, ErrInvalidBreaker)
+		})
+	})
+}
@@ -0,0 +1,200 @@
+package cache
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+var (
+	ErrEmptyEncryptionKeys  = errors.New("at least one encryption key is required")
+	ErrInvalidEncryptionKey = errors.New("encryption keys should be 32 bytes long")
+	ErrInvalidIndexKey      = errors.New("index key should be 32 bytes long")
+	ErrInvalidKeyID         = errors.New("encryption key IDs should not be empty or contain '.'")
+	ErrUnknownCurrentKey    = errors.New("current encryption key ID is not one of the configured keys")
+	ErrUnknownKeyID         = errors.New("session is encrypted with a key that is not configured")
+	ErrInvalidEnvelope      = errors.New("session is not a valid encrypted envelope")
+	ErrPlaintextSession     = errors.New("session is stored unencrypted")
+	ErrDecryptionFailed     = errors.New("session could not be decrypted")
+)
+
+// envelopeVersion prefixes every encrypted session, so the envelope format can be changed in future
+const envelopeVersion = "v1"
+
+// Codec - encodes a session's JSON before it is written to redis and decodes it when it is read back. id is the
+// session's ID, which an encoding may bind the stored value to.
+type Codec interface {
+	Encode(id string, plaintext []byte) ([]byte, error)
+	Decode(id string, stored []byte) ([]byte, error)
+}
+
+// staleCodec - a Codec that can report that a stored session should be encoded again, for example because it was
+// encrypted with a key that is being rotated out
+type staleCodec interface {
+	Stale(stored []byte) bool
+}
+
+// indexCodec - a Codec that derives the value a user's sessions are indexed by from their email, so that the email does
+// not appear in key names
+type indexCodec interface {
+	Index(email string) string
+}
+
+// plaintextCodec - a Codec that can report whether it still reads values stored before it was enabled
+type plaintextCodec interface {
+	AllowsPlaintext() bool
+}
+
+// plainCodec - stores session JSON as it is
+type plainCodec struct{}
+
+func (plainCodec) Encode(id string, plaintext []byte) ([]byte, error) {
+	return plaintext, nil
+}
+
+func (plainCodec) Decode(id string, stored []byte) ([]byte, error) {
+	return stored, nil
+}
+
+// EncryptionConfig - config options for an AESGCMCodec
+type EncryptionConfig struct {
+	// Keys are the AES-256 keys sessions may be encrypted with, by key ID. Key IDs are stored in each session's
+	// envelope so should not be secret.
+	Keys map[string][]byte `json:"-"`
+	// CurrentKeyID is the ID of the key new and refreshed sessions are encrypted with
+	CurrentKeyID string
+	// IndexKey is the HMAC-SHA256 key users' emails are hashed with to name the keys indexing their sessions. Changing it
+	// orphans every user's index until their sessions expire.
+	IndexKey []byte `json:"-"`
+	// AllowPlaintext lets sessions stored before encryption was enabled be read, until they are next written, and found
+	// by email through the index they were stored with. It should only be set while migrating, as it lets anyone able to
+	// write to redis store sessions.
+	AllowPlaintext bool
+}
+
+// AESGCMCodec - encrypts sessions with AES-256-GCM. A session is stored as an envelope,
+// v1.<key ID>.<base64url(nonce|ciphertext)>, so it can be decrypted with any configured key while keys are rotated.
+// The session ID is authenticated along with the ciphertext, so an envelope cannot be moved to another session's key.
+// Users' sessions are indexed by an HMAC of their email.
+type AESGCMCodec struct {
+	current        string
+	aeads          map[string]cipher.AEAD
+	indexKey       []byte
+	allowPlaintext bool
+}
+
+// NewAESGCMCodec - creates an AESGCMCodec encrypting with the current key in c
+func NewAESGCMCodec(c EncryptionConfig) (*AESGCMCodec, error) {
+	if len(c.Keys) == 0 {
+		return nil, ErrEmptyEncryptionKeys
+	}
+
+	aeads := make(map[string]cipher.AEAD, len(c.Keys))
+	for id, key := range c.Keys {
+		if id == "" || strings.Contains(id, ".") {
+			return nil, ErrInvalidKeyID
+		}
+
+		if len(key) != 32 {
+			return nil, ErrInvalidEncryptionKey
+		}
+
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		aeads[id] = aead
+	}
+
+	if _, ok := aeads[c.CurrentKeyID]; !ok {
+		return nil, ErrUnknownCurrentKey
+	}
+
+	if len(c.IndexKey) != 32 {
+		return nil, ErrInvalidIndexKey
+	}
+
+	return &AESGCMCodec{current: c.CurrentKeyID, aeads: aeads, indexKey: c.IndexKey, allowPlaintext: c.AllowPlaintext}, nil
+}
+
+// Index - returns the hex encoded HMAC-SHA256 of the email, which a user's sessions are indexed by. Emails are matched
+// exactly, as they are when sessions are indexed without encryption.
+func (c *AESGCMCodec) Index(email string) string {
+	mac := hmac.New(sha256.New, c.indexKey)
+	mac.Write([]byte(email))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// AllowsPlaintext - reports whether sessions stored before encryption was enabled can still be read
+func (c *AESGCMCodec) AllowsPlaintext() bool {
+	return c.allowPlaintext
+}
+
+// Encode - encrypts plaintext with the current key, returning its envelope
+func (c *AESGCMCodec) Encode(id string, plaintext []byte) ([]byte, error) {
+	aead := c.aeads[c.current]
+
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("unable to generate nonce: %w", err)
+	}
+	sealed := aead.Seal(nonce, nonce, plaintext, []byte(id))
+
+	return []byte(envelopeVersion + "." + c.current + "." + base64.RawURLEncoding.EncodeToString(sealed)), nil
+}
+
+// Stale - reports whether stored is unencrypted or was encrypted with a key other than the current one, so should be
+// encrypted again
+func (c *AESGCMCodec) Stale(stored []byte) bool {
+	if bytes.HasPrefix(stored, []byte("{")) {
+		return true
+	}
+	return !bytes.HasPrefix(stored, []byte(envelopeVersion+"."+c.current+"."))
+}
+
+// Decode - decrypts an envelope with the key it names. Unencrypted session JSON is returned unchanged if plaintext is
+// allowed, otherwise ErrPlaintextSession is returned.
+func (c *AESGCMCodec) Decode(id string, stored []byte) ([]byte, error) {
+	if bytes.HasPrefix(stored, []byte("{")) {
+		if c.allowPlaintext {
+			return stored, nil
+		}
+		return nil, ErrPlaintextSession
+	}
+
+	parts := strings.SplitN(string(stored), ".", 3)
+	if len(parts) != 3 || parts[0] != envelopeVersion {
+		return nil, ErrInvalidEnvelope
+	}
+
+	aead, ok := c.aeads[parts[1]]
+	if !ok {
+		return nil, ErrUnknownKeyID
+	}
+
+	sealed, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return nil, ErrInvalidEnvelope
+	}
+
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(id))
+	if err != nil {
+		return nil, ErrDecryptionFailed
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,342 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ONSdigital/dp-sessions-api/session"
+	"github.com/alicebob/miniredis/v2"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var (
+	oldKey   = bytes.Repeat([]byte{1}, 32)
+	newKey   = bytes.Repeat([]byte{2}, 32)
+	indexKey = bytes.Repeat([]byte{3}, 32)
+)
+
+func TestNewAESGCMCodec(t *testing.T) {
+	Convey("Given invalid encryption config", t, func() {
+		cases := map[string]struct {
+			config EncryptionConfig
+			err    error
+		}{
+			"no keys":                {EncryptionConfig{CurrentKeyID: "1"}, ErrEmptyEncryptionKeys},
+			"a short key":            {EncryptionConfig{Keys: map[string][]byte{"1": oldKey[:16]}, CurrentKeyID: "1"}, ErrInvalidEncryptionKey},
+			"an empty key ID":        {EncryptionConfig{Keys: map[string][]byte{"": oldKey}, CurrentKeyID: ""}, ErrInvalidKeyID},
+			"a key ID with a period": {EncryptionConfig{Keys: map[string][]byte{"v1.1": oldKey}, CurrentKeyID: "v1.1"}, ErrInvalidKeyID},
+			"an unknown current key": {EncryptionConfig{Keys: map[string][]byte{"1": oldKey}, CurrentKeyID: "2"}, ErrUnknownCurrentKey},
+			"no index key":           {EncryptionConfig{Keys: map[string][]byte{"1": oldKey}, CurrentKeyID: "1"}, ErrInvalidIndexKey},
+			"a short index key":      {EncryptionConfig{Keys: map[string][]byte{"1": oldKey}, CurrentKeyID: "1", IndexKey: indexKey[:16]}, ErrInvalidIndexKey},
+		}
+
+		for name, c := range cases {
+			Convey("Then the codec is not created given "+name, func() {
+				codec, err := NewAESGCMCodec(c.config)
+				So(codec, ShouldBeNil)
+				So(err, ShouldEqual, c.err)
+			})
+		}
+	})
+}
+
+func TestAESGCMCodec(t *testing.T) {
+	Convey("Given a codec with a single key", t, func() {
+		codec, err := NewAESGCMCodec(EncryptionConfig{Keys: map[string][]byte{"2020-08": oldKey}, CurrentKeyID: "2020-08", IndexKey: indexKey})
+		So(err, ShouldBeNil)
+
+		Convey("When a session is encoded", func() {
+			stored, err := codec.Encode(testSessionID, resp)
+			So(err, ShouldBeNil)
+
+			Convey("Then it is stored in an envelope naming the key, without the plaintext", func() {
+				So(string(stored), ShouldStartWith, "v1.2020-08.")
+				So(string(stored), ShouldNotContainSubstring, testEmail)
+			})
+
+			Convey("Then it decodes to the original session", func() {
+				plaintext, err := codec.Decode(testSessionID, stored)
+				So(err, ShouldBeNil)
+				So(plaintext, ShouldResemble, resp)
+			})
+
+			Convey("Then encoding it again gives a different envelope", func() {
+				again, err := codec.Encode(testSessionID, resp)
+				So(err, ShouldBeNil)
+				So(again, ShouldNotResemble, stored)
+			})
+
+			Convey("Then it cannot be decoded as another session", func() {
+				_, err := codec.Decode("5678", stored)
+				So(err, ShouldEqual, ErrDecryptionFailed)
+			})
+
+			Convey("Then it cannot be decoded once tampered with", func() {
+				parts := strings.SplitN(string(stored), ".", 3)
+				sealed, err := base64.RawURLEncoding.DecodeString(parts[2])
+				So(err, ShouldBeNil)
+				sealed[len(sealed)-1] ^= 1
+				tampered := parts[0] + "." + parts[1] + "." + base64.RawURLEncoding.EncodeToString(sealed)
+
+				_, err = codec.Decode(testSessionID, []byte(tampered))
+				So(err, ShouldEqual, ErrDecryptionFailed)
+			})
+		})
+
+		Convey("When a user's email is indexed", func() {
+			index := codec.Index(testEmail)
+
+			Convey("Then the index does not contain the email", func() {
+				So(index, ShouldHaveLength, 64)
+				So(index, ShouldNotContainSubstring, "user")
+			})
+
+			Convey("Then the email is matched exactly, as it is without encryption", func() {
+				So(codec.Index("User@Email.com"), ShouldNotEqual, index)
+			})
+
+			Convey("Then different emails have different indexes", func() {
+				So(codec.Index("other@email.com"), ShouldNotEqual, index)
+			})
+		})
+
+		Convey("When a value that is not an envelope is decoded", func() {
+			_, err := codec.Decode(testSessionID, []byte("v2.2020-08.abc"))
+
+			Convey("Then ErrInvalidEnvelope is returned", func() {
+				So(err, ShouldEqual, ErrInvalidEnvelope)
+			})
+		})
+
+		Convey("When an unencrypted session is decoded", func() {
+			_, err := codec.Decode(testSessionID, resp)
+
+			Convey("Then ErrPlaintextSession is returned", func() {
+				So(err, ShouldEqual, ErrPlaintextSession)
+			})
+		})
+	})
+
+	Convey("Given a codec migrating from unencrypted sessions", t, func() {
+		codec, err := NewAESGCMCodec(EncryptionConfig{
+			Keys:           map[string][]byte{"2020-08": oldKey},
+			CurrentKeyID:   "2020-08",
+			IndexKey:       indexKey,
+			AllowPlaintext: true,
+		})
+		So(err, ShouldBeNil)
+
+		Convey("When an unencrypted session is decoded", func() {
+			plaintext, err := codec.Decode(testSessionID, resp)
+
+			Convey("Then it is returned unchanged", func() {
+				So(err, ShouldBeNil)
+				So(plaintext, ShouldResemble, resp)
+			})
+		})
+	})
+
+	Convey("Given a session encrypted with the old key", t, func() {
+		old, err := NewAESGCMCodec(EncryptionConfig{Keys: map[string][]byte{"2020-08": oldKey}, CurrentKeyID: "2020-08", IndexKey: indexKey})
+		So(err, ShouldBeNil)
+		stored, err := old.Encode(testSessionID, resp)
+		So(err, ShouldBeNil)
+
+		Convey("When the key is rotated, keeping the old key configured", func() {
+			rotated, err := NewAESGCMCodec(EncryptionConfig{
+				Keys:         map[string][]byte{"2020-08": oldKey, "2020-09": newKey},
+				CurrentKeyID: "2020-09",
+				IndexKey:     indexKey,
+			})
+			So(err, ShouldBeNil)
+
+			Convey("Then the session can still be decoded", func() {
+				plaintext, err := rotated.Decode(testSessionID, stored)
+				So(err, ShouldBeNil)
+				So(plaintext, ShouldResemble, resp)
+			})
+
+			Convey("Then sessions are encoded with the new key", func() {
+				reencoded, err := rotated.Encode(testSessionID, resp)
+				So(err, ShouldBeNil)
+				So(string(reencoded), ShouldStartWith, "v1.2020-09.")
+			})
+
+			Convey("Then the session is reported as stale until it is encoded with the new key", func() {
+				So(rotated.Stale(stored), ShouldBeTrue)
+				So(rotated.Stale(resp), ShouldBeTrue)
+
+				reencoded, err := rotated.Encode(testSessionID, resp)
+				So(err, ShouldBeNil)
+				So(rotated.Stale(reencoded), ShouldBeFalse)
+			})
+		})
+
+		Convey("When the old key is no longer configured", func() {
+			rotated, err := NewAESGCMCodec(EncryptionConfig{Keys: map[string][]byte{"2020-09": newKey}, CurrentKeyID: "2020-09", IndexKey: indexKey})
+			So(err, ShouldBeNil)
+
+			Convey("Then the session cannot be decoded", func() {
+				_, err := rotated.Decode(testSessionID, stored)
+				So(err, ShouldEqual, ErrUnknownKeyID)
+			})
+		})
+	})
+}
+
+func TestClient_Encryption(t *testing.T) {
+	Convey("Given a client that encrypts sessions", t, func() {
+		m, err := miniredis.Run()
+		So(err, ShouldBeNil)
+		defer m.Close()
+		m.RequireAuth("password")
+
+		newClient := func(keys map[string][]byte, current string) *ElasticacheClient {
+			codec, err := NewAESGCMCodec(EncryptionConfig{Keys: keys, CurrentKeyID: current, IndexKey: indexKey})
+			So(err, ShouldBeNil)
+			c, err := New(Config{Addr: m.Addr(), Password: "password", TTL: testTTL, Codec: codec})
+			So(err, ShouldBeNil)
+			return c
+		}
+		c := newClient(map[string][]byte{"2020-08": oldKey}, "2020-08")
+
+		s, err := session.New(testEmail)
+		So(err, ShouldBeNil)
+		So(c.SetSession(testCtx, s), ShouldBeNil)
+
+		Convey("Then the session is stored encrypted", func() {
+			stored, err := m.Get(c.idKey(s.ID))
+			So(err, ShouldBeNil)
+			So(stored, ShouldStartWith, "v1.2020-08.")
+			So(stored, ShouldNotContainSubstring, testEmail)
+		})
+
+		Convey("Then no key name or owner value contains the email", func() {
+			for _, key := range m.Keys() {
+				So(key, ShouldNotContainSubstring, testEmail)
+			}
+
+			owner, err := m.Get(c.ownerKey(s.ID))
+			So(err, ShouldBeNil)
+			So(owner, ShouldStartWith, "v1.2020-08.")
+			So(owner, ShouldNotContainSubstring, testEmail)
+		})
+
+		Convey("Then the session can be read by ID and by email", func() {
+			byID, err := c.GetByID(testCtx, s.ID)
+			So(err, ShouldBeNil)
+			So(byID.Email, ShouldEqual, testEmail)
+
+			byEmail, err := c.GetByEmail(testCtx, testEmail)
+			So(err, ShouldBeNil)
+			So(byEmail.ID, ShouldEqual, s.ID)
+		})
+
+		Convey("When the key is rotated and the session is accessed", func() {
+			rotated := newClient(map[string][]byte{"2020-08": oldKey, "2020-09": newKey}, "2020-09")
+			_, err := rotated.GetByID(testCtx, s.ID)
+			So(err, ShouldBeNil)
+
+			Convey("Then the session is re-encrypted with the new key", func() {
+				stored, err := m.Get(rotated.idKey(s.ID))
+				So(err, ShouldBeNil)
+				So(stored, ShouldStartWith, "v1.2020-09.")
+			})
+		})
+
+		Convey("When the session's owner was stored unencrypted and the session is refreshed", func() {
+			So(m.Set(c.ownerKey(s.ID), testEmail), ShouldBeNil)
+			_, err := c.Refresh(testCtx, s.ID)
+
+			Convey("Then the session is refreshed and its owner encrypted", func() {
+				So(err, ShouldBeNil)
+				owner, err := m.Get(c.ownerKey(s.ID))
+				So(err, ShouldBeNil)
+				So(owner, ShouldStartWith, "v1.2020-08.")
+			})
+		})
+
+		Convey("When the session is accessed without the key being rotated", func() {
+			before, err := m.Get(c.idKey(s.ID))
+			So(err, ShouldBeNil)
+			_, err = c.GetByID(testCtx, s.ID)
+			So(err, ShouldBeNil)
+
+			Convey("Then the session is not rewritten", func() {
+				after, err := m.Get(c.idKey(s.ID))
+				So(err, ShouldBeNil)
+				So(after, ShouldEqual, before)
+			})
+		})
+	})
+
+	Convey("Given sessions stored before encryption was enabled", t, func() {
+		m, err := miniredis.Run()
+		So(err, ShouldBeNil)
+		defer m.Close()
+		m.RequireAuth("password")
+
+		plain, err := New(Config{Addr: m.Addr(), Password: "password", TTL: testTTL})
+		So(err, ShouldBeNil)
+		older := newTestSession("older", time.Now().Add(-time.Minute))
+		So(plain.SetSession(testCtx, older), ShouldBeNil)
+		So(plain.SetSession(testCtx, newTestSession(testSessionID, time.Now())), ShouldBeNil)
+		So(m.Exists(testUserKey), ShouldBeTrue)
+
+		codec, err := NewAESGCMCodec(EncryptionConfig{
+			Keys:           map[string][]byte{"2020-08": oldKey},
+			CurrentKeyID:   "2020-08",
+			IndexKey:       indexKey,
+			AllowPlaintext: true,
+		})
+		So(err, ShouldBeNil)
+		c, err := New(Config{Addr: m.Addr(), Password: "password", TTL: testTTL, Codec: codec})
+		So(err, ShouldBeNil)
+
+		Convey("When encryption is enabled and the user's sessions are revoked", func() {
+			revoked, err := c.RevokeByEmail(testCtx, testEmail)
+
+			Convey("Then the sessions indexed by the user's email are revoked", func() {
+				So(err, ShouldBeNil)
+				So(revoked, ShouldEqual, 2)
+				So(m.Exists(testIDKey), ShouldBeFalse)
+				So(m.Exists("session:id:older"), ShouldBeFalse)
+				So(m.Exists(testUserKey), ShouldBeFalse)
+			})
+		})
+
+		Convey("When encryption is enabled and a session is created and the user's latest session deleted", func() {
+			So(c.SetSession(testCtx, newTestSession("newest", time.Now().Add(time.Minute))), ShouldBeNil)
+			So(c.DeleteByEmail(testCtx, testEmail), ShouldBeNil)
+
+			Convey("Then the sessions from both indexes are listed oldest first", func() {
+				sessions, err := c.ListByEmail(testCtx, testEmail)
+				So(err, ShouldBeNil)
+				So(sessions, ShouldHaveLength, 2)
+				So(sessions[0].ID, ShouldEqual, "older")
+				So(sessions[1].ID, ShouldEqual, testSessionID)
+			})
+
+			Convey("Then the sessions are moved to the encrypted index once listed", func() {
+				_, err := c.ListByEmail(testCtx, testEmail)
+				So(err, ShouldBeNil)
+				So(m.Exists(testUserKey), ShouldBeFalse)
+				ids, err := m.ZMembers(c.userKey(testEmail))
+				So(err, ShouldBeNil)
+				So(ids, ShouldResemble, []string{"older", testSessionID})
+			})
+		})
+
+		Convey("When encryption is enabled and the latest session is got by email", func() {
+			latest, err := c.GetByEmail(testCtx, testEmail)
+
+			Convey("Then the session indexed by the user's email is found", func() {
+				So(err, ShouldBeNil)
+				So(latest.ID, ShouldEqual, testSessionID)
+			})
+		})
+	})
+}
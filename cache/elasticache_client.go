@@ -2,123 +2,604 @@ package cache
 
 import (
 	"context"
-	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
-	health "github.com/ONSdigital/dp-healthcheck/healthcheck"
+	"github.com/ONSdigital/dp-sessions-api/events"
 	"github.com/ONSdigital/dp-sessions-api/session"
-	"github.com/go-redis/redis"
+	"github.com/ONSdigital/log.go/log"
+	"github.com/go-redis/redis/v8"
 )
 
 var (
-	HealthyMessage       = "elasticache is OK"
-	ErrEmptySessionID    = errors.New("session id required but was empty")
-	ErrEmptySessionEmail = errors.New("session email required but was empty")
-	ErrEmptySession      = errors.New("session is empty")
-	ErrEmptyAddress      = errors.New("address is empty")
-	ErrEmptyPassword     = errors.New("password is empty")
-	ErrInvalidTTL        = errors.New("ttl should not be zero")
-	ErrSessionNotFound   = errors.New("session not found")
+	HealthyMessage            = "elasticache is OK"
+	CircuitOpenMessage        = "elasticache circuit breaker is open"
+	CircuitHalfOpenMessage    = "elasticache circuit breaker is half-open"
+	DegradedMessage           = "elasticache is degraded"
+	ErrEmptySessionID         = errors.New("session id required but was empty")
+	ErrEmptySessionEmail      = errors.New("session email required but was empty")
+	ErrEmptySession           = errors.New("session is empty")
+	ErrEmptyAddress           = errors.New("address is empty")
+	ErrEmptyPassword          = errors.New("password is empty")
+	ErrInvalidTTL             = errors.New("ttl should not be zero")
+	ErrInvalidLifetime        = errors.New("max lifetime should not be negative")
+	ErrSessionNotFound        = errors.New("session not found")
+	ErrSessionExpired         = errors.New("session has exceeded its max lifetime")
+	ErrInvalidMaxSessions     = errors.New("max sessions per user should not be negative")
+	ErrInvalidLimitPolicy     = errors.New("session limit policy should be evict or reject")
+	ErrTooManySessions        = errors.New("user has reached the max number of concurrent sessions")
+	ErrInvalidMode            = errors.New("mode should be standalone, sentinel or cluster")
+	ErrEmptyMasterName        = errors.New("master name is required in sentinel mode")
+	ErrInvalidDatabase        = errors.New("database should be zero in cluster mode")
+	ErrInvalidKeyPrefix       = errors.New("key prefix should not contain a hash tag in cluster mode")
+	ErrInvalidTimeout         = errors.New("timeouts should not be negative")
+	ErrInvalidRetryPolicy     = errors.New("retries and retry backoffs should not be negative")
+	ErrInvalidBreaker         = errors.New("circuit breaker threshold and open timeout should not be negative")
+	ErrInvalidPool            = errors.New("pool size, min idle connections, max connection age and pool timeout should not be negative")
+	ErrInvalidHealthThreshold = errors.New("latency threshold should not be negative and memory threshold should be between 0 and 1")
+)
+
+const (
+	// DefaultKeyPrefix is the namespace used for session keys when Config.KeyPrefix is empty
+	DefaultKeyPrefix = "session:"
+	// DefaultBreakerOpenTimeout is how long the circuit breaker stays open when Config.BreakerOpenTimeout is zero
+	DefaultBreakerOpenTimeout = 10 * time.Second
+
+	idKeyPrefix     = "id:"
+	userKeyPrefix   = "user:"
+	ownerKeyPrefix  = "owner:"
+	accessKeyPrefix = "access:"
+	scanCount       = 100
+
+	// ownerKeyGrace - how long a session's owner key outlives its ID key, giving the ExpirySubscriber time to read it
+	// once the ID key has expired
+	ownerKeyGrace = time.Hour
+
+	// countScanCount - keys are only counted, not deleted, so larger batches are scanned to reduce round trips
+	countScanCount = 1000
+)
+
+// LimitPolicy - determines what happens when a user who already has the max number of concurrent sessions logs in
+type LimitPolicy string
+
+const (
+	// EvictOldest removes the user's oldest sessions to make room for the new one
+	EvictOldest LimitPolicy = "evict"
+	// RejectNew refuses to create the new session
+	RejectNew LimitPolicy = "reject"
+)
+
+// Mode - the kind of redis deployment sessions are stored in
+type Mode string
+
+const (
+	// Standalone connects to the single redis node at Config.Addr
+	Standalone Mode = "standalone"
+	// Sentinel connects to the master named Config.MasterName, found through the sentinels at Config.SeedAddrs, and
+	// follows it when it fails over
+	Sentinel Mode = "sentinel"
+	// Cluster connects to the redis cluster the nodes at Config.SeedAddrs belong to
+	Cluster Mode = "cluster"
 )
 
 type ElasticacheClient struct {
-	client RedisClienter
-	ttl    time.Duration
+	lifetime
+	client      RedisClienter
+	cluster     *redis.ClusterClient
+	observer    CommandObserver
+	maxSessions int
+	limitPolicy LimitPolicy
+	keyPrefix   string
+	hashTags    bool
+	database    int
+	publisher   events.EventPublisher
+	timeout     time.Duration
+	retry       retryingClient
+	breaker     *circuitBreaker
+	pinger      RedisClienter
+	pool        pooler
+	codec       Codec
+
+	accessPublisher events.EventPublisher
+
+	latencyThreshold time.Duration
+	memoryThreshold  float64
+	lastEvicted      int64
+}
+
+// pooler - a go-redis client that reports statistics for its connection pool
+type pooler interface {
+	PoolStats() *redis.PoolStats
 }
 
 // Config - config options for the elasticache client
 type Config struct {
-	Addr     string
-	Password string `json:"-"`
-	Database int
-	TTL      time.Duration
-	TLS      *tls.Config
+	// Mode is the kind of redis deployment to connect to. Defaults to Standalone.
+	Mode Mode
+	// Addr is the address of the redis node in Standalone mode
+	Addr string
+	// SeedAddrs are the addresses of the sentinels in Sentinel mode, or of some of the cluster's nodes in Cluster mode.
+	// Defaults to Addr.
+	SeedAddrs []string
+	// MasterName is the name the sentinels know the master by in Sentinel mode
+	MasterName string
+	Password   string `json:"-"`
+	Database   int
+	TTL        time.Duration
+	// TLS, if set, secures the connection to redis
+	TLS *TLSConfig
+	// KeyPrefix namespaces every key. In Cluster mode each session's keys carry a hash tag of its ID and each user's index
+	// a hash tag of their email, spreading them across the cluster, so the prefix must not contain a hash tag of its own.
+	KeyPrefix string
+	// OperationTimeout bounds each SessionCache operation, across every command it sends, in addition to any deadline
+	// the caller sets. Zero means operations are only bounded by the caller's deadline and the socket timeouts.
+	OperationTimeout time.Duration
+	// DialTimeout, ReadTimeout and WriteTimeout bound establishing a connection to redis and each socket read and write.
+	// Zero uses the go-redis defaults.
+	DialTimeout  time.Duration
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	// PoolSize is the maximum number of connections to redis, to each node in Cluster mode. Zero uses the go-redis
+	// default of 10 connections per CPU.
+	PoolSize int
+	// MinIdleConns is the number of idle connections kept open so that bursts of requests do not wait to dial redis
+	MinIdleConns int
+	// MaxConnAge is the age at which a connection is closed and replaced. Zero means connections are not closed because
+	// of their age.
+	MaxConnAge time.Duration
+	// PoolTimeout is how long a command waits for a connection when every connection is in use. Zero uses the go-redis
+	// default of ReadTimeout plus one second.
+	PoolTimeout time.Duration
+	// LatencyWarningThreshold is the ping latency above which the health check reports WARNING. Zero disables it.
+	LatencyWarningThreshold time.Duration
+	// MemoryWarningThreshold is the fraction of redis maxmemory in use above which the health check reports WARNING.
+	// Zero disables it, as does redis having no maxmemory.
+	MemoryWarningThreshold float64
+	// MaxRetries is the number of times a read that fails because redis could not be reached, or is failing over, is
+	// retried. Writes are never retried. Zero disables retries.
+	MaxRetries int
+	// MinRetryBackoff and MaxRetryBackoff bound the exponential backoff between retries. Default to
+	// DefaultMinRetryBackoff and DefaultMaxRetryBackoff.
+	MinRetryBackoff time.Duration
+	MaxRetryBackoff time.Duration
+	// BreakerFailureThreshold is the number of consecutive failed commands that opens the circuit breaker, after which
+	// commands fail with a *CircuitOpenError without redis being contacted. Zero disables the circuit breaker.
+	BreakerFailureThreshold int
+	// BreakerOpenTimeout is how long the circuit breaker stays open before a probe command is let through. Defaults to
+	// DefaultBreakerOpenTimeout.
+	BreakerOpenTimeout time.Duration
+	// MaxLifetime is the absolute lifetime of a session measured from Session.Start. Zero means sessions only expire
+	// through the sliding TTL.
+	MaxLifetime time.Duration
+	// MaxSessionsPerUser is the number of concurrent sessions a user may hold. Zero means there is no limit.
+	MaxSessionsPerUser int
+	// LimitPolicy is applied when a user at MaxSessionsPerUser starts a new session. Defaults to EvictOldest.
+	LimitPolicy LimitPolicy
+	// Observer, if set, is notified of the duration of every redis command
+	Observer CommandObserver
+	// Codec, if set, encodes sessions before they are stored in redis, for example an AESGCMCodec to encrypt them. It is
+	// not used by the InMemoryCache.
+	Codec Codec
+	// Publisher, if set, is sent an event when a session is accessed, expires or is revoked. Creating a session does
+	// not publish an event, that is left to the caller.
+	Publisher events.EventPublisher
+	// AccessPublisher, if set, is sent the accessed events instead of Publisher. Sessions are accessed on every read so
+	// it should not wait for events to be sent, e.g. one returned by events.BestEffort. A failure to publish an accessed
+	// event is only logged, whichever publisher is used, so it never fails a read.
+	AccessPublisher events.EventPublisher
+}
+
+// validate - checks the session options in c, which are shared by every SessionCache, and applies their defaults
+func (c *Config) validate() error {
+	if c.TTL == 0 {
+		return ErrInvalidTTL
+	}
+
+	if c.MaxLifetime < 0 {
+		return ErrInvalidLifetime
+	}
+
+	if c.MaxSessionsPerUser < 0 {
+		return ErrInvalidMaxSessions
+	}
+
+	switch c.LimitPolicy {
+	case "":
+		c.LimitPolicy = EvictOldest
+	case EvictOldest, RejectNew:
+	default:
+		return ErrInvalidLimitPolicy
+	}
+
+	if c.Publisher == nil {
+		c.Publisher = events.NopPublisher{}
+	}
+
+	if c.AccessPublisher == nil {
+		c.AccessPublisher = c.Publisher
+	}
+
+	return nil
 }
 
 // New - create new session cache client instance
 func New(c Config) (*ElasticacheClient, error) {
-	if c.Addr == "" {
+	switch c.Mode {
+	case "":
+		c.Mode = Standalone
+	case Standalone, Sentinel, Cluster:
+	default:
+		return nil, ErrInvalidMode
+	}
+
+	if len(c.SeedAddrs) == 0 && c.Addr != "" {
+		c.SeedAddrs = []string{c.Addr}
+	}
+
+	if c.Addr == "" && c.Mode == Standalone || len(c.SeedAddrs) == 0 {
 		return nil, ErrEmptyAddress
 	}
 
+	if c.MasterName == "" && c.Mode == Sentinel {
+		return nil, ErrEmptyMasterName
+	}
+
+	if c.Database != 0 && c.Mode == Cluster {
+		return nil, ErrInvalidDatabase
+	}
+
+	if strings.Contains(c.KeyPrefix, "{") && c.Mode == Cluster {
+		return nil, ErrInvalidKeyPrefix
+	}
+
 	if c.Password == "" {
 		return nil, ErrEmptyPassword
 	}
 
-	if c.TTL == 0 {
-		return nil, ErrInvalidTTL
+	if c.OperationTimeout < 0 || c.DialTimeout < 0 || c.ReadTimeout < 0 || c.WriteTimeout < 0 {
+		return nil, ErrInvalidTimeout
+	}
+
+	if c.PoolSize < 0 || c.MinIdleConns < 0 || c.MaxConnAge < 0 || c.PoolTimeout < 0 {
+		return nil, ErrInvalidPool
+	}
+
+	if c.LatencyWarningThreshold < 0 || c.MemoryWarningThreshold < 0 || c.MemoryWarningThreshold > 1 {
+		return nil, ErrInvalidHealthThreshold
+	}
+
+	if c.MaxRetries < 0 || c.MinRetryBackoff < 0 || c.MaxRetryBackoff < 0 {
+		return nil, ErrInvalidRetryPolicy
+	}
+
+	if c.MinRetryBackoff == 0 {
+		c.MinRetryBackoff = DefaultMinRetryBackoff
 	}
 
-	return &ElasticacheClient{
-		client: redis.NewClient(&redis.Options{
-			Addr:      c.Addr,
-			Password:  c.Password,
-			DB:        c.Database,
-			TLSConfig: c.TLS,
-		}),
-		ttl: c.TTL,
-	}, nil
+	if c.MaxRetryBackoff == 0 {
+		c.MaxRetryBackoff = DefaultMaxRetryBackoff
+	}
+
+	if c.BreakerFailureThreshold < 0 || c.BreakerOpenTimeout < 0 {
+		return nil, ErrInvalidBreaker
+	}
+
+	if c.BreakerOpenTimeout == 0 {
+		c.BreakerOpenTimeout = DefaultBreakerOpenTimeout
+	}
+
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+
+	if c.KeyPrefix == "" {
+		c.KeyPrefix = DefaultKeyPrefix
+	}
+
+	if c.Codec == nil {
+		c.Codec = plainCodec{}
+	}
+
+	tlsConfig, err := c.TLS.build()
+	if err != nil {
+		return nil, err
+	}
+
+	var client RedisClienter
+	var cluster *redis.ClusterClient
+	switch c.Mode {
+	case Sentinel:
+		client = redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:    c.MasterName,
+			SentinelAddrs: c.SeedAddrs,
+			Password:      c.Password,
+			DB:            c.Database,
+			TLSConfig:     tlsConfig,
+			DialTimeout:   c.DialTimeout,
+			ReadTimeout:   c.ReadTimeout,
+			WriteTimeout:  c.WriteTimeout,
+			MaxRetries:    -1,
+			PoolSize:      c.PoolSize,
+			MinIdleConns:  c.MinIdleConns,
+			MaxConnAge:    c.MaxConnAge,
+			PoolTimeout:   c.PoolTimeout,
+		})
+	case Cluster:
+		cluster = redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:        c.SeedAddrs,
+			Password:     c.Password,
+			TLSConfig:    tlsConfig,
+			DialTimeout:  c.DialTimeout,
+			ReadTimeout:  c.ReadTimeout,
+			WriteTimeout: c.WriteTimeout,
+			MaxRetries:   -1,
+			PoolSize:     c.PoolSize,
+			MinIdleConns: c.MinIdleConns,
+			MaxConnAge:   c.MaxConnAge,
+			PoolTimeout:  c.PoolTimeout,
+		})
+		client = cluster
+	default:
+		client = redis.NewClient(&redis.Options{
+			Addr:         c.Addr,
+			Password:     c.Password,
+			DB:           c.Database,
+			TLSConfig:    tlsConfig,
+			DialTimeout:  c.DialTimeout,
+			ReadTimeout:  c.ReadTimeout,
+			WriteTimeout: c.WriteTimeout,
+			MaxRetries:   -1,
+			PoolSize:     c.PoolSize,
+			MinIdleConns: c.MinIdleConns,
+			MaxConnAge:   c.MaxConnAge,
+			PoolTimeout:  c.PoolTimeout,
+		})
+	}
+
+	cache := &ElasticacheClient{
+		lifetime:    lifetime{ttl: c.TTL, maxLifetime: c.MaxLifetime},
+		cluster:     cluster,
+		observer:    c.Observer,
+		maxSessions: c.MaxSessionsPerUser,
+		limitPolicy: c.LimitPolicy,
+		keyPrefix:   c.KeyPrefix,
+		hashTags:    c.Mode == Cluster,
+		database:    c.Database,
+		publisher:   c.Publisher,
+		codec:       c.Codec,
+		timeout:     c.OperationTimeout,
+		retry:       retryingClient{maxRetries: c.MaxRetries, minBackoff: c.MinRetryBackoff, maxBackoff: c.MaxRetryBackoff},
+
+		accessPublisher: c.AccessPublisher,
+
+		latencyThreshold: c.LatencyWarningThreshold,
+		memoryThreshold:  c.MemoryWarningThreshold,
+		lastEvicted:      -1,
+	}
+	if c.BreakerFailureThreshold > 0 {
+		cache.breaker = newCircuitBreaker(c.BreakerFailureThreshold, c.BreakerOpenTimeout)
+	}
+	cache.pool = client.(pooler)
+	cache.client = cache.wrap(client)
+	cache.pinger = cache.guard(instrument(client, cache.observer))
+	return cache, nil
+}
+
+// wrap - decorates client so that its commands are observed, its reads retried and all of its commands guarded by the
+// circuit breaker. Each retry is observed, but the breaker only sees a read's final outcome.
+func (c *ElasticacheClient) wrap(client RedisClienter) RedisClienter {
+	client = instrument(client, c.observer)
+	if c.retry.maxRetries > 0 {
+		retrying := c.retry
+		retrying.RedisClienter = client
+		client = &retrying
+	}
+	return c.guard(client)
+}
+
+// guard - guards client's commands with the circuit breaker, if there is one
+func (c *ElasticacheClient) guard(client RedisClienter) RedisClienter {
+	if c.breaker != nil {
+		client = &breakerClient{client: client, breaker: c.breaker}
+	}
+	return client
 }
 
-// SetSession - add session to elasticache
-func (c *ElasticacheClient) SetSession(s *session.Session) error {
+// SetSession - add session to elasticache. If a session limit is configured the session is only indexed against its
+// user once admit has checked the limit, and is removed again if the limit rejects it. A revoked event is published for
+// each session evicted to make room for it, as publish describes.
+func (c *ElasticacheClient) SetSession(ctx context.Context, s *session.Session) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
 	if s == nil {
 		return ErrEmptySession
 	}
 
-	sJSON, err := s.MarshalJSON()
+	ttl := c.expiration(s, time.Now())
+	if ttl <= 0 {
+		return ErrSessionExpired
+	}
+	c.setExpiry(s)
+
+	sJSON, err := c.encode(s)
 	if err != nil {
 		return err
 	}
 
-	// Add session using ID as key
-	err = c.client.Set(s.ID, sJSON, c.ttl).Err()
+	owner, err := c.encodeOwner(s.ID, s.Email)
 	if err != nil {
-		return fmt.Errorf("elasticache client.Set returned an unexpected error: %w", err)
+		return err
+	}
+
+	// Remove the user's expired sessions from their index first so that they do not count towards the limit
+	if c.maxSessions > 0 {
+		if _, err = c.userSessions(ctx, s.Email); err != nil {
+			return err
+		}
 	}
 
-	// Add session using email as key
-	err = c.client.Set(s.Email, sJSON, c.ttl).Err()
+	// Add session using ID as key in a single MULTI/EXEC transaction, indexing it against the user's email in the same
+	// transaction when there is no limit to check. In Cluster mode the user's index is in a different hash slot, so
+	// go-redis sends it in a transaction of its own. The owner key records the email for the ID so the index can be
+	// cleaned up once the session expires, and the access key the times refresh needs so it does not read the session.
+	userKey := c.userKey(s.Email)
+	_, err = c.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, c.idKey(s.ID), sJSON, ttl)
+		pipe.Set(ctx, c.ownerKey(s.ID), owner, c.ttl+ownerKeyGrace)
+		pipe.HSet(ctx, c.accessKey(s.ID), startField, millis(s.Start), lastAccessedField, millis(s.LastAccessed))
+		pipe.PExpire(ctx, c.accessKey(s.ID), ttl)
+		if c.maxSessions == 0 {
+			pipe.ZAdd(ctx, userKey, &redis.Z{Score: score(s), Member: s.ID})
+			pipe.Expire(ctx, userKey, c.ttl)
+		}
+		return nil
+	})
 	if err != nil {
 		return fmt.Errorf("elasticache client.Set returned an unexpected error: %w", err)
 	}
 
+	if c.maxSessions == 0 {
+		return nil
+	}
+
+	// A session that is not admitted, whether the limit rejected it or the script failed, has its keys removed so it is
+	// not left behind outside its user's index. The removal is not bound by ctx, which may be what failed the script. If
+	// the script did index the session before failing, the index entry is removed when the index is next read.
+	evict, err := c.admit(ctx, s)
+	if err != nil {
+		cleanupCtx, cancel := c.withTimeout(context.Background())
+		defer cancel()
+		if delErr := c.client.Del(cleanupCtx, c.idKey(s.ID), c.accessKey(s.ID), c.ownerKey(s.ID)).Err(); delErr != nil {
+			log.Event(ctx, "failed to remove session that was not admitted", log.ERROR, log.Error(delErr), log.Data{"session_id": s.ID})
+		}
+		return err
+	}
+
+	if len(evict) == 0 {
+		return nil
+	}
+
+	// The evicted sessions are already out of the index, so their keys are removed after the script rather than in it.
+	// The new session has been admitted by then, so the removal is not bound by ctx, which may have run out, and a
+	// failure is only logged rather than reported for a session that exists. The evicted sessions are revoked either way.
+	cleanupCtx, cancel := c.withTimeout(context.Background())
+	defer cancel()
+	_, err = c.client.TxPipelined(cleanupCtx, func(pipe redis.Pipeliner) error {
+		c.delSessions(cleanupCtx, pipe, evict)
+		return nil
+	})
+	if err != nil {
+		log.Event(ctx, "failed to remove evicted sessions", log.ERROR, log.Error(err), log.Data{"session_id": s.ID, "evicted": evict})
+	}
+
+	c.publish(ctx, events.Revoked, s.Email, evict...)
 	return nil
 }
 
 // GetByID - gets a session from elasticache by the Session ID.
 // Returns cache.ErrSessionNotFound if the session with the specified ID does not exist.
-func (c *ElasticacheClient) GetByID(id string) (*session.Session, error) {
+func (c *ElasticacheClient) GetByID(ctx context.Context, id string) (*session.Session, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
 	if id == "" {
 		return nil, ErrEmptySessionID
 	}
 
-	msg, err := c.client.Get(id).Result()
+	s, stored, err := c.readSession(ctx, id)
 	if err != nil {
-		if err == redis.Nil {
-			return nil, ErrSessionNotFound
-		}
 		return nil, err
 	}
 
-	var s *session.Session
+	// Refresh TTL on access and update LastAccessed in session
+	_, err = c.refresh(ctx, id, s)
+	if err != nil {
+		return nil, err
+	}
+
+	// The session itself is only rewritten when it is stored in an out of date encoding, so that sessions are moved
+	// onto the current encryption key as they are used. The session has been refreshed, so a failure is only logged.
+	if codec, ok := c.codec.(staleCodec); ok && codec.Stale([]byte(stored)) {
+		if err = c.rewrite(ctx, s); err != nil {
+			log.Event(ctx, "failed to re-encode session", log.WARN, log.Error(err), log.Data{"session_id": id})
+		}
+	}
+
+	return s, nil
+}
+
+// Refresh - extends the TTL of the session with the specified ID and updates its LastAccessed time without the
+// session being read. Returns the time the session will now expire, or cache.ErrSessionNotFound if the session with the
+// specified ID does not exist or has expired.
+func (c *ElasticacheClient) Refresh(ctx context.Context, id string) (time.Time, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	if id == "" {
+		return time.Time{}, ErrEmptySessionID
+	}
+
+	return c.refresh(ctx, id, nil)
+}
+
+// UpdateAttributes - merges attributes into the attributes of the session with the specified ID, as described by
+// session.MergeAttributes, and returns the updated session. Updating a session counts as accessing it so its TTL is
+// refreshed. Returns cache.ErrSessionNotFound if the session with the specified ID does not exist or has expired.
+//
+// Concurrent updates to the same session are not isolated from one another; the last write wins.
+func (c *ElasticacheClient) UpdateAttributes(ctx context.Context, id string, attributes map[string]interface{}) (*session.Session, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	if id == "" {
+		return nil, ErrEmptySessionID
+	}
 
-	err = json.Unmarshal([]byte(msg), &s)
+	s, err := c.getSession(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 
-	// Refresh TTL on access and update LastAccessed in session
-	s.LastAccessed = time.Now()
-	err = c.Expire(s.ID, c.ttl)
+	if err = s.MergeAttributes(attributes); err != nil {
+		return nil, err
+	}
+
+	if _, err = c.refresh(ctx, id, s); err != nil {
+		return nil, err
+	}
+
+	if err = c.rewrite(ctx, s); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// GetByEmail - gets the most recently started session from elasticache for the email address.
+// Returns cache.ErrSessionNotFound if a session with the specified email does not exist.
+func (c *ElasticacheClient) GetByEmail(ctx context.Context, email string) (*session.Session, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	if email == "" {
+		return nil, ErrEmptySessionEmail
+	}
+
+	s, err := c.latestSession(ctx, email)
 	if err != nil {
 		return nil, err
 	}
 
-	err = c.Expire(s.Email, c.ttl)
+	// Refresh TTL on access and update LastAccessed in session
+	_, err = c.refresh(ctx, s.ID, s)
 	if err != nil {
 		return nil, err
 	}
@@ -126,64 +607,717 @@ func (c *ElasticacheClient) GetByID(id string) (*session.Session, error) {
 	return s, nil
 }
 
-// GetByEmail - gets a session from elasticache by the email address.
-// Returns cache.ErrSessionNotFound if a session with the specified email does not exist.
-func (c *ElasticacheClient) GetByEmail(email string) (*session.Session, error) {
+// ListByEmail - gets every active session from elasticache for the email address, oldest first. Listing sessions
+// does not count as accessing them so their TTLs are not refreshed.
+func (c *ElasticacheClient) ListByEmail(ctx context.Context, email string) ([]*session.Session, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
 	if email == "" {
 		return nil, ErrEmptySessionEmail
 	}
 
-	msg, err := c.client.Get(email).Result()
+	return c.userSessions(ctx, email)
+}
+
+// DeleteByID - removes the session with the specified ID from elasticache, along with its entry in the user's index.
+// Returns cache.ErrSessionNotFound if the session with the specified ID does not exist.
+func (c *ElasticacheClient) DeleteByID(ctx context.Context, id string) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	if id == "" {
+		return ErrEmptySessionID
+	}
+
+	s, err := c.getSession(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return c.revokeSession(ctx, s)
+}
+
+// Discard - removes the session with the specified ID as DeleteByID does but without publishing a revoked event, for
+// undoing SetSession when the creation of a session cannot be completed. Returns cache.ErrSessionNotFound if the
+// session with the specified ID does not exist.
+func (c *ElasticacheClient) Discard(ctx context.Context, id string) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	if id == "" {
+		return ErrEmptySessionID
+	}
+
+	s, err := c.getSession(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return c.deleteSession(ctx, s)
+}
+
+// DeleteByEmail - removes the most recently started session for the specified email from elasticache.
+// Returns cache.ErrSessionNotFound if a session with the specified email does not exist.
+func (c *ElasticacheClient) DeleteByEmail(ctx context.Context, email string) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	if email == "" {
+		return ErrEmptySessionEmail
+	}
+
+	s, err := c.latestSession(ctx, email)
+	if err != nil {
+		return err
+	}
+
+	return c.revokeSession(ctx, s)
+}
+
+// RevokeByEmail - removes every session for the specified email from elasticache, along with their entries in the
+// user's index, and returns the number of sessions that were revoked. Sessions belonging to other users are untouched.
+func (c *ElasticacheClient) RevokeByEmail(ctx context.Context, email string) (int, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	if email == "" {
+		return 0, ErrEmptySessionEmail
+	}
+
+	userKeys := c.userKeys(email)
+
+	ids, _, err := c.indexedIDs(ctx, userKeys)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	// Only the IDs that were read are removed from the index so a session created concurrently is not orphaned
+	cmds, err := c.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		c.delSessions(ctx, pipe, ids)
+		for _, key := range userKeys {
+			pipe.ZRem(ctx, key, members(ids)...)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("elasticache client.Del returned an unexpected error: %w", err)
+	}
+
+	c.publish(ctx, events.Revoked, email, ids...)
+
+	var revoked int
+	for i := range ids {
+		if del, ok := cmds[2*i].(*redis.IntCmd); ok {
+			revoked += int(del.Val())
+		}
+	}
+	return revoked, nil
+}
+
+// DeleteAll - removes all sessions from elasticache. Only keys within the configured key prefix are removed, so other
+// data stored on the same instance is left untouched. Keys are deleted without being read so no revoked events are
+// published.
+func (c *ElasticacheClient) DeleteAll(ctx context.Context) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	return c.forEachMaster(ctx, func(client RedisClienter) error {
+		var cursor uint64
+		for {
+			keys, next, err := client.Scan(ctx, cursor, keyPattern(c.keyPrefix), scanCount).Result()
+			if err != nil {
+				return err
+			}
+
+			if err = c.del(ctx, client, keys); err != nil {
+				return err
+			}
+
+			if next == 0 {
+				return nil
+			}
+			cursor = next
+		}
+	})
+}
+
+// del - removes keys from client. In Cluster mode the keys are in different hash slots, which a single DEL cannot span,
+// so each is removed by its own command.
+func (c *ElasticacheClient) del(ctx context.Context, client RedisClienter, keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	if !c.hashTags {
+		return client.Del(ctx, keys...).Err()
+	}
+
+	for _, key := range keys {
+		if err := client.Del(ctx, key).Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CountSessions - returns the number of sessions in elasticache. Every session key is scanned, so this should only be
+// called periodically, e.g. on the interval the active sessions metric is refreshed on.
+func (c *ElasticacheClient) CountSessions(ctx context.Context) (int, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	var count int64
+	err := c.forEachMaster(ctx, func(client RedisClienter) error {
+		var cursor uint64
+		for {
+			keys, next, err := client.Scan(ctx, cursor, keyPattern(c.keyPrefix+idKeyPrefix), countScanCount).Result()
+			if err != nil {
+				return err
+			}
+			atomic.AddInt64(&count, int64(len(keys)))
+
+			if next == 0 {
+				return nil
+			}
+			cursor = next
+		}
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return int(count), nil
+}
+
+// forEachMaster - calls fn with a client for each master node, concurrently in Cluster mode, returning the first error
+// encountered. Commands that are not sent to the node holding a particular key, such as SCAN and SUBSCRIBE, are only
+// handled by the node they are sent to so must be sent to every master when the sessions may be spread across them.
+func (c *ElasticacheClient) forEachMaster(ctx context.Context, fn func(client RedisClienter) error) error {
+	if c.cluster == nil {
+		return fn(c.client)
+	}
+
+	return c.cluster.ForEachMaster(ctx, func(ctx context.Context, master *redis.Client) error {
+		return fn(c.wrap(master))
+	})
+}
+
+// withTimeout - returns a copy of ctx bounded by the configured operation timeout
+func (c *ElasticacheClient) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.timeout == 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, c.timeout)
+}
+
+// IsTimeout - reports whether err was caused by redis not responding in time, either within the operation timeout,
+// the caller's deadline or a socket read or write timeout
+func IsTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// hashTag - returns s as a hash tag in Cluster mode, so that keys tagged with the same value map to the same hash slot
+// and can be used together in scripts and transactions, and unchanged otherwise
+func (c *ElasticacheClient) hashTag(s string) string {
+	if c.hashTags {
+		return "{" + s + "}"
+	}
+	return s
+}
+
+// globEscaper - escapes the characters redis treats as special in a glob pattern
+var globEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`)
+
+// keyPattern - returns the SCAN MATCH pattern for the keys beginning with prefix. Glob characters in prefix are escaped
+// so they match literally, and keys outside the namespace a prefix such as "sessions*" describes are never matched.
+func keyPattern(prefix string) string {
+	return globEscaper.Replace(prefix) + "*"
+}
+
+// idKey - returns the namespaced cache key for a session ID
+func (c *ElasticacheClient) idKey(id string) string {
+	return c.keyPrefix + idKeyPrefix + c.hashTag(id)
+}
+
+// sessionID - returns the session ID an ID key is for, or false if key is not an ID key
+func (c *ElasticacheClient) sessionID(key string) (string, bool) {
+	id := strings.TrimPrefix(key, c.keyPrefix+idKeyPrefix)
+	if id == key {
+		return "", false
+	}
+	if c.hashTags {
+		if !strings.HasPrefix(id, "{") || !strings.HasSuffix(id, "}") {
+			return "", false
+		}
+		id = id[1 : len(id)-1]
+	}
+	return id, true
+}
+
+// userKey - returns the namespaced cache key for the sorted set indexing a user's session IDs by start time. The key is
+// named with the email unless the codec derives an index from it.
+func (c *ElasticacheClient) userKey(email string) string {
+	index := email
+	if codec, ok := c.codec.(indexCodec); ok {
+		index = codec.Index(email)
+	}
+	return c.keyPrefix + userKeyPrefix + c.hashTag(index)
+}
+
+// userKeys - returns the keys of every index that may hold a user's sessions: the key userKey returns and, while the
+// codec still reads sessions stored before encryption was enabled, the key those sessions were indexed by their email
+// under. New sessions are only added to the first.
+func (c *ElasticacheClient) userKeys(email string) []string {
+	keys := []string{c.userKey(email)}
+	if codec, ok := c.codec.(plaintextCodec); ok && codec.AllowsPlaintext() {
+		if legacy := c.keyPrefix + userKeyPrefix + c.hashTag(email); legacy != keys[0] {
+			keys = append(keys, legacy)
+		}
+	}
+	return keys
+}
+
+// indexedIDs - returns the IDs in the indexes with the keys provided, oldest first within each index, along with the
+// IDs that were only found in an index other than the first
+func (c *ElasticacheClient) indexedIDs(ctx context.Context, keys []string) ([]string, map[string]bool, error) {
+	var ids []string
+	legacy := make(map[string]bool)
+	seen := make(map[string]bool)
+	for i, key := range keys {
+		indexed, err := c.client.ZRange(ctx, key, 0, -1).Result()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for _, id := range indexed {
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			ids = append(ids, id)
+			if i > 0 {
+				legacy[id] = true
+			}
+		}
+	}
+	return ids, legacy, nil
+}
+
+// accessKey - returns the namespaced cache key holding the times the session with the ID id started and was last
+// accessed, which expires along with its ID key
+func (c *ElasticacheClient) accessKey(id string) string {
+	return c.keyPrefix + accessKeyPrefix + c.hashTag(id)
+}
+
+// delSessions - queues the removal of the ID and access keys of each of the sessions with the IDs ids. Each session's
+// keys are removed by their own commands as, in Cluster mode, different sessions' keys are in different hash slots. The
+// first command queued for each session removes its ID key.
+func (c *ElasticacheClient) delSessions(ctx context.Context, pipe redis.Pipeliner, ids []string) {
+	for _, id := range ids {
+		pipe.Del(ctx, c.idKey(id))
+		pipe.Del(ctx, c.accessKey(id))
+	}
+}
+
+// ownerKey - returns the namespaced cache key holding the email of the user a session ID belongs to, encoded with the
+// codec. It is not removed when a session is deleted but expires ownerKeyGrace after the session would have.
+func (c *ElasticacheClient) ownerKey(id string) string {
+	return c.keyPrefix + ownerKeyPrefix + c.hashTag(id)
+}
+
+// getSession - gets the session with the ID id without refreshing its TTL
+func (c *ElasticacheClient) getSession(ctx context.Context, id string) (*session.Session, error) {
+	s, _, err := c.readSession(ctx, id)
+	return s, err
+}
+
+// readSession - gets the session with the ID id, as getSession does, along with the value it is stored as
+func (c *ElasticacheClient) readSession(ctx context.Context, id string) (*session.Session, string, error) {
+	msg, err := c.client.Get(ctx, c.idKey(id)).Result()
 	if err != nil {
 		if err == redis.Nil {
-			return nil, ErrSessionNotFound
+			return nil, "", ErrSessionNotFound
 		}
+		return nil, "", err
+	}
+
+	s, err := c.decode(id, msg)
+	if err != nil {
+		return nil, "", err
+	}
+	return s, msg, nil
+}
+
+// rewrite - encodes s and writes it back to its ID key, keeping the TTL refresh gave it. SET XX will not recreate the
+// session if it has expired since it was refreshed.
+func (c *ElasticacheClient) rewrite(ctx context.Context, s *session.Session) error {
+	sJSON, err := c.encode(s)
+	if err != nil {
+		return err
+	}
+
+	return c.client.SetXX(ctx, c.idKey(s.ID), sJSON, s.ExpiresAt.Sub(s.LastAccessed)).Err()
+}
+
+// encode - marshals s and encodes it with the codec, ready to be stored
+func (c *ElasticacheClient) encode(s *session.Session) ([]byte, error) {
+	sJSON, err := s.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	return c.codec.Encode(s.ID, sJSON)
+}
+
+// decode - decodes the stored value of the session with the ID id and unmarshals it
+func (c *ElasticacheClient) decode(id string, stored string) (*session.Session, error) {
+	sJSON, err := c.codec.Decode(id, []byte(stored))
+	if err != nil {
 		return nil, err
 	}
 
 	var s *session.Session
+	if err = json.Unmarshal(sJSON, &s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// encodeOwner - encodes email with the codec, ready to be stored in the owner key of the session with the ID id. The
+// value is bound to the owner key rather than the session, so it cannot be mistaken for the session itself.
+func (c *ElasticacheClient) encodeOwner(id string, email string) (string, error) {
+	owner, err := c.codec.Encode(ownerKeyPrefix+id, []byte(email))
+	if err != nil {
+		return "", err
+	}
+	return string(owner), nil
+}
 
-	err = json.Unmarshal([]byte(msg), &s)
+// decodeOwner - decodes the value stored in the owner key of the session with the ID id
+func (c *ElasticacheClient) decodeOwner(id string, stored string) (string, error) {
+	email, err := c.codec.Decode(ownerKeyPrefix+id, []byte(stored))
+	if err != nil {
+		return "", err
+	}
+	return string(email), nil
+}
+
+// userSessions - gets the active sessions for email, oldest first, without refreshing their TTLs. Each session's
+// LastAccessed time is read from its access key, as refresh does not rewrite the session. Index entries for sessions
+// that have expired are removed from the user's index. Sessions that have exceeded their max lifetime but are still
+// stored are removed and an expired event published for them; sessions whose keys have already expired are reported by
+// the ExpirySubscriber. Active sessions found in the index named by their user's email, as sessions were indexed before
+// encryption was enabled, are moved to the index userKey names.
+func (c *ElasticacheClient) userSessions(ctx context.Context, email string) ([]*session.Session, error) {
+	userKeys := c.userKeys(email)
+
+	ids, legacy, err := c.indexedIDs(ctx, userKeys)
 	if err != nil {
 		return nil, err
 	}
 
-	// Refresh TTL on access and update LastAccessed in session
-	s.LastAccessed = time.Now()
-	err = c.Expire(s.Email, c.ttl)
+	sessions := make([]*session.Session, 0, len(ids))
+	if len(ids) == 0 {
+		return sessions, nil
+	}
+
+	cmds, err := c.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, id := range ids {
+			pipe.Get(ctx, c.idKey(id))
+			pipe.HGet(ctx, c.accessKey(id), lastAccessedField)
+		}
+		return nil
+	})
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var stale, expired []string
+	var moved []*redis.Z
+	for i, id := range ids {
+		msg, err := cmds[2*i].(*redis.StringCmd).Result()
+		if err == redis.Nil {
+			stale = append(stale, id)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		s, err := c.decode(id, msg)
+		if err != nil {
+			return nil, err
+		}
+
+		lastAccessed, err := cmds[2*i+1].(*redis.StringCmd).Int64()
+		if err != nil && err != redis.Nil {
+			return nil, err
+		}
+		if err == nil {
+			s.LastAccessed = fromMillis(lastAccessed)
+		}
+
+		if c.expiration(s, now) <= 0 {
+			stale = append(stale, ids[i])
+			expired = append(expired, ids[i])
+			continue
+		}
+
+		c.setExpiry(s)
+		sessions = append(sessions, s)
+		if legacy[id] {
+			moved = append(moved, &redis.Z{Score: score(s), Member: id})
+		}
+	}
+
+	if len(stale) > 0 || len(moved) > 0 {
+		_, err = c.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			if len(stale) > 0 {
+				c.delSessions(ctx, pipe, stale)
+				for _, key := range userKeys {
+					pipe.ZRem(ctx, key, members(stale)...)
+				}
+			}
+			if len(moved) > 0 {
+				pipe.ZAdd(ctx, userKeys[0], moved...)
+				pipe.Expire(ctx, userKeys[0], c.ttl)
+				for _, key := range userKeys[1:] {
+					pipe.ZRem(ctx, key, zMembers(moved)...)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		c.publish(ctx, events.Expired, email, expired...)
+	}
+
+	if len(legacy) > 0 {
+		sort.SliceStable(sessions, func(i, j int) bool { return sessions[i].Start.Before(sessions[j].Start) })
+	}
+
+	return sessions, nil
+}
+
+// latestSession - gets the most recently started active session for email without refreshing its TTL
+func (c *ElasticacheClient) latestSession(ctx context.Context, email string) (*session.Session, error) {
+	sessions, err := c.userSessions(ctx, email)
 	if err != nil {
 		return nil, err
 	}
 
-	err = c.Expire(s.ID, c.ttl)
+	if len(sessions) == 0 {
+		return nil, ErrSessionNotFound
+	}
+
+	return sessions[len(sessions)-1], nil
+}
+
+// admit - indexes s against its user with admitScript, which enforces the session limit atomically. Returns the IDs of
+// the oldest sessions evicted from the index to make room for it, or cache.ErrTooManySessions if the user is at the
+// limit and the limit policy rejects new sessions.
+func (c *ElasticacheClient) admit(ctx context.Context, s *session.Session) ([]string, error) {
+	evict := 0
+	if c.limitPolicy == EvictOldest {
+		evict = 1
+	}
+
+	result, err := admitScript.run(ctx, c.client, []string{c.userKey(s.Email)}, s.ID, score(s), c.maxSessions, evict,
+		c.ttl.Milliseconds()).Slice()
 	if err != nil {
 		return nil, err
 	}
 
-	return s, nil
+	if status, _ := result[0].(int64); status == admitRejected {
+		return nil, ErrTooManySessions
+	}
+
+	ids := make([]string, 0, len(result)-1)
+	for _, id := range result[1:] {
+		if id, ok := id.(string); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
 }
 
-// DeleteAll - removes all items from elasticache
-func (c *ElasticacheClient) DeleteAll() error {
-	return c.client.FlushAll().Err()
+// refresh - extends the TTL of the session with the ID id, along with its user's index, and records when it was last
+// accessed with refreshScript, so the session is neither read nor rewritten. s is the session, if the caller has read
+// it, which has its LastAccessed, ExpiresAt and Deadline updated to match. Returns the time the session will now expire.
+// If the session has exceeded its max lifetime it is removed and cache.ErrSessionNotFound is returned.
+//
+// A session stored before access keys were introduced, or whose owner was stored before owners were encoded, is read,
+// if s is nil, so its access and owner keys can be written.
+//
+// An accessed event is published for a refreshed session, as publishAccessed describes, and an expired event for a
+// session that is removed.
+func (c *ElasticacheClient) refresh(ctx context.Context, id string, s *session.Session) (time.Time, error) {
+	now, err := session.FormatTime(time.Now().UTC())
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var start, owner, email string
+	if s != nil {
+		if owner, err = c.encodeOwner(id, s.Email); err != nil {
+			return time.Time{}, err
+		}
+		start, email = strconv.FormatInt(millis(s.Start), 10), s.Email
+	}
+
+	keys := []string{c.idKey(id), c.accessKey(id), c.ownerKey(id)}
+	result, err := refreshScript.run(ctx, c.client, keys, millis(now), c.ttl.Milliseconds(), c.maxLifetime.Milliseconds(),
+		(c.ttl + ownerKeyGrace).Milliseconds(), start, owner).Slice()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	ttl, _ := result[0].(int64)
+	if ttl >= 0 && s == nil {
+		// An owner stored before owners were encoded cannot be decoded, so is treated as unknown and replaced
+		stored, _ := result[1].(string)
+		if email, err = c.decodeOwner(id, stored); err != nil {
+			ttl = refreshUnknown
+		}
+	}
+	switch {
+	case ttl == refreshUnknown && s == nil:
+		if s, err = c.getSession(ctx, id); err != nil {
+			return time.Time{}, err
+		}
+		return c.refresh(ctx, id, s)
+	case ttl < 0:
+		return time.Time{}, ErrSessionNotFound
+	case ttl == 0:
+		expired := &session.Session{ID: id, Email: email}
+		if err := c.deleteSession(ctx, expired); err != nil {
+			if err != ErrSessionNotFound {
+				return time.Time{}, err
+			}
+			return time.Time{}, ErrSessionNotFound
+		}
+		c.publish(ctx, events.Expired, email, id)
+		return time.Time{}, ErrSessionNotFound
+	}
+
+	for _, userKey := range c.userKeys(email) {
+		if err = c.client.Expire(ctx, userKey, c.ttl).Err(); err != nil {
+			return time.Time{}, err
+		}
+	}
+
+	if s != nil {
+		s.LastAccessed = now
+		c.setExpiry(s)
+	}
+
+	publishAccessed(ctx, c.accessPublisher, events.New(events.Accessed, id, email))
+
+	return now.Add(time.Duration(ttl) * time.Millisecond), nil
 }
 
-// Ping - checks the connection to elasticache
-func (c *ElasticacheClient) Ping() error {
-	return c.client.Ping().Err()
+// deleteSession - removes the session's ID and access keys and its entry in the user's index
+func (c *ElasticacheClient) deleteSession(ctx context.Context, s *session.Session) error {
+	cmds, err := c.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, c.idKey(s.ID))
+		pipe.Del(ctx, c.accessKey(s.ID))
+		for _, userKey := range c.userKeys(s.Email) {
+			pipe.ZRem(ctx, userKey, s.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("elasticache client.Del returned an unexpected error: %w", err)
+	}
+
+	if del, ok := cmds[0].(*redis.IntCmd); ok && del.Val() == 0 {
+		return ErrSessionNotFound
+	}
+
+	return nil
 }
 
-// Expire - sets the expiration of key
-func (c *ElasticacheClient) Expire(key string, expiration time.Duration) error {
-	return c.client.Expire(key, expiration).Err()
+// revokeSession - removes the session as deleteSession does and publishes a revoked event for it
+func (c *ElasticacheClient) revokeSession(ctx context.Context, s *session.Session) error {
+	if err := c.deleteSession(ctx, s); err != nil {
+		return err
+	}
+
+	c.publish(ctx, events.Revoked, s.Email, s.ID)
+	return nil
 }
 
-func (c *ElasticacheClient) Checker(ctx context.Context, state *health.CheckState) error {
-	err := c.Ping()
-	if err != nil {
-		// Generic error
-		return state.Update(health.StatusCritical, err.Error(), 0)
+// publish - publishes an event with reason for each of the sessions with the IDs provided belonging to email. Events
+// are only published once the change they report has been committed, so a failure is logged and the remaining events
+// are still published, whatever the failure policy; failing the request would report a change that was made as not
+// made, and a retry would find nothing left to publish events for.
+func (c *ElasticacheClient) publish(ctx context.Context, reason, email string, ids ...string) {
+	for _, id := range ids {
+		if err := c.publisher.Publish(ctx, events.New(reason, id, email)); err != nil {
+			log.Event(ctx, "failed to publish session event", log.ERROR, log.Error(err), log.Data{"session_id": id, "reason": reason})
+		}
+	}
+}
+
+// publishAccessed - publishes e, an accessed event, with publisher. A failure is only logged, whatever the failure
+// policy, as sessions are accessed on every read and a read must not fail because an accessed event was not published.
+func publishAccessed(ctx context.Context, publisher events.EventPublisher, e events.Event) {
+	if err := publisher.Publish(ctx, e); err != nil {
+		log.Event(ctx, "failed to publish session accessed event", log.WARN, log.Error(err), log.Data{"session_id": e.SessionID})
+	}
+}
+
+// score - returns the sorted set score for a session, ordering a user's sessions by start time
+func score(s *session.Session) float64 {
+	return float64(millis(s.Start))
+}
+
+// millis - returns t in milliseconds since the epoch, as the times in a session's access key are stored
+func millis(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+// fromMillis - returns the UTC time ms milliseconds after the epoch
+func fromMillis(ms int64) time.Time {
+	return time.Unix(0, ms*int64(time.Millisecond)).UTC()
+}
+
+// members - converts session IDs into sorted set members
+func members(ids []string) []interface{} {
+	m := make([]interface{}, len(ids))
+	for i, id := range ids {
+		m[i] = id
+	}
+	return m
+}
+
+// zMembers - returns the members of zs, as ZRem takes them
+func zMembers(zs []*redis.Z) []interface{} {
+	m := make([]interface{}, len(zs))
+	for i, z := range zs {
+		m[i] = z.Member
 	}
-	// Success
-	return state.Update(health.StatusOK, HealthyMessage, 0)
+	return m
+}
+
+// Ping - checks the connection to elasticache
+func (c *ElasticacheClient) Ping(ctx context.Context) error {
+	return c.client.Ping(ctx).Err()
+}
+
+// Expire - sets the expiration of key
+func (c *ElasticacheClient) Expire(ctx context.Context, key string, expiration time.Duration) error {
+	return c.client.Expire(ctx, key, expiration).Err()
 }
@@ -1,12 +1,22 @@
 package cache
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	health "github.com/ONSdigital/dp-healthcheck/healthcheck"
+	"github.com/ONSdigital/dp-sessions-api/events"
 	"github.com/ONSdigital/dp-sessions-api/session"
-	"github.com/go-redis/redis"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
 	. "github.com/smartystreets/goconvey/convey"
 )
 
@@ -15,10 +25,15 @@ const (
 	respLastAccessed = "2020-08-13T08:40:18.652Z"
 	testEmail        = "user@email.com"
 	testSessionID    = "1234"
+	testIDKey        = "session:id:1234"
+	testUserKey      = "session:user:user@email.com"
+	testOwnerKey     = "session:owner:1234"
+	testAccessKey    = "session:access:1234"
 )
 
 var (
-	resp = []byte(`{"id":"1234","email":"user@email.com","start":"2020-08-13T08:40:18.652Z","last_accessed":"2020-08-13T08:40:18.652Z"}`)
+	testCtx = context.Background()
+	resp    = []byte(`{"id":"1234","email":"user@email.com","start":"2020-08-13T08:40:18.652Z","last_accessed":"2020-08-13T08:40:18.652Z"}`)
 )
 
 func TestNewClient(t *testing.T) {
@@ -36,6 +51,27 @@ func TestNewClient(t *testing.T) {
 				So(err, ShouldBeNil)
 				So(c, ShouldNotBeEmpty)
 			})
+
+			Convey("And the default key prefix is used", func() {
+				So(c.idKey(testSessionID), ShouldEqual, testIDKey)
+				So(c.userKey(testEmail), ShouldEqual, testUserKey)
+			})
+		})
+
+		Convey("When a key prefix is provided", func() {
+			c, err := New(Config{
+				Addr:      "123.0.0.1",
+				Password:  testSessionID,
+				Database:  0,
+				TTL:       testTTL,
+				KeyPrefix: "dp-sessions:",
+			})
+
+			Convey("Then session keys are namespaced with the prefix", func() {
+				So(err, ShouldBeNil)
+				So(c.idKey(testSessionID), ShouldEqual, "dp-sessions:id:1234")
+				So(c.userKey(testEmail), ShouldEqual, "dp-sessions:user:user@email.com")
+			})
 		})
 
 	})
@@ -97,13 +133,484 @@ func TestNewClient(t *testing.T) {
 	})
 }
 
+func TestNewClient_SessionLimit(t *testing.T) {
+	Convey("Given the redis configurations max sessions per user is negative", t, func() {
+
+		Convey("When NewClient is called", func() {
+			c, err := New(Config{
+				Addr:               "123.0.0.1",
+				Password:           testSessionID,
+				TTL:                testTTL,
+				MaxSessionsPerUser: -1,
+			})
+
+			Convey("Then the client will not be created and the invalid max sessions error is returned", func() {
+				So(c, ShouldBeNil)
+				So(err, ShouldEqual, ErrInvalidMaxSessions)
+			})
+		})
+	})
+
+	Convey("Given the redis configurations limit policy is not recognised", t, func() {
+
+		Convey("When NewClient is called", func() {
+			c, err := New(Config{
+				Addr:        "123.0.0.1",
+				Password:    testSessionID,
+				TTL:         testTTL,
+				LimitPolicy: "oldest",
+			})
+
+			Convey("Then the client will not be created and the invalid limit policy error is returned", func() {
+				So(c, ShouldBeNil)
+				So(err, ShouldEqual, ErrInvalidLimitPolicy)
+			})
+		})
+	})
+
+	Convey("Given the redis configurations limit policy is empty", t, func() {
+
+		Convey("When NewClient is called", func() {
+			c, err := New(Config{
+				Addr:     "123.0.0.1",
+				Password: testSessionID,
+				TTL:      testTTL,
+			})
+
+			Convey("Then the oldest sessions are evicted by default", func() {
+				So(err, ShouldBeNil)
+				So(c.limitPolicy, ShouldEqual, EvictOldest)
+			})
+		})
+	})
+}
+
+func TestNewClient_MaxLifetime(t *testing.T) {
+	Convey("Given the redis configurations max lifetime is negative", t, func() {
+
+		Convey("When NewClient is called", func() {
+			c, err := New(Config{
+				Addr:        "123.0.0.1",
+				Password:    testSessionID,
+				TTL:         testTTL,
+				MaxLifetime: -time.Hour,
+			})
+
+			Convey("Then the client will not be created and the invalid lifetime error is returned", func() {
+				So(c, ShouldBeNil)
+				So(err, ShouldEqual, ErrInvalidLifetime)
+			})
+		})
+	})
+}
+
+func TestNewClient_Mode(t *testing.T) {
+	Convey("Given invalid redis deployment configurations", t, func() {
+		cases := map[error]Config{
+			ErrInvalidMode:      {Mode: "replicated", Addr: "123.0.0.1"},
+			ErrEmptyAddress:     {Mode: Cluster},
+			ErrEmptyMasterName:  {Mode: Sentinel, SeedAddrs: []string{"123.0.0.1:26379"}},
+			ErrInvalidDatabase:  {Mode: Cluster, Addr: "123.0.0.1", Database: 1},
+			ErrInvalidKeyPrefix: {Mode: Cluster, Addr: "123.0.0.1", KeyPrefix: "dp:{sessions}:"},
+		}
+
+		for expected, cfg := range cases {
+			cfg.Password = testSessionID
+			cfg.TTL = testTTL
+
+			Convey("Then "+expected.Error()+" is returned", func() {
+				c, err := New(cfg)
+				So(c, ShouldBeNil)
+				So(err, ShouldEqual, expected)
+			})
+		}
+	})
+
+	Convey("Given the redis configurations mode is sentinel", t, func() {
+
+		Convey("When NewClient is called with the sentinels' addresses and the master name", func() {
+			c, err := New(Config{
+				Mode:       Sentinel,
+				SeedAddrs:  []string{"123.0.0.1:26379", "123.0.0.2:26379"},
+				MasterName: "sessions",
+				Password:   testSessionID,
+				TTL:        testTTL,
+			})
+
+			Convey("Then a failover client is created and the default key prefix is used", func() {
+				So(err, ShouldBeNil)
+				So(c.cluster, ShouldBeNil)
+				So(c.idKey(testSessionID), ShouldEqual, testIDKey)
+			})
+		})
+	})
+
+	Convey("Given the redis configurations mode is cluster", t, func() {
+
+		Convey("When NewClient is called with only an address", func() {
+			c, err := New(Config{
+				Mode:     Cluster,
+				Addr:     "123.0.0.1:6379",
+				Password: testSessionID,
+				TTL:      testTTL,
+			})
+
+			Convey("Then a cluster client is created", func() {
+				So(err, ShouldBeNil)
+				So(c.cluster, ShouldNotBeNil)
+			})
+
+			Convey("And a session's keys are tagged with its ID and a user's index with their email", func() {
+				So(c.idKey(testSessionID), ShouldEqual, "session:id:{1234}")
+				So(c.accessKey(testSessionID), ShouldEqual, "session:access:{1234}")
+				So(c.ownerKey(testSessionID), ShouldEqual, "session:owner:{1234}")
+				So(c.userKey(testEmail), ShouldEqual, "session:user:{user@email.com}")
+			})
+
+			Convey("And the session ID is read back from a tagged ID key", func() {
+				id, ok := c.sessionID("session:id:{1234}")
+				So(ok, ShouldBeTrue)
+				So(id, ShouldEqual, testSessionID)
+
+				_, ok = c.sessionID("session:id:1234")
+				So(ok, ShouldBeFalse)
+			})
+		})
+	})
+}
+
+func TestClient_Cluster(t *testing.T) {
+	Convey("Given a client for a redis cluster", t, func() {
+		m, err := miniredis.Run()
+		So(err, ShouldBeNil)
+		defer m.Close()
+		m.RequireAuth("password")
+
+		c, err := New(Config{
+			Mode:      Cluster,
+			SeedAddrs: []string{m.Addr()},
+			Password:  "password",
+			TTL:       testTTL,
+		})
+		So(err, ShouldBeNil)
+
+		Convey("When sessions are stored", func() {
+			So(c.SetSession(testCtx, newTestSession(testSessionID, time.Now())), ShouldBeNil)
+			So(c.SetSession(testCtx, newTestSession("5678", time.Now())), ShouldBeNil)
+
+			Convey("Then they are stored under hash tagged keys", func() {
+				So(m.Exists("session:id:{1234}"), ShouldBeTrue)
+				So(m.Exists("session:access:{1234}"), ShouldBeTrue)
+				So(m.Exists("session:owner:{1234}"), ShouldBeTrue)
+				So(m.Exists("session:user:{user@email.com}"), ShouldBeTrue)
+			})
+
+			Convey("And they can be revoked", func() {
+				revoked, err := c.RevokeByEmail(testCtx, testEmail)
+				So(err, ShouldBeNil)
+				So(revoked, ShouldEqual, 2)
+				So(m.Exists("session:id:{1234}"), ShouldBeFalse)
+				So(m.Exists("session:id:{5678}"), ShouldBeFalse)
+			})
+
+			Convey("And they can be read by ID and email", func() {
+				s, err := c.GetByID(testCtx, testSessionID)
+				So(err, ShouldBeNil)
+				So(s.Email, ShouldEqual, testEmail)
+
+				sessions, err := c.ListByEmail(testCtx, testEmail)
+				So(err, ShouldBeNil)
+				So(sessions, ShouldHaveLength, 2)
+			})
+
+			Convey("And they are counted and deleted on every master", func() {
+				count, err := c.CountSessions(testCtx)
+				So(err, ShouldBeNil)
+				So(count, ShouldEqual, 2)
+
+				So(c.DeleteAll(testCtx), ShouldBeNil)
+				So(m.Keys(), ShouldBeEmpty)
+			})
+		})
+
+		Convey("When the slots of different users' and sessions' keys are calculated", func() {
+			So(slot("foo"), ShouldEqual, 12182)
+
+			Convey("Then different users' indexes are in different slots", func() {
+				So(slot(c.userKey(testEmail)), ShouldNotEqual, slot(c.userKey("other@email.com")))
+			})
+
+			Convey("And different sessions are in different slots", func() {
+				So(slot(c.idKey(testSessionID)), ShouldNotEqual, slot(c.idKey("5678")))
+			})
+
+			Convey("And each session's keys share a slot, as the scripts that use them together require", func() {
+				So(slot(c.accessKey(testSessionID)), ShouldEqual, slot(c.idKey(testSessionID)))
+				So(slot(c.ownerKey(testSessionID)), ShouldEqual, slot(c.idKey(testSessionID)))
+			})
+		})
+	})
+}
+
+func TestClient_Timeout(t *testing.T) {
+	Convey("Given a negative timeout", t, func() {
+		c, err := New(Config{Addr: "123.0.0.1", Password: testSessionID, TTL: testTTL, ReadTimeout: -time.Second})
+
+		Convey("Then the client will not be created and the invalid timeout error is returned", func() {
+			So(c, ShouldBeNil)
+			So(err, ShouldEqual, ErrInvalidTimeout)
+		})
+	})
+
+	Convey("Given a redis server that accepts connections but never responds", t, func() {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		So(err, ShouldBeNil)
+		defer l.Close()
+		go func() {
+			for {
+				conn, err := l.Accept()
+				if err != nil {
+					return
+				}
+				defer conn.Close()
+			}
+		}()
+
+		c, err := New(Config{
+			Addr:             l.Addr().String(),
+			Password:         "password",
+			TTL:              testTTL,
+			OperationTimeout: 50 * time.Millisecond,
+		})
+		So(err, ShouldBeNil)
+
+		Convey("When a session is read", func() {
+			start := time.Now()
+			_, err := c.GetByID(testCtx, testSessionID)
+
+			Convey("Then the operation gives up once the operation timeout passes", func() {
+				So(IsTimeout(err), ShouldBeTrue)
+				So(time.Since(start), ShouldBeLessThan, time.Second)
+			})
+		})
+
+		Convey("When the caller's context is cancelled", func() {
+			ctx, cancel := context.WithCancel(testCtx)
+			cancel()
+			_, err := c.GetByID(ctx, testSessionID)
+
+			Convey("Then the operation is abandoned and the context's error returned", func() {
+				So(errors.Is(err, context.Canceled), ShouldBeTrue)
+				So(IsTimeout(err), ShouldBeFalse)
+			})
+		})
+	})
+}
+
+func TestClient_Pool(t *testing.T) {
+	Convey("Given a negative pool size", t, func() {
+		c, err := New(Config{Addr: "123.0.0.1", Password: testSessionID, TTL: testTTL, PoolSize: -1})
+
+		Convey("Then the client will not be created and the invalid pool error is returned", func() {
+			So(c, ShouldBeNil)
+			So(err, ShouldEqual, ErrInvalidPool)
+		})
+	})
+
+	Convey("Given a client with a tuned connection pool", t, func() {
+		m, err := miniredis.Run()
+		So(err, ShouldBeNil)
+		defer m.Close()
+		m.RequireAuth("password")
+
+		c, err := New(Config{
+			Addr:        m.Addr(),
+			Password:    "password",
+			TTL:         testTTL,
+			PoolSize:    2,
+			MaxConnAge:  time.Minute,
+			PoolTimeout: time.Second,
+		})
+		So(err, ShouldBeNil)
+
+		Convey("When the health check runs", func() {
+			state := health.NewCheckState("elasticache")
+			So(c.Checker(testCtx, state), ShouldBeNil)
+
+			Convey("Then the pool statistics are reported in the health check message", func() {
+				stats := c.PoolStats()
+				So(stats.TotalConns, ShouldEqual, 1)
+				So(state.Message(), ShouldEndWith, poolMessage(stats))
+				So(state.Message(), ShouldContainSubstring, "pool: 1 connections, 1 idle")
+			})
+		})
+	})
+}
+
+func TestIsTimeout(t *testing.T) {
+	Convey("Given errors returned by redis operations", t, func() {
+		cases := map[error]bool{
+			context.DeadlineExceeded: true,
+			fmt.Errorf("elasticache client.Set returned an unexpected error: %w", context.DeadlineExceeded): true,
+			&net.OpError{Op: "read", Net: "tcp", Err: timeoutErr{}}:                                         true,
+			&net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}:                     false,
+			context.Canceled:   false,
+			ErrSessionNotFound: false,
+		}
+
+		for err, expected := range cases {
+			Convey(fmt.Sprintf("Then IsTimeout(%q) is %v", err, expected), func() {
+				So(IsTimeout(err), ShouldEqual, expected)
+			})
+		}
+	})
+}
+
+// timeoutErr - a net.Error reporting a timeout, as returned when a socket read or write deadline passes
+type timeoutErr struct{}
+
+func (timeoutErr) Error() string   { return "i/o timeout" }
+func (timeoutErr) Timeout() bool   { return true }
+func (timeoutErr) Temporary() bool { return true }
+
+func TestClient_MaxLifetime(t *testing.T) {
+	Convey("Given a session that has exceeded the max lifetime", t, func() {
+		m, err := miniredis.Run()
+		So(err, ShouldBeNil)
+		defer m.Close()
+
+		client, err := New(Config{Addr: m.Addr(), Password: "password", TTL: testTTL, MaxLifetime: 12 * time.Hour})
+		So(err, ShouldBeNil)
+		m.RequireAuth("password")
+
+		So(m.Set(testIDKey, string(resp)), ShouldBeNil)
+		So(m.Set(testOwnerKey, testEmail), ShouldBeNil)
+		_, err = m.ZAdd(testUserKey, 1, testSessionID)
+		So(err, ShouldBeNil)
+
+		Convey("When the session is read by ID", func() {
+			s, err := client.GetByID(testCtx, testSessionID)
+
+			Convey("Then ErrSessionNotFound is returned and the session is removed", func() {
+				So(s, ShouldBeNil)
+				So(err, ShouldEqual, ErrSessionNotFound)
+				So(m.Exists(testIDKey), ShouldBeFalse)
+				So(m.Exists(testUserKey), ShouldBeFalse)
+			})
+		})
+
+		Convey("When the session is read by email", func() {
+			s, err := client.GetByEmail(testCtx, testEmail)
+
+			Convey("Then ErrSessionNotFound is returned and the session is removed", func() {
+				So(s, ShouldBeNil)
+				So(err, ShouldEqual, ErrSessionNotFound)
+				So(m.Exists(testIDKey), ShouldBeFalse)
+			})
+		})
+
+		Convey("When the session is refreshed", func() {
+			_, err := client.Refresh(testCtx, testSessionID)
+
+			Convey("Then ErrSessionNotFound is returned and the session is removed", func() {
+				So(err, ShouldEqual, ErrSessionNotFound)
+				So(m.Exists(testIDKey), ShouldBeFalse)
+				So(m.Exists(testAccessKey), ShouldBeFalse)
+			})
+		})
+
+		Convey("When the user's sessions are listed", func() {
+			sessions, err := client.ListByEmail(testCtx, testEmail)
+
+			Convey("Then the expired session is not included", func() {
+				So(err, ShouldBeNil)
+				So(sessions, ShouldBeEmpty)
+				So(m.Exists(testIDKey), ShouldBeFalse)
+			})
+		})
+	})
+
+	Convey("Given a session that is close to its max lifetime", t, func() {
+		m, err := miniredis.Run()
+		So(err, ShouldBeNil)
+		defer m.Close()
+
+		client, err := New(Config{Addr: m.Addr(), Password: "password", TTL: testTTL, MaxLifetime: 12 * time.Hour})
+		So(err, ShouldBeNil)
+		m.RequireAuth("password")
+
+		s := &session.Session{
+			ID:           testSessionID,
+			Email:        testEmail,
+			Start:        time.Now().UTC().Add(-12*time.Hour + 10*time.Minute),
+			LastAccessed: time.Now().UTC(),
+		}
+
+		Convey("When the session is added to the cache", func() {
+			err := client.SetSession(testCtx, s)
+
+			Convey("Then the TTL is capped at the absolute deadline", func() {
+				So(err, ShouldBeNil)
+				So(m.TTL(testIDKey), ShouldBeLessThanOrEqualTo, 10*time.Minute)
+				So(m.TTL(testAccessKey), ShouldEqual, m.TTL(testIDKey))
+			})
+		})
+
+		Convey("When the session is read", func() {
+			So(client.SetSession(testCtx, s), ShouldBeNil)
+			read, err := client.GetByID(testCtx, testSessionID)
+
+			Convey("Then the TTL is capped at the absolute deadline", func() {
+				So(err, ShouldBeNil)
+				So(m.TTL(testIDKey), ShouldBeLessThanOrEqualTo, 10*time.Minute)
+				So(m.TTL(testIDKey), ShouldBeGreaterThan, 9*time.Minute)
+			})
+
+			Convey("And the session expires at its absolute deadline", func() {
+				deadline := read.Start.Add(12 * time.Hour)
+				So(read.Deadline, ShouldEqual, deadline)
+				So(read.ExpiresAt, ShouldEqual, deadline)
+			})
+		})
+
+		Convey("When the session is refreshed", func() {
+			So(client.SetSession(testCtx, s), ShouldBeNil)
+			expiresAt, err := client.Refresh(testCtx, testSessionID)
+
+			Convey("Then it expires at its absolute deadline", func() {
+				So(err, ShouldBeNil)
+				So(expiresAt, ShouldEqual, s.Start.Truncate(time.Millisecond).Add(12*time.Hour))
+			})
+		})
+	})
+
+	Convey("Given a session that started before the max lifetime", t, func() {
+		mockRedisClient, client := setUpMocks(redis.NewStatusCmd(testCtx), nil, nil, nil)
+		client.(*ElasticacheClient).maxLifetime = time.Hour
+
+		Convey("When the session is added to the cache", func() {
+			err := client.SetSession(testCtx, &session.Session{
+				ID:           testSessionID,
+				Email:        testEmail,
+				Start:        time.Now().Add(-2 * time.Hour),
+				LastAccessed: time.Now(),
+			})
+
+			Convey("Then ErrSessionExpired is returned and nothing is stored", func() {
+				So(err, ShouldEqual, ErrSessionExpired)
+				So(mockRedisClient.TxPipelinedCalls(), ShouldHaveLength, 0)
+			})
+		})
+	})
+}
+
 func TestClient_Set(t *testing.T) {
 	Convey("Given a valid sessions and redis client.Set returns no error", t, func() {
 		mockRedisClient, client := setUpMocks(
 			redis.NewStatusResult("success", nil),
-			redis.NewStringCmd(),
-			redis.NewStatusCmd(),
-			redis.NewBoolCmd(),
+			redis.NewStringCmd(testCtx),
+			redis.NewScanCmdResult(nil, 0, nil),
+			redis.NewBoolCmd(testCtx),
 		)
 
 		Convey("When there is a valid session", func() {
@@ -114,28 +621,55 @@ func TestClient_Set(t *testing.T) {
 				LastAccessed: time.Now(),
 			}
 
-			jsonByes, err := s.MarshalJSON()
-			So(err, ShouldBeNil)
+			err := client.SetSession(testCtx, s)
 
-			err = client.SetSession(s)
+			jsonByes, marshalErr := s.MarshalJSON()
+			So(marshalErr, ShouldBeNil)
 
 			Convey("Then the session is stored in the cache and no error is returned", func() {
 				So(err, ShouldBeNil)
+				So(mockRedisClient.TxPipelinedCalls(), ShouldHaveLength, 1)
 				So(mockRedisClient.SetCalls(), ShouldHaveLength, 2)
 
-				So(mockRedisClient.SetCalls()[0].Key, ShouldEqual, s.ID)
+				So(mockRedisClient.SetCalls()[0].Key, ShouldEqual, testIDKey)
 				So(mockRedisClient.SetCalls()[0].Value, ShouldResemble, jsonByes)
 				So(mockRedisClient.SetCalls()[0].Expiration, ShouldEqual, testTTL)
 			})
+
+			Convey("And the session ID is added to the user's index", func() {
+				So(mockRedisClient.ZAddCalls(), ShouldHaveLength, 1)
+				So(mockRedisClient.ZAddCalls()[0].Key, ShouldEqual, testUserKey)
+				So(mockRedisClient.ZAddCalls()[0].Members, ShouldResemble, []*redis.Z{{Score: score(s), Member: testSessionID}})
+
+				So(mockRedisClient.ExpireCalls(), ShouldHaveLength, 1)
+				So(mockRedisClient.ExpireCalls()[0].Key, ShouldEqual, testUserKey)
+				So(mockRedisClient.ExpireCalls()[0].Expiration, ShouldEqual, testTTL)
+			})
+
+			Convey("And the owner of the session ID is recorded to outlive the session", func() {
+				So(mockRedisClient.SetCalls()[1].Key, ShouldEqual, "session:owner:1234")
+				So(mockRedisClient.SetCalls()[1].Value, ShouldEqual, testEmail)
+				So(mockRedisClient.SetCalls()[1].Expiration, ShouldEqual, testTTL+ownerKeyGrace)
+			})
+
+			Convey("And the session's expiry time is set from the TTL", func() {
+				So(s.ExpiresAt, ShouldEqual, s.LastAccessed.Add(testTTL))
+				So(s.Deadline.IsZero(), ShouldBeTrue)
+			})
+
+			Convey("And no other sessions are evicted", func() {
+				So(mockRedisClient.ZRangeCalls(), ShouldHaveLength, 0)
+				So(mockRedisClient.DelCalls(), ShouldHaveLength, 0)
+			})
 		})
 	})
 
 	Convey("Given a valid session and redis client.Set returns an error", t, func() {
 		mockRedisClient, client := setUpMocks(
 			redis.NewStatusResult("fail", errors.New("failed to store session")),
-			redis.NewStringCmd(),
-			redis.NewStatusCmd(),
-			redis.NewBoolCmd(),
+			redis.NewStringCmd(testCtx),
+			redis.NewScanCmdResult(nil, 0, nil),
+			redis.NewBoolCmd(testCtx),
 		)
 
 		Convey("When there is a valid session but redis client.Set errors ", func() {
@@ -146,14 +680,15 @@ func TestClient_Set(t *testing.T) {
 				LastAccessed: time.Now(),
 			}
 
-			jsonByes, err := s.MarshalJSON()
-			So(err, ShouldBeNil)
+			err := client.SetSession(testCtx, s)
 
-			err = client.SetSession(s)
+			jsonByes, marshalErr := s.MarshalJSON()
+			So(marshalErr, ShouldBeNil)
 
 			Convey("Then the session will not be stored in the cache and an error is returned", func() {
-				So(mockRedisClient.SetCalls(), ShouldHaveLength, 1)
-				So(mockRedisClient.SetCalls()[0].Key, ShouldEqual, s.ID)
+				So(mockRedisClient.TxPipelinedCalls(), ShouldHaveLength, 1)
+				So(mockRedisClient.SetCalls(), ShouldHaveLength, 2)
+				So(mockRedisClient.SetCalls()[0].Key, ShouldEqual, testIDKey)
 				So(mockRedisClient.SetCalls()[0].Value, ShouldResemble, jsonByes)
 				So(mockRedisClient.SetCalls()[0].Expiration, ShouldEqual, testTTL)
 
@@ -165,15 +700,15 @@ func TestClient_Set(t *testing.T) {
 
 	Convey("Given an invalid session and redis client.Set returns an error", t, func() {
 		mockRedisClient, client := setUpMocks(
-			redis.NewStatusCmd(),
-			redis.NewStringCmd(),
-			redis.NewStatusCmd(),
-			redis.NewBoolCmd(),
+			redis.NewStatusCmd(testCtx),
+			redis.NewStringCmd(testCtx),
+			redis.NewScanCmdResult(nil, 0, nil),
+			redis.NewBoolCmd(testCtx),
 		)
 
 		Convey("When there is an invalid session", func() {
 			var s *session.Session
-			err := client.SetSession(s)
+			err := client.SetSession(testCtx, s)
 
 			Convey("Then the session will not be stored in the cache and an error is returned", func() {
 				So(mockRedisClient.SetCalls(), ShouldHaveLength, 0)
@@ -183,13 +718,11 @@ func TestClient_Set(t *testing.T) {
 		})
 	})
 
-	Convey("Given client.Set returns an error adding the Session ID to the cache", t, func() {
-		mockRedisClient, client := setUpMocks(
-			redis.NewStatusResult("", errors.New("Kapow!")),
-			nil,
-			nil,
-			nil,
-		)
+	Convey("Given the transaction fails after the ID key has been queued", t, func() {
+		mockRedisClient, client := setUpMocks(redis.NewStatusResult("OK", nil), nil, nil, nil)
+		mockRedisClient.ZAddFunc = func(ctx context.Context, key string, members ...*redis.Z) *redis.IntCmd {
+			return redis.NewIntResult(0, errors.New("connection reset"))
+		}
 
 		Convey("When cache.SetSession is called", func() {
 			s := &session.Session{
@@ -199,70 +732,266 @@ func TestClient_Set(t *testing.T) {
 				LastAccessed: time.Now(),
 			}
 
-			err := client.SetSession(s)
+			err := client.SetSession(testCtx, s)
 
-			Convey("Then the expected error is returned", func() {
+			Convey("Then the session and its index entry are sent in a single transaction and the error is returned", func() {
 				So(err, ShouldNotBeNil)
-				So(err.Error(), ShouldStartWith, "elasticache client.Set returned an unexpected error")
-				So(mockRedisClient.SetCalls(), ShouldHaveLength, 1)
+				So(err.Error(), ShouldEqual, "elasticache client.Set returned an unexpected error: connection reset")
+				So(mockRedisClient.TxPipelinedCalls(), ShouldHaveLength, 1)
+				So(mockRedisClient.SetCalls(), ShouldHaveLength, 2)
+				So(mockRedisClient.SetCalls()[0].Key, ShouldEqual, testIDKey)
+				So(mockRedisClient.ZAddCalls(), ShouldHaveLength, 1)
+				So(mockRedisClient.ZAddCalls()[0].Key, ShouldEqual, testUserKey)
 			})
 		})
 	})
 }
 
-func TestClient_GetByID(t *testing.T) {
-	Convey("Given a session ID client.GetByID returns a session and TTL is refreshed", t, func() {
-		mockRedisClient, client := setUpMocks(
-			redis.NewStatusCmd(),
-			redis.NewStringResult(string(resp), nil),
-			redis.NewStatusCmd(),
-			redis.NewBoolCmd(),
-		)
+func TestClient_SetSessionLimit(t *testing.T) {
+	older := newTestSession("older", time.Now().Add(-2*time.Hour))
+	old := newTestSession("old", time.Now().Add(-time.Hour))
+	s := newTestSession(testSessionID, time.Now())
 
-		Convey("When client uses the ID to get the session", func() {
-			s, err := client.GetByID(testSessionID)
-			So(err, ShouldBeNil)
+	newLimitedClient := func(policy LimitPolicy) (*miniredis.Miniredis, SessionCache) {
+		m, err := miniredis.Run()
+		So(err, ShouldBeNil)
 
-			Convey("Then redis client.Get is called with the expected parameters", func() {
-				So(mockRedisClient.GetCalls(), ShouldHaveLength, 1)
-				So(mockRedisClient.GetCalls()[0].Key, ShouldEqual, testSessionID)
+		client, err := New(Config{Addr: m.Addr(), Password: "password", TTL: testTTL, MaxSessionsPerUser: 2, LimitPolicy: policy})
+		So(err, ShouldBeNil)
+		m.RequireAuth("password")
+		return m, client
+	}
 
-				So(mockRedisClient.ExpireCalls(), ShouldHaveLength, 2) // Expects 2 due to refreshing by ID and Email
+	Convey("Given a user already has the max number of sessions and the limit policy is evict", t, func() {
+		m, client := newLimitedClient(EvictOldest)
+		defer m.Close()
+		So(client.SetSession(testCtx, older), ShouldBeNil)
+		So(client.SetSession(testCtx, old), ShouldBeNil)
 
-				So(mockRedisClient.ExpireCalls()[0].Key, ShouldEqual, testSessionID)
-				So(mockRedisClient.ExpireCalls()[0].Expiration, ShouldEqual, testTTL)
+		Convey("When a new session is added", func() {
+			err := client.SetSession(testCtx, s)
 
-				So(mockRedisClient.ExpireCalls()[1].Key, ShouldEqual, testEmail)
-				So(mockRedisClient.ExpireCalls()[1].Expiration, ShouldEqual, testTTL)
-			})
+			Convey("Then the oldest session is evicted and the new session added", func() {
+				So(err, ShouldBeNil)
+				So(m.Exists("session:id:older"), ShouldBeFalse)
+				So(m.Exists("session:access:older"), ShouldBeFalse)
+				So(m.Exists(testIDKey), ShouldBeTrue)
 
-			Convey("And the expected session is returned", func() {
-				So(s, ShouldNotBeEmpty)
-				So(s.ID, ShouldEqual, testSessionID)
-				So(s.LastAccessed.String(), ShouldNotEqual, respLastAccessed)
-				So(mockRedisClient.ExpireCalls()[0].Expiration, ShouldEqual, testTTL)
+				members, err := m.ZMembers(testUserKey)
+				So(err, ShouldBeNil)
+				So(members, ShouldResemble, []string{"old", testSessionID})
 			})
 		})
 	})
 
-	Convey("Given a session ID client.GetByID returns an error", t, func() {
-		mockRedisClient, client := setUpMocks(
-			redis.NewStatusCmd(),
-			redis.NewStringResult(string(resp), nil),
-			redis.NewStatusCmd(),
-			redis.NewBoolResult(false, errors.New("unable to refresh expiration")),
-		)
+	Convey("Given a user already has the max number of sessions and the evicted sessions cannot be removed", t, func() {
+		m, client := newLimitedClient(EvictOldest)
+		defer m.Close()
+		So(client.SetSession(testCtx, older), ShouldBeNil)
+		So(client.SetSession(testCtx, old), ShouldBeNil)
 
-		Convey("When client uses the ID to get the session", func() {
-			s, err := client.GetByID(testSessionID)
+		publisher := &events.InMemoryPublisher{}
+		c := client.(*ElasticacheClient)
+		c.publisher = publisher
+		c.client = &failAfterAdmitClient{RedisClienter: c.client, err: errors.New("connection reset")}
 
-			Convey("Then redis client.Get is called with the expected parameters", func() {
-				So(mockRedisClient.GetCalls(), ShouldHaveLength, 1)
-				So(mockRedisClient.GetCalls()[0].Key, ShouldEqual, testSessionID)
-				So(mockRedisClient.ExpireCalls(), ShouldHaveLength, 1)
+		Convey("When a new session is added", func() {
+			err := client.SetSession(testCtx, s)
+
+			Convey("Then the new session is added and no error is returned, though the evicted session's keys remain", func() {
+				So(err, ShouldBeNil)
+				So(m.Exists(testIDKey), ShouldBeTrue)
+				So(m.Exists("session:id:older"), ShouldBeTrue)
+
+				members, err := m.ZMembers(testUserKey)
+				So(err, ShouldBeNil)
+				So(members, ShouldResemble, []string{"old", testSessionID})
 			})
 
-			Convey("And the expected error is returned", func() {
+			Convey("And the evicted session is still revoked", func() {
+				So(publisher.Reasons(), ShouldResemble, []string{events.Revoked})
+				So(publisher.Events()[0].SessionID, ShouldEqual, "older")
+			})
+		})
+	})
+
+	Convey("Given a user already has the max number of sessions and the limit policy is reject", t, func() {
+		m, client := newLimitedClient(RejectNew)
+		defer m.Close()
+		So(client.SetSession(testCtx, older), ShouldBeNil)
+		So(client.SetSession(testCtx, old), ShouldBeNil)
+
+		Convey("When a new session is added", func() {
+			err := client.SetSession(testCtx, s)
+
+			Convey("Then ErrTooManySessions is returned and nothing is changed", func() {
+				So(err, ShouldEqual, ErrTooManySessions)
+				So(m.Exists(testIDKey), ShouldBeFalse)
+				So(m.Exists(testAccessKey), ShouldBeFalse)
+				So(m.Exists(testOwnerKey), ShouldBeFalse)
+
+				members, err := m.ZMembers(testUserKey)
+				So(err, ShouldBeNil)
+				So(members, ShouldResemble, []string{"older", "old"})
+			})
+		})
+	})
+
+	Convey("Given a user's index contains a session that has expired", t, func() {
+		m, client := newLimitedClient(RejectNew)
+		defer m.Close()
+		So(client.SetSession(testCtx, old), ShouldBeNil)
+		_, err := m.ZAdd(testUserKey, 1, "expired")
+		So(err, ShouldBeNil)
+
+		Convey("When a new session is added", func() {
+			err := client.SetSession(testCtx, s)
+
+			Convey("Then the expired session does not count towards the limit and is removed from the index", func() {
+				So(err, ShouldBeNil)
+
+				members, err := m.ZMembers(testUserKey)
+				So(err, ShouldBeNil)
+				So(members, ShouldResemble, []string{"old", testSessionID})
+			})
+		})
+	})
+
+	Convey("Given the limit policy is reject", t, func() {
+		m, client := newLimitedClient(RejectNew)
+		defer m.Close()
+
+		Convey("When more sessions than the limit are added for a user concurrently", func() {
+			var wg sync.WaitGroup
+			var added int32
+			for i := 0; i < 5; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					if client.SetSession(testCtx, newTestSession(fmt.Sprintf("id-%d", i), time.Now())) == nil {
+						atomic.AddInt32(&added, 1)
+					}
+				}(i)
+			}
+			wg.Wait()
+
+			Convey("Then only the max number of sessions are added", func() {
+				So(added, ShouldEqual, 2)
+
+				members, err := m.ZMembers(testUserKey)
+				So(err, ShouldBeNil)
+				So(members, ShouldHaveLength, 2)
+			})
+		})
+	})
+
+	Convey("Given the session limit cannot be checked", t, func() {
+		mockRedisClient, client := setUpMocks(redis.NewStatusResult("OK", nil), nil, nil, nil)
+		client.(*ElasticacheClient).maxSessions = 2
+		admitErr := errors.New("connection reset")
+		mockRedisClient.EvalShaFunc = func(ctx context.Context, sha1 string, keys []string, args ...interface{}) *redis.Cmd {
+			return redis.NewCmdResult(nil, admitErr)
+		}
+
+		Convey("When a new session is added", func() {
+			err := client.SetSession(testCtx, s)
+
+			Convey("Then the error is returned and the session's keys are removed", func() {
+				So(err, ShouldEqual, admitErr)
+				So(mockRedisClient.SetCalls(), ShouldHaveLength, 2)
+				So(mockRedisClient.DelCalls(), ShouldHaveLength, 1)
+				So(mockRedisClient.DelCalls()[0].Keys, ShouldResemble, []string{testIDKey, testAccessKey, testOwnerKey})
+			})
+		})
+	})
+}
+
+// failAfterAdmitClient - a RedisClienter whose transactions fail once a script has run, so the keys of sessions
+// evicted by admitScript cannot be removed
+type failAfterAdmitClient struct {
+	RedisClienter
+	err      error
+	admitted bool
+}
+
+func (c *failAfterAdmitClient) EvalSha(ctx context.Context, sha1 string, keys []string, args ...interface{}) *redis.Cmd {
+	c.admitted = true
+	return c.RedisClienter.EvalSha(ctx, sha1, keys, args...)
+}
+
+func (c *failAfterAdmitClient) Eval(ctx context.Context, script string, keys []string, args ...interface{}) *redis.Cmd {
+	c.admitted = true
+	return c.RedisClienter.Eval(ctx, script, keys, args...)
+}
+
+func (c *failAfterAdmitClient) TxPipelined(ctx context.Context, fn func(redis.Pipeliner) error) ([]redis.Cmder, error) {
+	if c.admitted {
+		return nil, c.err
+	}
+	return c.RedisClienter.TxPipelined(ctx, fn)
+}
+
+func TestClient_GetByID(t *testing.T) {
+	Convey("Given a session ID client.GetByID returns a session and TTL is refreshed", t, func() {
+		mockRedisClient, client := setUpMocks(
+			redis.NewStatusCmd(testCtx),
+			redis.NewStringResult(string(resp), nil),
+			redis.NewScanCmdResult(nil, 0, nil),
+			redis.NewBoolCmd(testCtx),
+		)
+
+		Convey("When client uses the ID to get the session", func() {
+			s, err := client.GetByID(testCtx, testSessionID)
+			So(err, ShouldBeNil)
+
+			Convey("Then redis client.Get is called with the expected parameters", func() {
+				So(mockRedisClient.GetCalls(), ShouldHaveLength, 1)
+				So(mockRedisClient.GetCalls()[0].Key, ShouldEqual, testIDKey)
+
+				So(mockRedisClient.EvalShaCalls(), ShouldHaveLength, 1)
+				So(mockRedisClient.EvalShaCalls()[0].Sha1, ShouldEqual, refreshScript.hash)
+				So(mockRedisClient.EvalShaCalls()[0].Keys, ShouldResemble, []string{testIDKey, testAccessKey, testOwnerKey})
+
+				So(mockRedisClient.ExpireCalls(), ShouldHaveLength, 1)
+				So(mockRedisClient.ExpireCalls()[0].Key, ShouldEqual, testUserKey)
+				So(mockRedisClient.ExpireCalls()[0].Expiration, ShouldEqual, testTTL)
+			})
+
+			Convey("And the expected session is returned", func() {
+				So(s, ShouldNotBeEmpty)
+				So(s.ID, ShouldEqual, testSessionID)
+				So(s.LastAccessed.String(), ShouldNotEqual, respLastAccessed)
+				So(s.LastAccessed, ShouldEqual, s.LastAccessed.Truncate(time.Millisecond))
+				So(s.ExpiresAt, ShouldEqual, s.LastAccessed.Add(testTTL))
+			})
+
+			Convey("And the session itself is not rewritten", func() {
+				So(mockRedisClient.SetXXCalls(), ShouldHaveLength, 0)
+			})
+		})
+	})
+
+	Convey("Given a session ID client.GetByID returns an error", t, func() {
+		mockRedisClient, client := setUpMocks(
+			redis.NewStatusCmd(testCtx),
+			redis.NewStringResult(string(resp), nil),
+			redis.NewScanCmdResult(nil, 0, nil),
+			nil,
+		)
+		mockRedisClient.EvalShaFunc = func(ctx context.Context, sha1 string, keys []string, args ...interface{}) *redis.Cmd {
+			return redis.NewCmdResult(nil, errors.New("unable to refresh expiration"))
+		}
+
+		Convey("When client uses the ID to get the session", func() {
+			s, err := client.GetByID(testCtx, testSessionID)
+
+			Convey("Then redis client.Get is called with the expected parameters", func() {
+				So(mockRedisClient.GetCalls(), ShouldHaveLength, 1)
+				So(mockRedisClient.GetCalls()[0].Key, ShouldEqual, testIDKey)
+				So(mockRedisClient.EvalShaCalls(), ShouldHaveLength, 1)
+			})
+
+			Convey("And the expected error is returned", func() {
 				So(err, ShouldNotBeEmpty)
 				So(err.Error(), ShouldEqual, "unable to refresh expiration")
 				So(s, ShouldBeNil)
@@ -279,7 +1008,7 @@ func TestClient_GetByID(t *testing.T) {
 		)
 
 		Convey("When client.GetByID is called", func() {
-			s, err := client.GetByID(testSessionID)
+			s, err := client.GetByID(testCtx, testSessionID)
 
 			Convey("Then error.SessionNotFound", func() {
 				So(err, ShouldEqual, ErrSessionNotFound)
@@ -288,22 +1017,22 @@ func TestClient_GetByID(t *testing.T) {
 
 			Convey("And the redis client is called with the expected parameters", func() {
 				So(mockRedisClient.GetCalls(), ShouldHaveLength, 1)
-				So(mockRedisClient.GetCalls()[0].Key, ShouldEqual, testSessionID)
-				So(mockRedisClient.ExpireCalls(), ShouldHaveLength, 0)
+				So(mockRedisClient.GetCalls()[0].Key, ShouldEqual, testIDKey)
+				So(mockRedisClient.EvalShaCalls(), ShouldHaveLength, 0)
 			})
 		})
 	})
 
 	Convey("Given a blank session ID client.GetByID returns an error", t, func() {
 		mockRedisClient, client := setUpMocks(
-			redis.NewStatusCmd(),
-			redis.NewStringCmd(),
-			redis.NewStatusCmd(),
-			redis.NewBoolCmd(),
+			redis.NewStatusCmd(testCtx),
+			redis.NewStringCmd(testCtx),
+			redis.NewScanCmdResult(nil, 0, nil),
+			redis.NewBoolCmd(testCtx),
 		)
 
 		Convey("When client.GetByID is called has an empty ID", func() {
-			s, err := client.GetByID("")
+			s, err := client.GetByID(testCtx, "")
 
 			Convey("Then client.GetByID returns an error and no session is returned", func() {
 				So(mockRedisClient.GetCalls(), ShouldHaveLength, 0)
@@ -316,14 +1045,14 @@ func TestClient_GetByID(t *testing.T) {
 
 	Convey("Given a session ID client.GetByID returns an error", t, func() {
 		mockRedisClient, client := setUpMocks(
-			redis.NewStatusCmd(),
+			redis.NewStatusCmd(testCtx),
 			redis.NewStringResult("", errors.New("unexpected end of JSON input")),
-			redis.NewStatusCmd(),
-			redis.NewBoolCmd(),
+			redis.NewScanCmdResult(nil, 0, nil),
+			redis.NewBoolCmd(testCtx),
 		)
 
 		Convey("When client.GetByID is called with a valid session ID", func() {
-			s, err := client.GetByID(testSessionID)
+			s, err := client.GetByID(testCtx, testSessionID)
 
 			Convey("Then the redis client.Get returns an error and no session is returned", func() {
 				So(mockRedisClient.GetCalls(), ShouldHaveLength, 1)
@@ -335,58 +1064,241 @@ func TestClient_GetByID(t *testing.T) {
 	})
 }
 
+func TestClient_Refresh(t *testing.T) {
+	Convey("Given a session exists for the ID", t, func() {
+		m, err := miniredis.Run()
+		So(err, ShouldBeNil)
+		defer m.Close()
+
+		client, err := New(Config{Addr: m.Addr(), Password: "password", TTL: testTTL})
+		So(err, ShouldBeNil)
+		m.RequireAuth("password")
+
+		So(client.SetSession(testCtx, newTestSession(testSessionID, time.Now().Add(-time.Hour))), ShouldBeNil)
+		stored, err := m.Get(testIDKey)
+		So(err, ShouldBeNil)
+		m.FastForward(time.Minute)
+
+		Convey("When Refresh is called", func() {
+			before := time.Now().UTC().Truncate(time.Millisecond)
+			expiresAt, err := client.Refresh(testCtx, testSessionID)
+
+			Convey("Then the TTL of the session and its keys is extended", func() {
+				So(err, ShouldBeNil)
+				So(m.TTL(testIDKey), ShouldEqual, testTTL)
+				So(m.TTL(testAccessKey), ShouldEqual, testTTL)
+				So(m.TTL(testOwnerKey), ShouldEqual, testTTL+ownerKeyGrace)
+				So(m.TTL(testUserKey), ShouldEqual, testTTL)
+			})
+
+			Convey("And the new expiry time is returned", func() {
+				So(expiresAt, ShouldHappenOnOrBetween, before.Add(testTTL), time.Now().UTC().Add(testTTL))
+			})
+
+			Convey("And the last accessed time is recorded without the session being rewritten", func() {
+				after, err := m.Get(testIDKey)
+				So(err, ShouldBeNil)
+				So(after, ShouldEqual, stored)
+
+				sessions, err := client.ListByEmail(testCtx, testEmail)
+				So(err, ShouldBeNil)
+				So(sessions, ShouldHaveLength, 1)
+				So(sessions[0].LastAccessed, ShouldHappenOnOrBetween, before, time.Now().UTC())
+			})
+		})
+	})
+
+	Convey("Given a session stored without an access key", t, func() {
+		m, err := miniredis.Run()
+		So(err, ShouldBeNil)
+		defer m.Close()
+
+		client, err := New(Config{Addr: m.Addr(), Password: "password", TTL: testTTL})
+		So(err, ShouldBeNil)
+		m.RequireAuth("password")
+
+		So(m.Set(testIDKey, string(resp)), ShouldBeNil)
+
+		Convey("When Refresh is called", func() {
+			_, err := client.Refresh(testCtx, testSessionID)
+
+			Convey("Then the session is read so its access key and owner key can be created", func() {
+				So(err, ShouldBeNil)
+				So(m.HGet(testAccessKey, startField), ShouldEqual, "1597308018652")
+				So(m.HGet(testAccessKey, lastAccessedField), ShouldNotBeEmpty)
+				So(m.TTL(testAccessKey), ShouldEqual, testTTL)
+				owner, err := m.Get(testOwnerKey)
+				So(err, ShouldBeNil)
+				So(owner, ShouldEqual, testEmail)
+			})
+		})
+	})
+
+	Convey("Given a session does not exist for the ID", t, func() {
+		m, err := miniredis.Run()
+		So(err, ShouldBeNil)
+		defer m.Close()
+
+		client, err := New(Config{Addr: m.Addr(), Password: "password", TTL: testTTL})
+		So(err, ShouldBeNil)
+		m.RequireAuth("password")
+
+		Convey("When Refresh is called", func() {
+			_, err := client.Refresh(testCtx, testSessionID)
+
+			Convey("Then ErrSessionNotFound is returned and nothing is written", func() {
+				So(err, ShouldEqual, ErrSessionNotFound)
+				So(m.Keys(), ShouldBeEmpty)
+			})
+		})
+	})
+
+	Convey("Given a blank session ID", t, func() {
+		mockRedisClient, client := setUpMocks(nil, nil, nil, nil)
+
+		Convey("When Refresh is called", func() {
+			_, err := client.Refresh(testCtx, "")
+
+			Convey("Then ErrEmptySessionID is returned", func() {
+				So(err, ShouldEqual, ErrEmptySessionID)
+				So(mockRedisClient.EvalShaCalls(), ShouldHaveLength, 0)
+			})
+		})
+	})
+}
+
+func TestClient_UpdateAttributes(t *testing.T) {
+	stored := []byte(`{"id":"1234","email":"user@email.com","start":"2020-08-13T08:40:18.652Z","last_accessed":"2020-08-13T08:40:18.652Z","attributes":{"name":"Test User","csrf":"abc"}}`)
+
+	Convey("Given a session with attributes exists for the ID", t, func() {
+		mockRedisClient, client := setUpMocks(
+			nil,
+			redis.NewStringResult(string(stored), nil),
+			nil,
+			redis.NewBoolResult(true, nil),
+		)
+
+		Convey("When UpdateAttributes is called", func() {
+			s, err := client.UpdateAttributes(testCtx, testSessionID, map[string]interface{}{"collection": "xyz", "csrf": nil})
+
+			Convey("Then the attributes are merged and the updated session is returned", func() {
+				So(err, ShouldBeNil)
+				So(s.Attributes, ShouldResemble, map[string]interface{}{"name": "Test User", "collection": "xyz"})
+			})
+
+			Convey("And the updated session is written back and its TTL refreshed", func() {
+				expected, err := s.MarshalJSON()
+				So(err, ShouldBeNil)
+				So(mockRedisClient.EvalShaCalls(), ShouldHaveLength, 1)
+				So(mockRedisClient.SetXXCalls(), ShouldHaveLength, 1)
+				So(mockRedisClient.SetXXCalls()[0].Key, ShouldEqual, testIDKey)
+				So(mockRedisClient.SetXXCalls()[0].Value, ShouldResemble, expected)
+				So(mockRedisClient.SetXXCalls()[0].Expiration, ShouldEqual, testTTL)
+			})
+		})
+
+		Convey("When UpdateAttributes is called with invalid attributes", func() {
+			s, err := client.UpdateAttributes(testCtx, testSessionID, map[string]interface{}{"": "value"})
+
+			Convey("Then the validation error is returned and nothing is written", func() {
+				So(s, ShouldBeNil)
+				So(err, ShouldEqual, session.EmptyAttributeKeyErr)
+				So(mockRedisClient.EvalShaCalls(), ShouldHaveLength, 0)
+				So(mockRedisClient.SetXXCalls(), ShouldHaveLength, 0)
+			})
+		})
+	})
+
+	Convey("Given a session does not exist for the ID", t, func() {
+		mockRedisClient, client := setUpMocks(nil, redis.NewStringResult("", redis.Nil), nil, nil)
+
+		Convey("When UpdateAttributes is called", func() {
+			s, err := client.UpdateAttributes(testCtx, testSessionID, map[string]interface{}{"csrf": "token"})
+
+			Convey("Then ErrSessionNotFound is returned and nothing is written", func() {
+				So(s, ShouldBeNil)
+				So(err, ShouldEqual, ErrSessionNotFound)
+				So(mockRedisClient.TxPipelinedCalls(), ShouldHaveLength, 0)
+			})
+		})
+	})
+
+	Convey("Given a blank session ID", t, func() {
+		mockRedisClient, client := setUpMocks(nil, nil, nil, nil)
+
+		Convey("When UpdateAttributes is called", func() {
+			_, err := client.UpdateAttributes(testCtx, "", map[string]interface{}{"csrf": "token"})
+
+			Convey("Then ErrEmptySessionID is returned", func() {
+				So(err, ShouldEqual, ErrEmptySessionID)
+				So(mockRedisClient.GetCalls(), ShouldHaveLength, 0)
+			})
+		})
+	})
+}
+
 func TestClient_GetByEmail(t *testing.T) {
-	Convey("Given a session email client.GetByEmail returns a session and TTL is refreshed", t, func() {
+	Convey("Given a user with several sessions client.GetByEmail returns the latest session and TTL is refreshed", t, func() {
 		mockRedisClient, client := setUpMocks(
-			redis.NewStatusCmd(),
-			redis.NewStringResult(string(resp), nil),
-			redis.NewStatusCmd(),
-			redis.NewBoolCmd(),
+			redis.NewStatusCmd(testCtx),
+			nil,
+			redis.NewScanCmdResult(nil, 0, nil),
+			redis.NewBoolCmd(testCtx),
 		)
+		older := newTestSession("older", time.Now().Add(-time.Hour))
+		withUserIndex(mockRedisClient, marshal(older), resp)
 
 		Convey("When client uses the email to get the session", func() {
-			s, err := client.GetByEmail(testEmail)
+			s, err := client.GetByEmail(testCtx, testEmail)
 			So(err, ShouldBeNil)
 
-			Convey("Then redis client.Get is called with the expected parameters", func() {
-				So(mockRedisClient.GetCalls(), ShouldHaveLength, 1)
-				So(mockRedisClient.GetCalls()[0].Key, ShouldEqual, testEmail)
-
-				So(mockRedisClient.ExpireCalls(), ShouldHaveLength, 2) // Expects 2 due to refreshing by ID and Email
-				So(mockRedisClient.ExpireCalls()[0].Key, ShouldEqual, testEmail)
-				So(mockRedisClient.ExpireCalls()[0].Expiration, ShouldEqual, testTTL)
+			Convey("Then the user's index is read with the expected parameters", func() {
+				So(mockRedisClient.ZRangeCalls(), ShouldHaveLength, 1)
+				So(mockRedisClient.ZRangeCalls()[0].Key, ShouldEqual, testUserKey)
+				So(mockRedisClient.TxPipelinedCalls(), ShouldHaveLength, 1)
+				So(mockRedisClient.GetCalls(), ShouldHaveLength, 2)
+				So(mockRedisClient.GetCalls()[0].Key, ShouldEqual, "session:id:older")
+				So(mockRedisClient.GetCalls()[1].Key, ShouldEqual, testIDKey)
+			})
 
-				So(mockRedisClient.ExpireCalls()[1].Key, ShouldEqual, testSessionID)
-				So(mockRedisClient.ExpireCalls()[1].Expiration, ShouldEqual, testTTL)
+			Convey("And only the latest session is refreshed", func() {
+				So(mockRedisClient.EvalShaCalls(), ShouldHaveLength, 1)
+				So(mockRedisClient.EvalShaCalls()[0].Keys, ShouldResemble, []string{testIDKey, testAccessKey, testOwnerKey})
+				So(mockRedisClient.ExpireCalls(), ShouldHaveLength, 1)
+				So(mockRedisClient.ExpireCalls()[0].Key, ShouldEqual, testUserKey)
 			})
 
 			Convey("And the expected session is returned", func() {
-
 				So(s, ShouldNotBeEmpty)
+				So(s.ID, ShouldEqual, testSessionID)
 				So(s.Email, ShouldEqual, testEmail)
 				So(s.LastAccessed.String(), ShouldNotEqual, respLastAccessed)
 			})
+
+			Convey("And the session itself is not rewritten", func() {
+				So(mockRedisClient.SetXXCalls(), ShouldHaveLength, 0)
+			})
 		})
 	})
 
 	Convey("Given a session email client.GetByEmail returns an error", t, func() {
 		mockRedisClient, client := setUpMocks(
-			redis.NewStatusCmd(),
-			redis.NewStringResult(string(resp), nil),
-			redis.NewStatusCmd(),
-			redis.NewBoolResult(false, errors.New("unable to refresh expiration")),
+			redis.NewStatusCmd(testCtx),
+			nil,
+			redis.NewScanCmdResult(nil, 0, nil),
+			nil,
 		)
+		withUserIndex(mockRedisClient, resp)
+		mockRedisClient.EvalShaFunc = func(ctx context.Context, sha1 string, keys []string, args ...interface{}) *redis.Cmd {
+			return redis.NewCmdResult(nil, errors.New("unable to refresh expiration"))
+		}
 
 		Convey("When client uses the email to get the session", func() {
-			s, err := client.GetByEmail(testEmail)
-
-			Convey("Then redis client.Get is called with the expected parameters", func() {
-				So(mockRedisClient.GetCalls(), ShouldHaveLength, 1)
-				So(mockRedisClient.GetCalls()[0].Key, ShouldEqual, testEmail)
+			s, err := client.GetByEmail(testCtx, testEmail)
 
-				So(mockRedisClient.ExpireCalls(), ShouldHaveLength, 1) // Expects 2 due to refreshing by ID and Email
-				So(mockRedisClient.ExpireCalls()[0].Key, ShouldEqual, testEmail)
-				So(mockRedisClient.ExpireCalls()[0].Expiration, ShouldEqual, testTTL)
+			Convey("Then the session is refreshed with the expected parameters", func() {
+				So(mockRedisClient.EvalShaCalls(), ShouldHaveLength, 1)
+				So(mockRedisClient.EvalShaCalls()[0].Keys[0], ShouldEqual, testIDKey)
 			})
 
 			Convey("Then redis client.Get is called and returns an error", func() {
@@ -397,16 +1309,11 @@ func TestClient_GetByEmail(t *testing.T) {
 		})
 	})
 
-	Convey("Given client.GetByID returns not found error", t, func() {
-		mockRedisClient, client := setUpMocks(
-			nil,
-			redis.NewStringResult("", redis.Nil),
-			nil,
-			nil,
-		)
+	Convey("Given the user has no sessions", t, func() {
+		mockRedisClient, client := setUpMocks(nil, nil, nil, nil)
 
-		Convey("When client.GetByID is called", func() {
-			s, err := client.GetByEmail(testEmail)
+		Convey("When client.GetByEmail is called", func() {
+			s, err := client.GetByEmail(testCtx, testEmail)
 
 			Convey("Then error.SessionNotFound", func() {
 				So(err, ShouldEqual, ErrSessionNotFound)
@@ -414,26 +1321,45 @@ func TestClient_GetByEmail(t *testing.T) {
 			})
 
 			Convey("And the redis client is called with the expected parameters", func() {
-				So(mockRedisClient.GetCalls(), ShouldHaveLength, 1)
-				So(mockRedisClient.GetCalls()[0].Key, ShouldEqual, testEmail)
-				So(mockRedisClient.ExpireCalls(), ShouldHaveLength, 0)
+				So(mockRedisClient.ZRangeCalls(), ShouldHaveLength, 1)
+				So(mockRedisClient.ZRangeCalls()[0].Key, ShouldEqual, testUserKey)
+				So(mockRedisClient.TxPipelinedCalls(), ShouldHaveLength, 0)
+				So(mockRedisClient.EvalShaCalls(), ShouldHaveLength, 0)
+			})
+		})
+	})
+
+	Convey("Given every session in the user's index has expired", t, func() {
+		mockRedisClient, client := setUpMocks(nil, nil, nil, nil)
+		withUserIndex(mockRedisClient, nil, nil)
+
+		Convey("When client.GetByEmail is called", func() {
+			s, err := client.GetByEmail(testCtx, testEmail)
+
+			Convey("Then error.SessionNotFound and the stale index entries are removed", func() {
+				So(err, ShouldEqual, ErrSessionNotFound)
+				So(s, ShouldBeNil)
+				So(mockRedisClient.ZRemCalls(), ShouldHaveLength, 1)
+				So(mockRedisClient.ZRemCalls()[0].Key, ShouldEqual, testUserKey)
+				So(mockRedisClient.ZRemCalls()[0].Members, ShouldResemble, []interface{}{"id-0", "id-1"})
+				So(mockRedisClient.EvalShaCalls(), ShouldHaveLength, 0)
 			})
 		})
 	})
 
 	Convey("Given a blank session email client.GetByEmail returns an error", t, func() {
 		mockRedisClient, client := setUpMocks(
-			redis.NewStatusCmd(),
-			redis.NewStringCmd(),
-			redis.NewStatusCmd(),
-			redis.NewBoolCmd(),
+			redis.NewStatusCmd(testCtx),
+			redis.NewStringCmd(testCtx),
+			redis.NewScanCmdResult(nil, 0, nil),
+			redis.NewBoolCmd(testCtx),
 		)
 
 		Convey("When client.GetByEmail is called has an empty ID", func() {
-			s, err := client.GetByEmail("")
+			s, err := client.GetByEmail(testCtx, "")
 
 			Convey("Then client.GetByEmail returns an error and no session is returned", func() {
-				So(mockRedisClient.GetCalls(), ShouldHaveLength, 0)
+				So(mockRedisClient.ZRangeCalls(), ShouldHaveLength, 0)
 				So(s, ShouldBeNil)
 				So(err, ShouldNotBeEmpty)
 				So(err, ShouldEqual, ErrEmptySessionEmail)
@@ -441,24 +1367,23 @@ func TestClient_GetByEmail(t *testing.T) {
 		})
 	})
 
-	Convey("Given a session ID client.GetByEmail returns an error", t, func() {
-		mockRedisClient, client := setUpMocks(
-			redis.NewStatusCmd(),
-			redis.NewStringResult("", errors.New("unexpected end of JSON input")),
-			redis.NewStatusCmd(),
-			redis.NewBoolCmd(),
-		)
+	Convey("Given reading the user's sessions returns an error", t, func() {
+		mockRedisClient, client := setUpMocks(nil, nil, nil, nil)
+		withUserIndex(mockRedisClient, resp)
+		mockRedisClient.GetFunc = func(ctx context.Context, key string) *redis.StringCmd {
+			return redis.NewStringResult("", errors.New("unexpected end of JSON input"))
+		}
 
-		Convey("When client.GetByEmail is called with a valid session ID", func() {
-			s, err := client.GetByEmail("user@test.com")
+		Convey("When client.GetByEmail is called with a valid session email", func() {
+			s, err := client.GetByEmail(testCtx, "user@test.com")
 
-			Convey("Then redis client.Get is called with the expected parameters", func() {
-				So(mockRedisClient.GetCalls(), ShouldHaveLength, 1)
-				So(mockRedisClient.GetCalls()[0].Key, ShouldEqual, "user@test.com")
-				So(mockRedisClient.ExpireCalls(), ShouldHaveLength, 0) // Expects 2 due to refreshing by ID and Email
+			Convey("Then the user's index is read with the expected parameters", func() {
+				So(mockRedisClient.ZRangeCalls(), ShouldHaveLength, 1)
+				So(mockRedisClient.ZRangeCalls()[0].Key, ShouldEqual, "session:user:user@test.com")
+				So(mockRedisClient.EvalShaCalls(), ShouldHaveLength, 0)
 			})
 
-			Convey("Then the redis client.Get returns an error and no session is returned", func() {
+			Convey("Then an error is returned and no session is returned", func() {
 				So(s, ShouldBeNil)
 				So(err, ShouldNotBeEmpty)
 				So(err.Error(), ShouldEqual, "unexpected end of JSON input")
@@ -467,63 +1392,844 @@ func TestClient_GetByEmail(t *testing.T) {
 	})
 }
 
-func TestClient_DeleteAll(t *testing.T) {
-	Convey("Given DeleteAll removes all sessions from cache", t, func() {
-		mockRedisClient, client := setUpMocks(
-			redis.NewStatusCmd(),
-			redis.NewStringCmd(),
-			redis.NewStatusCmd(),
-			redis.NewBoolCmd(),
-		)
+func TestClient_ListByEmail(t *testing.T) {
+	Convey("Given a user with several sessions", t, func() {
+		mockRedisClient, client := setUpMocks(nil, nil, nil, nil)
+		older := newTestSession("older", time.Now().Add(-time.Hour))
+		withUserIndex(mockRedisClient, marshal(older), nil, resp)
 
-		Convey("When DeleteAll is called", func() {
-			err := client.DeleteAll()
+		Convey("When ListByEmail is called", func() {
+			sessions, err := client.ListByEmail(testCtx, testEmail)
 
-			Convey("Then all sessions are removed from cache and no error is returned", func() {
+			Convey("Then the active sessions are returned oldest first", func() {
 				So(err, ShouldBeNil)
-				So(mockRedisClient.FlushAllCalls(), ShouldHaveLength, 1)
+				So(sessions, ShouldHaveLength, 2)
+				So(sessions[0].ID, ShouldEqual, "older")
+				So(sessions[1].ID, ShouldEqual, testSessionID)
+			})
+
+			Convey("And the expired session is removed from the index", func() {
+				So(mockRedisClient.ZRemCalls(), ShouldHaveLength, 1)
+				So(mockRedisClient.ZRemCalls()[0].Members, ShouldResemble, []interface{}{"id-1"})
+			})
+
+			Convey("And the sessions are not refreshed", func() {
+				So(mockRedisClient.SetXXCalls(), ShouldHaveLength, 0)
+				So(mockRedisClient.ExpireCalls(), ShouldHaveLength, 0)
 			})
 		})
 	})
 
-	Convey("Given DeleteAll returns an error", t, func() {
-		mockRedisClient, client := setUpMocks(
-			redis.NewStatusCmd(),
-			redis.NewStringCmd(),
-			redis.NewStatusResult("fail", errors.New("some redis error")),
-			redis.NewBoolCmd(),
-		)
+	Convey("Given the user has no sessions", t, func() {
+		_, client := setUpMocks(nil, nil, nil, nil)
 
-		Convey("When DeleteAll is called", func() {
-			err := client.DeleteAll()
+		Convey("When ListByEmail is called", func() {
+			sessions, err := client.ListByEmail(testCtx, testEmail)
 
-			Convey("Then no sessions are removed and a redis error is returned", func() {
-				So(err, ShouldNotBeEmpty)
-				So(err.Error(), ShouldEqual, "some redis error")
-				So(mockRedisClient.FlushAllCalls(), ShouldHaveLength, 1)
+			Convey("Then an empty list is returned", func() {
+				So(err, ShouldBeNil)
+				So(sessions, ShouldNotBeNil)
+				So(sessions, ShouldBeEmpty)
 			})
 		})
+	})
+
+	Convey("Given a blank session email", t, func() {
+		mockRedisClient, client := setUpMocks(nil, nil, nil, nil)
 
+		Convey("When ListByEmail is called", func() {
+			sessions, err := client.ListByEmail(testCtx, "")
+
+			Convey("Then ErrEmptySessionEmail is returned", func() {
+				So(sessions, ShouldBeNil)
+				So(err, ShouldEqual, ErrEmptySessionEmail)
+				So(mockRedisClient.ZRangeCalls(), ShouldHaveLength, 0)
+			})
+		})
 	})
 }
 
-func setUpMocks(setStatusCmd *redis.StatusCmd, getStringCmd *redis.StringCmd, flushAllStatusCmd *redis.StatusCmd, expireBoolCmd *redis.BoolCmd) (*RedisClienterMock, SessionCache) {
-	mockRedisClient := &RedisClienterMock{
-		PingFunc: nil,
-		SetFunc: func(key string, value interface{}, ttl time.Duration) *redis.StatusCmd {
-			return setStatusCmd
-		},
-		GetFunc: func(key string) *redis.StringCmd {
-			return getStringCmd
-		},
-		FlushAllFunc: func() *redis.StatusCmd {
-			return flushAllStatusCmd
-		},
-		ExpireFunc: func(key string, expiration time.Duration) *redis.BoolCmd {
-			return expireBoolCmd
-		}}
-	return mockRedisClient, &ElasticacheClient{
-		client: mockRedisClient,
-		ttl:    testTTL,
+func TestClient_Discard(t *testing.T) {
+	Convey("Given a session exists for the ID", t, func() {
+		mockRedisClient, sessionCache := setUpMocks(nil, redis.NewStringResult(string(resp), nil), nil, nil)
+		publisher := &events.InMemoryPublisher{}
+		client := sessionCache.(*ElasticacheClient)
+		client.publisher = publisher
+
+		Convey("When Discard is called", func() {
+			err := client.Discard(testCtx, testSessionID)
+
+			Convey("Then the session and its index entry are removed without a revoked event being published", func() {
+				So(err, ShouldBeNil)
+				So(mockRedisClient.DelCalls(), ShouldHaveLength, 2)
+				So(mockRedisClient.DelCalls()[0].Keys, ShouldResemble, []string{testIDKey})
+				So(mockRedisClient.DelCalls()[1].Keys, ShouldResemble, []string{testAccessKey})
+				So(mockRedisClient.ZRemCalls(), ShouldHaveLength, 1)
+				So(mockRedisClient.ZRemCalls()[0].Key, ShouldEqual, testUserKey)
+				So(publisher.Events(), ShouldBeEmpty)
+			})
+		})
+	})
+
+	Convey("Given a session does not exist for the ID", t, func() {
+		mockRedisClient, client := setUpMocks(nil, redis.NewStringResult("", redis.Nil), nil, nil)
+
+		Convey("When Discard is called", func() {
+			err := client.Discard(testCtx, testSessionID)
+
+			Convey("Then ErrSessionNotFound is returned and nothing is deleted", func() {
+				So(err, ShouldEqual, ErrSessionNotFound)
+				So(mockRedisClient.DelCalls(), ShouldHaveLength, 0)
+			})
+		})
+	})
+}
+
+func TestClient_DeleteByID(t *testing.T) {
+	Convey("Given a session exists for the ID", t, func() {
+		mockRedisClient, client := setUpMocks(
+			redis.NewStatusCmd(testCtx),
+			redis.NewStringResult(string(resp), nil),
+			redis.NewScanCmdResult(nil, 0, nil),
+			redis.NewBoolCmd(testCtx),
+		)
+
+		Convey("When DeleteByID is called", func() {
+			err := client.DeleteByID(testCtx, testSessionID)
+
+			Convey("Then the session and its index entry are removed and no error is returned", func() {
+				So(err, ShouldBeNil)
+				So(mockRedisClient.GetCalls(), ShouldHaveLength, 1)
+				So(mockRedisClient.GetCalls()[0].Key, ShouldEqual, testIDKey)
+				So(mockRedisClient.TxPipelinedCalls(), ShouldHaveLength, 1)
+				So(mockRedisClient.DelCalls(), ShouldHaveLength, 2)
+				So(mockRedisClient.DelCalls()[0].Keys, ShouldResemble, []string{testIDKey})
+				So(mockRedisClient.DelCalls()[1].Keys, ShouldResemble, []string{testAccessKey})
+				So(mockRedisClient.ZRemCalls(), ShouldHaveLength, 1)
+				So(mockRedisClient.ZRemCalls()[0].Key, ShouldEqual, testUserKey)
+				So(mockRedisClient.ZRemCalls()[0].Members, ShouldResemble, []interface{}{testSessionID})
+				So(mockRedisClient.SetXXCalls(), ShouldHaveLength, 0)
+			})
+		})
+	})
+
+	Convey("Given a session does not exist for the ID", t, func() {
+		mockRedisClient, client := setUpMocks(
+			nil,
+			redis.NewStringResult("", redis.Nil),
+			nil,
+			nil,
+		)
+
+		Convey("When DeleteByID is called", func() {
+			err := client.DeleteByID(testCtx, testSessionID)
+
+			Convey("Then ErrSessionNotFound is returned and nothing is deleted", func() {
+				So(err, ShouldEqual, ErrSessionNotFound)
+				So(mockRedisClient.DelCalls(), ShouldHaveLength, 0)
+			})
+		})
+	})
+
+	Convey("Given the session key expires before it can be deleted", t, func() {
+		mockRedisClient, client := setUpMocks(
+			nil,
+			redis.NewStringResult(string(resp), nil),
+			nil,
+			nil,
+		)
+		mockRedisClient.DelFunc = func(ctx context.Context, keys ...string) *redis.IntCmd {
+			return redis.NewIntResult(0, nil)
+		}
+
+		Convey("When DeleteByID is called", func() {
+			err := client.DeleteByID(testCtx, testSessionID)
+
+			Convey("Then ErrSessionNotFound is returned", func() {
+				So(err, ShouldEqual, ErrSessionNotFound)
+				So(mockRedisClient.DelCalls(), ShouldHaveLength, 2)
+			})
+		})
+	})
+
+	Convey("Given redis client.Del returns an error", t, func() {
+		mockRedisClient, client := setUpMocks(
+			nil,
+			redis.NewStringResult(string(resp), nil),
+			nil,
+			nil,
+		)
+		mockRedisClient.DelFunc = func(ctx context.Context, keys ...string) *redis.IntCmd {
+			return redis.NewIntResult(0, errors.New("some redis error"))
+		}
+
+		Convey("When DeleteByID is called", func() {
+			err := client.DeleteByID(testCtx, testSessionID)
+
+			Convey("Then the expected error is returned", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "elasticache client.Del returned an unexpected error: some redis error")
+			})
+		})
+	})
+
+	Convey("Given a blank session ID", t, func() {
+		mockRedisClient, client := setUpMocks(nil, nil, nil, nil)
+
+		Convey("When DeleteByID is called", func() {
+			err := client.DeleteByID(testCtx, "")
+
+			Convey("Then ErrEmptySessionID is returned", func() {
+				So(err, ShouldEqual, ErrEmptySessionID)
+				So(mockRedisClient.GetCalls(), ShouldHaveLength, 0)
+				So(mockRedisClient.DelCalls(), ShouldHaveLength, 0)
+			})
+		})
+	})
+}
+
+func TestClient_DeleteByEmail(t *testing.T) {
+	Convey("Given a user with several sessions", t, func() {
+		mockRedisClient, client := setUpMocks(nil, nil, nil, nil)
+		older := newTestSession("older", time.Now().Add(-time.Hour))
+		withUserIndex(mockRedisClient, marshal(older), resp)
+
+		Convey("When DeleteByEmail is called", func() {
+			err := client.DeleteByEmail(testCtx, testEmail)
+
+			Convey("Then only the latest session and its index entry are removed and no error is returned", func() {
+				So(err, ShouldBeNil)
+				So(mockRedisClient.ZRangeCalls(), ShouldHaveLength, 1)
+				So(mockRedisClient.ZRangeCalls()[0].Key, ShouldEqual, testUserKey)
+				So(mockRedisClient.DelCalls(), ShouldHaveLength, 2)
+				So(mockRedisClient.DelCalls()[0].Keys, ShouldResemble, []string{testIDKey})
+				So(mockRedisClient.DelCalls()[1].Keys, ShouldResemble, []string{testAccessKey})
+				So(mockRedisClient.ZRemCalls(), ShouldHaveLength, 1)
+				So(mockRedisClient.ZRemCalls()[0].Members, ShouldResemble, []interface{}{testSessionID})
+			})
+		})
+	})
+
+	Convey("Given a session does not exist for the email", t, func() {
+		mockRedisClient, client := setUpMocks(nil, nil, nil, nil)
+
+		Convey("When DeleteByEmail is called", func() {
+			err := client.DeleteByEmail(testCtx, testEmail)
+
+			Convey("Then ErrSessionNotFound is returned and nothing is deleted", func() {
+				So(err, ShouldEqual, ErrSessionNotFound)
+				So(mockRedisClient.DelCalls(), ShouldHaveLength, 0)
+			})
+		})
+	})
+
+	Convey("Given a blank session email", t, func() {
+		mockRedisClient, client := setUpMocks(nil, nil, nil, nil)
+
+		Convey("When DeleteByEmail is called", func() {
+			err := client.DeleteByEmail(testCtx, "")
+
+			Convey("Then ErrEmptySessionEmail is returned", func() {
+				So(err, ShouldEqual, ErrEmptySessionEmail)
+				So(mockRedisClient.ZRangeCalls(), ShouldHaveLength, 0)
+				So(mockRedisClient.DelCalls(), ShouldHaveLength, 0)
+			})
+		})
+	})
+}
+
+func TestClient_RevokeByEmail(t *testing.T) {
+	Convey("Given a user with several sessions", t, func() {
+		mockRedisClient, client := setUpMocks(nil, nil, nil, nil)
+		older := newTestSession("older", time.Now().Add(-time.Hour))
+		withUserIndex(mockRedisClient, marshal(older), resp)
+
+		Convey("When RevokeByEmail is called", func() {
+			revoked, err := client.RevokeByEmail(testCtx, testEmail)
+
+			Convey("Then every session and index entry for the user is removed in a single transaction", func() {
+				So(err, ShouldBeNil)
+				So(revoked, ShouldEqual, 2)
+				So(mockRedisClient.ZRangeCalls(), ShouldHaveLength, 1)
+				So(mockRedisClient.ZRangeCalls()[0].Key, ShouldEqual, testUserKey)
+				So(mockRedisClient.TxPipelinedCalls(), ShouldHaveLength, 1)
+				So(mockRedisClient.DelCalls(), ShouldHaveLength, 4)
+				So(mockRedisClient.DelCalls()[0].Keys, ShouldResemble, []string{"session:id:older"})
+				So(mockRedisClient.DelCalls()[1].Keys, ShouldResemble, []string{"session:access:older"})
+				So(mockRedisClient.DelCalls()[2].Keys, ShouldResemble, []string{testIDKey})
+				So(mockRedisClient.DelCalls()[3].Keys, ShouldResemble, []string{testAccessKey})
+				So(mockRedisClient.ZRemCalls(), ShouldHaveLength, 1)
+				So(mockRedisClient.ZRemCalls()[0].Key, ShouldEqual, testUserKey)
+				So(mockRedisClient.ZRemCalls()[0].Members, ShouldResemble, []interface{}{"older", testSessionID})
+			})
+
+			Convey("And no other keys are scanned or removed", func() {
+				So(mockRedisClient.ScanCalls(), ShouldHaveLength, 0)
+			})
+		})
+	})
+
+	Convey("Given some of the user's sessions have already expired", t, func() {
+		mockRedisClient, client := setUpMocks(nil, nil, nil, nil)
+		withUserIndex(mockRedisClient, nil, resp)
+		mockRedisClient.DelFunc = func(ctx context.Context, keys ...string) *redis.IntCmd {
+			if keys[0] == testIDKey {
+				return redis.NewIntResult(1, nil)
+			}
+			return redis.NewIntResult(0, nil)
+		}
+
+		Convey("When RevokeByEmail is called", func() {
+			revoked, err := client.RevokeByEmail(testCtx, testEmail)
+
+			Convey("Then only the sessions that were removed are counted", func() {
+				So(err, ShouldBeNil)
+				So(revoked, ShouldEqual, 1)
+			})
+		})
+	})
+
+	Convey("Given the user has no sessions", t, func() {
+		mockRedisClient, client := setUpMocks(nil, nil, nil, nil)
+
+		Convey("When RevokeByEmail is called", func() {
+			revoked, err := client.RevokeByEmail(testCtx, testEmail)
+
+			Convey("Then zero is returned and nothing is deleted", func() {
+				So(err, ShouldBeNil)
+				So(revoked, ShouldEqual, 0)
+				So(mockRedisClient.TxPipelinedCalls(), ShouldHaveLength, 0)
+			})
+		})
+	})
+
+	Convey("Given redis client.Del returns an error", t, func() {
+		mockRedisClient, client := setUpMocks(nil, nil, nil, nil)
+		withUserIndex(mockRedisClient, resp)
+		mockRedisClient.DelFunc = func(ctx context.Context, keys ...string) *redis.IntCmd {
+			return redis.NewIntResult(0, errors.New("some redis error"))
+		}
+
+		Convey("When RevokeByEmail is called", func() {
+			revoked, err := client.RevokeByEmail(testCtx, testEmail)
+
+			Convey("Then the expected error is returned", func() {
+				So(revoked, ShouldEqual, 0)
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "elasticache client.Del returned an unexpected error: some redis error")
+			})
+		})
+	})
+
+	Convey("Given a blank session email", t, func() {
+		mockRedisClient, client := setUpMocks(nil, nil, nil, nil)
+
+		Convey("When RevokeByEmail is called", func() {
+			_, err := client.RevokeByEmail(testCtx, "")
+
+			Convey("Then ErrEmptySessionEmail is returned", func() {
+				So(err, ShouldEqual, ErrEmptySessionEmail)
+				So(mockRedisClient.ZRangeCalls(), ShouldHaveLength, 0)
+			})
+		})
+	})
+}
+
+func TestClient_DeleteAll(t *testing.T) {
+	Convey("Given DeleteAll removes all sessions from cache", t, func() {
+		mockRedisClient, client := setUpMocks(
+			redis.NewStatusCmd(testCtx),
+			redis.NewStringCmd(testCtx),
+			nil,
+			redis.NewBoolCmd(testCtx),
+		)
+		mockRedisClient.ScanFunc = func(ctx context.Context, cursor uint64, match string, count int64) *redis.ScanCmd {
+			if cursor == 0 {
+				return redis.NewScanCmdResult([]string{testIDKey, testUserKey}, 7, nil)
+			}
+			return redis.NewScanCmdResult([]string{"session:id:5678"}, 0, nil)
+		}
+
+		Convey("When DeleteAll is called", func() {
+			err := client.DeleteAll(testCtx)
+
+			Convey("Then only keys in the session namespace are scanned and removed and no error is returned", func() {
+				So(err, ShouldBeNil)
+				So(mockRedisClient.ScanCalls(), ShouldHaveLength, 2)
+				So(mockRedisClient.ScanCalls()[0].Cursor, ShouldEqual, 0)
+				So(mockRedisClient.ScanCalls()[0].Match, ShouldEqual, "session:*")
+				So(mockRedisClient.ScanCalls()[1].Cursor, ShouldEqual, 7)
+
+				So(mockRedisClient.DelCalls(), ShouldHaveLength, 2)
+				So(mockRedisClient.DelCalls()[0].Keys, ShouldResemble, []string{testIDKey, testUserKey})
+				So(mockRedisClient.DelCalls()[1].Keys, ShouldResemble, []string{"session:id:5678"})
+			})
+		})
+	})
+
+	Convey("Given a key prefix containing glob characters and a key outside it that the unescaped prefix would match", t, func() {
+		m, err := miniredis.Run()
+		So(err, ShouldBeNil)
+		defer m.Close()
+
+		client, err := New(Config{Addr: m.Addr(), Password: "password", TTL: testTTL, KeyPrefix: "svc*:"})
+		So(err, ShouldBeNil)
+		m.RequireAuth("password")
+
+		So(client.SetSession(testCtx, newTestSession(testSessionID, time.Now())), ShouldBeNil)
+		So(m.Set("svc-other:id:other", "other"), ShouldBeNil)
+
+		Convey("When the sessions are counted", func() {
+			count, err := client.CountSessions(testCtx)
+
+			Convey("Then only sessions within the prefix are counted", func() {
+				So(err, ShouldBeNil)
+				So(count, ShouldEqual, 1)
+			})
+		})
+
+		Convey("When DeleteAll is called", func() {
+			err := client.DeleteAll(testCtx)
+
+			Convey("Then only keys within the prefix are removed", func() {
+				So(err, ShouldBeNil)
+				So(m.Exists("svc*:id:"+testSessionID), ShouldBeFalse)
+				So(m.Exists("svc-other:id:other"), ShouldBeTrue)
+			})
+		})
+	})
+
+	Convey("Given a key prefix containing every glob character", t, func() {
+		pattern := keyPattern(`a*b?c[d]e\`)
+
+		Convey("Then each is escaped in the pattern matching the keys beginning with it", func() {
+			So(pattern, ShouldEqual, `a\*b\?c\[d\]e\\*`)
+		})
+	})
+
+	Convey("Given there are no sessions in the cache", t, func() {
+		mockRedisClient, client := setUpMocks(
+			redis.NewStatusCmd(testCtx),
+			redis.NewStringCmd(testCtx),
+			redis.NewScanCmdResult(nil, 0, nil),
+			redis.NewBoolCmd(testCtx),
+		)
+
+		Convey("When DeleteAll is called", func() {
+			err := client.DeleteAll(testCtx)
+
+			Convey("Then nothing is deleted and no error is returned", func() {
+				So(err, ShouldBeNil)
+				So(mockRedisClient.ScanCalls(), ShouldHaveLength, 1)
+				So(mockRedisClient.DelCalls(), ShouldHaveLength, 0)
+			})
+		})
+	})
+
+	Convey("Given DeleteAll returns an error", t, func() {
+		mockRedisClient, client := setUpMocks(
+			redis.NewStatusCmd(testCtx),
+			redis.NewStringCmd(testCtx),
+			redis.NewScanCmdResult(nil, 0, errors.New("some redis error")),
+			redis.NewBoolCmd(testCtx),
+		)
+
+		Convey("When DeleteAll is called", func() {
+			err := client.DeleteAll(testCtx)
+
+			Convey("Then no sessions are removed and a redis error is returned", func() {
+				So(err, ShouldNotBeEmpty)
+				So(err.Error(), ShouldEqual, "some redis error")
+				So(mockRedisClient.ScanCalls(), ShouldHaveLength, 1)
+				So(mockRedisClient.DelCalls(), ShouldHaveLength, 0)
+			})
+		})
+
+	})
+}
+
+func TestClient_Events(t *testing.T) {
+	Convey("Given a client publishing session events", t, func() {
+		publisher := &events.InMemoryPublisher{}
+		mockRedisClient, client := setUpMocks(
+			redis.NewStatusCmd(testCtx),
+			redis.NewStringResult(string(resp), nil),
+			nil,
+			redis.NewBoolResult(true, nil),
+		)
+		client.(*ElasticacheClient).publisher = publisher
+		client.(*ElasticacheClient).accessPublisher = publisher
+
+		Convey("When a session is read", func() {
+			_, err := client.GetByID(testCtx, testSessionID)
+
+			Convey("Then an accessed event is published with the session ID and hashed email", func() {
+				So(err, ShouldBeNil)
+				So(publisher.Events(), ShouldHaveLength, 1)
+				e := publisher.Events()[0]
+				So(e.Reason, ShouldEqual, events.Accessed)
+				So(e.SessionID, ShouldEqual, testSessionID)
+				So(e.EmailHash, ShouldEqual, events.HashEmail(testEmail))
+				So(e.Timestamp, ShouldNotBeEmpty)
+			})
+		})
+
+		Convey("When a session is refreshed", func() {
+			_, err := client.Refresh(testCtx, testSessionID)
+
+			Convey("Then an accessed event is published", func() {
+				So(err, ShouldBeNil)
+				So(publisher.Reasons(), ShouldResemble, []string{events.Accessed})
+			})
+		})
+
+		Convey("When a session is deleted", func() {
+			err := client.DeleteByID(testCtx, testSessionID)
+
+			Convey("Then a revoked event is published", func() {
+				So(err, ShouldBeNil)
+				So(publisher.Reasons(), ShouldResemble, []string{events.Revoked})
+			})
+		})
+
+		Convey("When a session that does not exist is deleted", func() {
+			mockRedisClient.DelFunc = func(ctx context.Context, keys ...string) *redis.IntCmd {
+				return redis.NewIntResult(0, nil)
+			}
+			err := client.DeleteByID(testCtx, testSessionID)
+
+			Convey("Then no event is published", func() {
+				So(err, ShouldEqual, ErrSessionNotFound)
+				So(publisher.Events(), ShouldBeEmpty)
+			})
+		})
+
+		Convey("When a session has exceeded its max lifetime", func() {
+			mockRedisClient.EvalShaFunc = func(ctx context.Context, sha1 string, keys []string, args ...interface{}) *redis.Cmd {
+				return redis.NewCmdResult([]interface{}{int64(0), testEmail}, nil)
+			}
+			_, err := client.GetByID(testCtx, testSessionID)
+
+			Convey("Then an expired event is published", func() {
+				So(err, ShouldEqual, ErrSessionNotFound)
+				So(publisher.Reasons(), ShouldResemble, []string{events.Expired})
+			})
+		})
+
+		Convey("When the user's sessions are revoked", func() {
+			older := newTestSession("older", time.Now().Add(-time.Hour))
+			withUserIndex(mockRedisClient, marshal(older), resp)
+			_, err := client.RevokeByEmail(testCtx, testEmail)
+
+			Convey("Then a revoked event is published for each session", func() {
+				So(err, ShouldBeNil)
+				So(publisher.Reasons(), ShouldResemble, []string{events.Revoked, events.Revoked})
+				So(publisher.Events()[0].SessionID, ShouldEqual, "older")
+				So(publisher.Events()[1].SessionID, ShouldEqual, testSessionID)
+			})
+		})
+
+		Convey("When the user's sessions are listed and one has exceeded its max lifetime", func() {
+			client.(*ElasticacheClient).maxLifetime = 12 * time.Hour
+			withUserIndex(mockRedisClient, nil, resp)
+			_, err := client.ListByEmail(testCtx, testEmail)
+
+			Convey("Then an expired event is published for that session only", func() {
+				So(err, ShouldBeNil)
+				So(publisher.Reasons(), ShouldResemble, []string{events.Expired})
+				So(publisher.Events()[0].SessionID, ShouldEqual, testSessionID)
+			})
+
+			Convey("And the session whose key has already expired is left to the expiry subscriber", func() {
+				So(mockRedisClient.ZRemCalls()[0].Members, ShouldResemble, []interface{}{"id-0", testSessionID})
+			})
+		})
+
+		Convey("When a new session evicts the user's oldest session", func() {
+			client.(*ElasticacheClient).maxSessions = 1
+			client.(*ElasticacheClient).limitPolicy = EvictOldest
+			withUserIndex(mockRedisClient, resp)
+			withAdmitted(mockRedisClient, testSessionID)
+			err := client.SetSession(testCtx, newTestSession("new", time.Now()))
+
+			Convey("Then a revoked event is published for the evicted session", func() {
+				So(err, ShouldBeNil)
+				So(mockRedisClient.DelCalls()[0].Keys, ShouldResemble, []string{testIDKey})
+				So(mockRedisClient.DelCalls()[1].Keys, ShouldResemble, []string{testAccessKey})
+				So(publisher.Reasons(), ShouldResemble, []string{events.Revoked})
+				So(publisher.Events()[0].SessionID, ShouldEqual, testSessionID)
+			})
+		})
+
+		Convey("When a new session evicts the user's oldest session and the revoked event cannot be published", func() {
+			client.(*ElasticacheClient).maxSessions = 1
+			client.(*ElasticacheClient).limitPolicy = EvictOldest
+			withUserIndex(mockRedisClient, resp)
+			withAdmitted(mockRedisClient, testSessionID)
+			publisher.Err = errors.New("broker unavailable")
+			err := client.SetSession(testCtx, newTestSession("new", time.Now()))
+
+			Convey("Then the new session is still added as the eviction is already committed", func() {
+				So(err, ShouldBeNil)
+				So(mockRedisClient.SetCalls()[0].Key, ShouldEqual, DefaultKeyPrefix+idKeyPrefix+"new")
+			})
+		})
+
+		Convey("When the publisher fails", func() {
+			publisher.Err = errors.New("broker unavailable")
+
+			Convey("Then an error publishing an accessed event is only logged", func() {
+				_, err := client.GetByID(testCtx, testSessionID)
+				So(err, ShouldBeNil)
+			})
+
+			Convey("Then an error publishing a revoked event is only logged as the session is already deleted", func() {
+				err := client.DeleteByID(testCtx, testSessionID)
+				So(err, ShouldBeNil)
+				So(mockRedisClient.DelCalls(), ShouldHaveLength, 2)
+			})
+
+			Convey("Then an error publishing the revoked events for a user's sessions is only logged and the count returned", func() {
+				withUserIndex(mockRedisClient, resp)
+				revoked, err := client.RevokeByEmail(testCtx, testEmail)
+				So(err, ShouldBeNil)
+				So(revoked, ShouldEqual, 1)
+			})
+
+			Convey("Then an error publishing an expired event is only logged and the session reported as not found", func() {
+				mockRedisClient.EvalShaFunc = func(ctx context.Context, sha1 string, keys []string, args ...interface{}) *redis.Cmd {
+					return redis.NewCmdResult([]interface{}{int64(0), testEmail}, nil)
+				}
+				_, err := client.GetByID(testCtx, testSessionID)
+				So(err, ShouldEqual, ErrSessionNotFound)
+			})
+		})
+
+		Convey("When accessed events are sent to a separate publisher", func() {
+			accessPublisher := &events.InMemoryPublisher{}
+			client.(*ElasticacheClient).accessPublisher = accessPublisher
+			_, err := client.GetByID(testCtx, testSessionID)
+
+			Convey("Then the accessed event is published with it", func() {
+				So(err, ShouldBeNil)
+				So(accessPublisher.Reasons(), ShouldResemble, []string{events.Accessed})
+				So(publisher.Events(), ShouldBeEmpty)
+			})
+		})
+	})
+}
+
+func setUpMocks(setStatusCmd *redis.StatusCmd, getStringCmd *redis.StringCmd, scanCmd *redis.ScanCmd, setXXBoolCmd *redis.BoolCmd) (*RedisClienterMock, SessionCache) {
+	mockRedisClient := &RedisClienterMock{
+		PingFunc: nil,
+		SetFunc: func(ctx context.Context, key string, value interface{}, ttl time.Duration) *redis.StatusCmd {
+			return setStatusCmd
+		},
+		GetFunc: func(ctx context.Context, key string) *redis.StringCmd {
+			return getStringCmd
+		},
+		ScanFunc: func(ctx context.Context, cursor uint64, match string, count int64) *redis.ScanCmd {
+			return scanCmd
+		},
+		SetXXFunc: func(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.BoolCmd {
+			return setXXBoolCmd
+		},
+		ExpireFunc: func(ctx context.Context, key string, expiration time.Duration) *redis.BoolCmd {
+			return redis.NewBoolResult(true, nil)
+		},
+		DelFunc: func(ctx context.Context, keys ...string) *redis.IntCmd {
+			return redis.NewIntResult(int64(len(keys)), nil)
+		},
+		ZAddFunc: func(ctx context.Context, key string, members ...*redis.Z) *redis.IntCmd {
+			return redis.NewIntResult(int64(len(members)), nil)
+		},
+		ZRemFunc: func(ctx context.Context, key string, members ...interface{}) *redis.IntCmd {
+			return redis.NewIntResult(int64(len(members)), nil)
+		},
+		ZRangeFunc: func(ctx context.Context, key string, start, stop int64) *redis.StringSliceCmd {
+			return redis.NewStringSliceResult(nil, nil)
+		},
+		EvalShaFunc: func(ctx context.Context, sha1 string, keys []string, args ...interface{}) *redis.Cmd {
+			return redis.NewCmdResult([]interface{}{testTTL.Milliseconds(), testEmail}, nil)
+		}}
+	mockRedisClient.TxPipelinedFunc = func(ctx context.Context, fn func(redis.Pipeliner) error) ([]redis.Cmder, error) {
+		return newPipelineMock(mockRedisClient).pipelined(fn)
+	}
+	return mockRedisClient, &ElasticacheClient{
+		lifetime:        lifetime{ttl: testTTL},
+		client:          mockRedisClient,
+		pinger:          mockRedisClient,
+		keyPrefix:       DefaultKeyPrefix,
+		publisher:       events.NopPublisher{},
+		accessPublisher: events.NopPublisher{},
+		codec:           plainCodec{},
+	}
+}
+
+// slot - returns the redis cluster hash slot of key, the CRC16 of its hash tag, or of the whole key if it has none,
+// modulo 16384. miniredis reports every key as being in the same slot so cannot be asked.
+func slot(key string) uint16 {
+	if open := strings.Index(key, "{"); open >= 0 {
+		if end := strings.Index(key[open+1:], "}"); end > 0 {
+			key = key[open+1 : open+1+end]
+		}
+	}
+
+	var crc uint16
+	for i := 0; i < len(key); i++ {
+		crc ^= uint16(key[i]) << 8
+		for bit := 0; bit < 8; bit++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc % 16384
+}
+
+// withAdmitted - configures the mock so that admitScript adds the new session to the user's index, evicting the sessions
+// with the IDs evicted
+func withAdmitted(mockRedisClient *RedisClienterMock, evicted ...string) {
+	refresh := mockRedisClient.EvalShaFunc
+	mockRedisClient.EvalShaFunc = func(ctx context.Context, sha1 string, keys []string, args ...interface{}) *redis.Cmd {
+		if sha1 != admitScript.hash {
+			return refresh(ctx, sha1, keys, args...)
+		}
+		result := []interface{}{int64(1)}
+		for _, id := range evicted {
+			result = append(result, id)
+		}
+		return redis.NewCmdResult(result, nil)
+	}
+}
+
+// withUserIndex - configures the mock so the test user's index holds a session ID for each of the provided session
+// JSON values. A nil value simulates a session that has expired, its ID in the index is id-<position>.
+func withUserIndex(mockRedisClient *RedisClienterMock, sessions ...[]byte) {
+	ids := make([]string, len(sessions))
+	values := make([]interface{}, len(sessions))
+	for i, b := range sessions {
+		if b == nil {
+			ids[i] = fmt.Sprintf("id-%d", i)
+			continue
+		}
+
+		s := &session.Session{}
+		if err := json.Unmarshal(b, s); err != nil {
+			panic(err)
+		}
+		ids[i] = s.ID
+		values[i] = string(b)
+	}
+
+	mockRedisClient.ZRangeFunc = func(ctx context.Context, key string, start, stop int64) *redis.StringSliceCmd {
+		return redis.NewStringSliceResult(ids, nil)
+	}
+	mockRedisClient.GetFunc = func(ctx context.Context, key string) *redis.StringCmd {
+		for i, id := range ids {
+			if key == DefaultKeyPrefix+idKeyPrefix+id && values[i] != nil {
+				return redis.NewStringResult(values[i].(string), nil)
+			}
+		}
+		return redis.NewStringResult("", redis.Nil)
+	}
+}
+
+func newTestSession(id string, start time.Time) *session.Session {
+	return &session.Session{
+		ID:           id,
+		Email:        testEmail,
+		Start:        start,
+		LastAccessed: start,
+	}
+}
+
+func marshal(s *session.Session) []byte {
+	b, _ := s.MarshalJSON()
+	return b
+}
+
+// pipelineMock is a redis.Pipeliner that queues the commands used by the cache and, on exec, forwards them to the
+// RedisClienterMock so that calls can be asserted in the same way as non-pipelined commands.
+type pipelineMock struct {
+	redis.Pipeliner
+	client *RedisClienterMock
+	queued []func() redis.Cmder
+}
+
+func newPipelineMock(client *RedisClienterMock) *pipelineMock {
+	return &pipelineMock{client: client}
+}
+
+func (p *pipelineMock) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd {
+	p.queued = append(p.queued, func() redis.Cmder { return p.client.Set(ctx, key, value, expiration) })
+	return redis.NewStatusCmd(ctx)
+}
+
+func (p *pipelineMock) Get(ctx context.Context, key string) *redis.StringCmd {
+	p.queued = append(p.queued, func() redis.Cmder { return p.client.Get(ctx, key) })
+	return redis.NewStringCmd(ctx)
+}
+
+// HGet - is not forwarded to the client, the access keys it reads are always reported as missing
+func (p *pipelineMock) HGet(ctx context.Context, key, field string) *redis.StringCmd {
+	p.queued = append(p.queued, func() redis.Cmder { return redis.NewStringResult("", redis.Nil) })
+	return redis.NewStringCmd(ctx)
+}
+
+// HSet - is not forwarded to the client, the access keys it writes are asserted against miniredis instead
+func (p *pipelineMock) HSet(ctx context.Context, key string, values ...interface{}) *redis.IntCmd {
+	p.queued = append(p.queued, func() redis.Cmder { return redis.NewIntResult(int64(len(values)/2), nil) })
+	return redis.NewIntCmd(ctx)
+}
+
+// PExpire - is not forwarded to the client, it is only used for access keys
+func (p *pipelineMock) PExpire(ctx context.Context, key string, expiration time.Duration) *redis.BoolCmd {
+	p.queued = append(p.queued, func() redis.Cmder { return redis.NewBoolResult(true, nil) })
+	return redis.NewBoolCmd(ctx)
+}
+
+func (p *pipelineMock) SetXX(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.BoolCmd {
+	p.queued = append(p.queued, func() redis.Cmder { return p.client.SetXX(ctx, key, value, expiration) })
+	return redis.NewBoolCmd(ctx)
+}
+
+func (p *pipelineMock) Expire(ctx context.Context, key string, expiration time.Duration) *redis.BoolCmd {
+	p.queued = append(p.queued, func() redis.Cmder { return p.client.Expire(ctx, key, expiration) })
+	return redis.NewBoolCmd(ctx)
+}
+
+func (p *pipelineMock) Del(ctx context.Context, keys ...string) *redis.IntCmd {
+	p.queued = append(p.queued, func() redis.Cmder { return p.client.Del(ctx, keys...) })
+	return redis.NewIntCmd(ctx)
+}
+
+func (p *pipelineMock) ZAdd(ctx context.Context, key string, members ...*redis.Z) *redis.IntCmd {
+	p.queued = append(p.queued, func() redis.Cmder { return p.client.ZAdd(ctx, key, members...) })
+	return redis.NewIntCmd(ctx)
+}
+
+func (p *pipelineMock) ZRem(ctx context.Context, key string, members ...interface{}) *redis.IntCmd {
+	p.queued = append(p.queued, func() redis.Cmder { return p.client.ZRem(ctx, key, members...) })
+	return redis.NewIntCmd(ctx)
+}
+
+// pipelined mimics MULTI/EXEC: every queued command is executed and the first error encountered is returned
+func (p *pipelineMock) pipelined(fn func(redis.Pipeliner) error) ([]redis.Cmder, error) {
+	if err := fn(p); err != nil {
+		return nil, err
+	}
+
+	var firstErr error
+	cmds := make([]redis.Cmder, 0, len(p.queued))
+	for _, exec := range p.queued {
+		cmd := exec()
+		if firstErr == nil && cmd != nil && cmd.Err() != nil {
+			firstErr = cmd.Err()
+		}
+		cmds = append(cmds, cmd)
 	}
+	return cmds, firstErr
 }
@@ -0,0 +1,153 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/ONSdigital/dp-sessions-api/events"
+	"github.com/ONSdigital/log.go/log"
+	"github.com/go-redis/redis/v8"
+)
+
+// ExpiryObserver - is notified each time a session expires through its TTL
+type ExpiryObserver interface {
+	ObserveExpiry()
+}
+
+// ExpirySubscriber - listens for the keyspace notifications redis sends when a session's ID key expires through its
+// TTL, removes the session from its user's index and reports the expiry. Redis only sends these notifications when
+// notify-keyspace-events includes Ex.
+type ExpirySubscriber struct {
+	cache    *ElasticacheClient
+	observer ExpiryObserver
+	mu       sync.Mutex
+	pubsubs  []*redis.PubSub
+	wg       sync.WaitGroup
+}
+
+// NewExpirySubscriber - creates an ExpirySubscriber for the sessions stored by c. observer may be nil.
+func NewExpirySubscriber(c *ElasticacheClient, observer ExpiryObserver) *ExpirySubscriber {
+	return &ExpirySubscriber{
+		cache:    c,
+		observer: observer,
+	}
+}
+
+// Start - subscribes to expired key notifications and handles them in the background until Close is called. The
+// subscription is re-established if the connection to redis is lost.
+//
+// Redis only notifies the clients connected to the node where a key expired, so in Cluster mode every master is
+// subscribed to. Masters added to the cluster later are not.
+func (s *ExpirySubscriber) Start(ctx context.Context) error {
+	channel := fmt.Sprintf("__keyevent@%d__:expired", s.cache.database)
+
+	err := s.cache.forEachMaster(ctx, func(client RedisClienter) error {
+		pubsub := client.Subscribe(ctx, channel)
+
+		s.mu.Lock()
+		s.pubsubs = append(s.pubsubs, pubsub)
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			for msg := range pubsub.Channel() {
+				s.handle(context.Background(), msg.Payload)
+			}
+		}()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Event(ctx, "subscribed to session expiry notifications", log.INFO, log.Data{"channel": channel, "subscriptions": len(s.pubsubs)})
+	return nil
+}
+
+// Close - unsubscribes and waits for the notifications being handled, if any, to finish
+func (s *ExpirySubscriber) Close(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, pubsub := range s.pubsubs {
+		if err := pubsub.Close(); err != nil {
+			return err
+		}
+	}
+	s.pubsubs = nil
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// handle - cleans up after the expiry of key if it is a session ID key. Keys outside the configured key prefix, and
+// the cache's other keys, are ignored.
+//
+// Every replica of the service is subscribed, so each is notified of the same expiry. A replica only handles the expiry
+// once it has claimed it by deleting the session's owner key, which only one DEL can do, so each expiry is observed and
+// published once however many replicas there are.
+func (s *ExpirySubscriber) handle(ctx context.Context, key string) {
+	c := s.cache
+
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	id, ok := c.sessionID(key)
+	if !ok {
+		return
+	}
+
+	logData := log.Data{"session_id": id}
+
+	owner, err := c.client.Get(ctx, c.ownerKey(id)).Result()
+	if err != nil {
+		if err == redis.Nil {
+			log.Event(ctx, "session expired but its owner is unknown, it has been handled by another replica or will be removed from its user's index when next read", log.INFO, logData)
+			return
+		}
+		log.Event(ctx, "failed to get the owner of an expired session", log.ERROR, log.Error(err), logData)
+		return
+	}
+
+	email, err := c.decodeOwner(id, owner)
+	if err != nil {
+		log.Event(ctx, "failed to decode the owner of an expired session, it will be removed from its user's index when next read", log.WARN, log.Error(err), logData)
+		return
+	}
+
+	claimed, err := c.client.Del(ctx, c.ownerKey(id)).Result()
+	if err != nil {
+		log.Event(ctx, "failed to claim an expired session", log.ERROR, log.Error(err), logData)
+		return
+	}
+	if claimed == 0 {
+		return
+	}
+
+	// the expiry has been claimed so no other replica will report it; a stale index entry is removed when next read
+	for _, userKey := range c.userKeys(email) {
+		if err = c.client.ZRem(ctx, userKey, id).Err(); err != nil {
+			log.Event(ctx, "failed to remove an expired session from its user's index, it will be removed when next read", log.ERROR, log.Error(err), logData)
+		}
+	}
+
+	if s.observer != nil {
+		s.observer.ObserveExpiry()
+	}
+
+	log.Event(ctx, "session expired", log.INFO, logData)
+
+	c.publish(ctx, events.Expired, email, id)
+}
@@ -0,0 +1,170 @@
+package cache
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ONSdigital/dp-sessions-api/events"
+	"github.com/alicebob/miniredis/v2"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const expiredChannel = "__keyevent@0__:expired"
+
+type expiryObserverStub struct {
+	expired int32
+}
+
+func (o *expiryObserverStub) ObserveExpiry() {
+	atomic.AddInt32(&o.expired, 1)
+}
+
+func (o *expiryObserverStub) count() int32 {
+	return atomic.LoadInt32(&o.expired)
+}
+
+func TestExpirySubscriber(t *testing.T) {
+	Convey("Given a subscriber listening for expired sessions", t, func() {
+		m, err := miniredis.Run()
+		So(err, ShouldBeNil)
+		defer m.Close()
+		m.RequireAuth("password")
+
+		publisher := &events.InMemoryPublisher{}
+		c, err := New(Config{
+			Addr:      m.Addr(),
+			Password:  "password",
+			TTL:       testTTL,
+			Publisher: publisher,
+		})
+		So(err, ShouldBeNil)
+		So(c.SetSession(testCtx, newTestSession(testSessionID, time.Now())), ShouldBeNil)
+
+		observer := &expiryObserverStub{}
+		subscriber := NewExpirySubscriber(c, observer)
+		So(subscriber.Start(context.Background()), ShouldBeNil)
+		defer subscriber.Close(context.Background())
+
+		So(eventually(func() bool {
+			return m.PubSubNumSub(expiredChannel)[expiredChannel] == 1
+		}), ShouldBeTrue)
+
+		Convey("When a session's ID key expires", func() {
+			m.Del(testIDKey)
+			m.Publish(expiredChannel, testIDKey)
+
+			Convey("Then an expired event is published for the session", func() {
+				So(eventually(func() bool { return len(publisher.Events()) == 1 }), ShouldBeTrue)
+				e := publisher.Events()[0]
+				So(e.Reason, ShouldEqual, events.Expired)
+				So(e.SessionID, ShouldEqual, testSessionID)
+				So(e.EmailHash, ShouldEqual, events.HashEmail(testEmail))
+			})
+
+			Convey("And the session is removed from its user's index along with its owner key", func() {
+				So(eventually(func() bool { return !m.Exists(testUserKey) }), ShouldBeTrue)
+				So(m.Exists("session:owner:1234"), ShouldBeFalse)
+			})
+
+			Convey("And the expiry is observed", func() {
+				So(eventually(func() bool { return observer.count() == 1 }), ShouldBeTrue)
+			})
+		})
+
+		Convey("When a key that is not a session ID key expires", func() {
+			m.Publish(expiredChannel, testUserKey)
+			m.Publish(expiredChannel, "other:id:1234")
+			m.Del(testIDKey)
+			m.Publish(expiredChannel, testIDKey)
+
+			Convey("Then it is ignored", func() {
+				So(eventually(func() bool { return observer.count() == 1 }), ShouldBeTrue)
+				So(publisher.Events(), ShouldHaveLength, 1)
+			})
+		})
+
+		Convey("When a session whose owner is unknown expires", func() {
+			m.Del(testIDKey)
+			m.Del("session:owner:1234")
+			m.Publish(expiredChannel, testIDKey)
+
+			Convey("Then the expiry is neither observed nor published", func() {
+				time.Sleep(50 * time.Millisecond)
+				So(observer.count(), ShouldEqual, 0)
+				So(publisher.Events(), ShouldBeEmpty)
+				So(m.Exists(testUserKey), ShouldBeTrue)
+			})
+		})
+
+		Convey("When the session cannot be removed from its user's index", func() {
+			m.Del(testUserKey)
+			So(m.Set(testUserKey, "not a sorted set"), ShouldBeNil)
+			m.Del(testIDKey)
+			m.Publish(expiredChannel, testIDKey)
+
+			Convey("Then the claimed expiry is still observed and published once", func() {
+				So(eventually(func() bool { return len(publisher.Events()) > 0 }), ShouldBeTrue)
+				time.Sleep(50 * time.Millisecond)
+				So(m.Exists("session:owner:1234"), ShouldBeFalse)
+				So(observer.count(), ShouldEqual, 1)
+				So(publisher.Reasons(), ShouldResemble, []string{events.Expired})
+				So(publisher.Events()[0].SessionID, ShouldEqual, testSessionID)
+			})
+		})
+
+		Convey("When another replica is also subscribed and a session's ID key expires", func() {
+			otherPublisher := &events.InMemoryPublisher{}
+			other, err := New(Config{
+				Addr:      m.Addr(),
+				Password:  "password",
+				TTL:       testTTL,
+				Publisher: otherPublisher,
+			})
+			So(err, ShouldBeNil)
+
+			otherObserver := &expiryObserverStub{}
+			otherSubscriber := NewExpirySubscriber(other, otherObserver)
+			So(otherSubscriber.Start(context.Background()), ShouldBeNil)
+			defer otherSubscriber.Close(context.Background())
+
+			So(eventually(func() bool {
+				return m.PubSubNumSub(expiredChannel)[expiredChannel] == 2
+			}), ShouldBeTrue)
+
+			m.Del(testIDKey)
+			m.Publish(expiredChannel, testIDKey)
+
+			Convey("Then the expiry is observed and published by only one of them", func() {
+				So(eventually(func() bool { return observer.count()+otherObserver.count() == 1 }), ShouldBeTrue)
+				time.Sleep(50 * time.Millisecond)
+				So(observer.count()+otherObserver.count(), ShouldEqual, 1)
+				So(len(publisher.Events())+len(otherPublisher.Events()), ShouldEqual, 1)
+			})
+		})
+
+		Convey("When the subscriber is closed", func() {
+			err := subscriber.Close(context.Background())
+
+			Convey("Then it unsubscribes", func() {
+				So(err, ShouldBeNil)
+				So(eventually(func() bool {
+					return m.PubSubNumSub(expiredChannel)[expiredChannel] == 0
+				}), ShouldBeTrue)
+			})
+		})
+	})
+}
+
+// eventually - polls condition until it is true, returning false if it is still false after a second
+func eventually(condition func() bool) bool {
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if condition() {
+			return true
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return condition()
+}
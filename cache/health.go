@@ -0,0 +1,169 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	health "github.com/ONSdigital/dp-healthcheck/healthcheck"
+	"github.com/go-redis/redis/v8"
+)
+
+// memoryStatus - the memory usage and evictions reported by redis INFO
+type memoryStatus struct {
+	used    int64
+	max     int64
+	evicted int64
+}
+
+// usage - returns the fraction of maxmemory in use, or zero if redis has no maxmemory
+func (m memoryStatus) usage() float64 {
+	if m.max == 0 {
+		return 0
+	}
+	return float64(m.used) / float64(m.max)
+}
+
+func (m memoryStatus) String() string {
+	if m.max == 0 {
+		return fmt.Sprintf("memory: %d bytes used, no maxmemory, evicted keys: %d", m.used, m.evicted)
+	}
+	return fmt.Sprintf("memory: %d of %d bytes used (%.0f%%), evicted keys: %d", m.used, m.max, m.usage()*100, m.evicted)
+}
+
+// Checker - reports CRITICAL while the circuit breaker is open and WARNING while it is half-open, without pinging
+// redis, as pings would be refused or compete with the probe deciding whether the breaker closes. Otherwise redis is
+// pinged, and WARNING is reported if the ping is slow, redis is close to its maxmemory or keys, and so sessions, have
+// been evicted since the last check. The message includes the latency, memory and pool figures whatever the status.
+func (c *ElasticacheClient) Checker(ctx context.Context, state *health.CheckState) error {
+	if c.breaker != nil {
+		switch c.breaker.State() {
+		case breakerOpen:
+			return state.Update(health.StatusCritical, CircuitOpenMessage, 0)
+		case breakerHalfOpen:
+			return state.Update(health.StatusWarning, CircuitHalfOpenMessage, 0)
+		}
+	}
+
+	// The ping is not retried, so its latency is a single round trip rather than including any retry backoff
+	start := time.Now()
+	err := c.pinger.Ping(ctx).Err()
+	if err != nil {
+		// Generic error
+		return state.Update(health.StatusCritical, err.Error(), 0)
+	}
+	latency := time.Since(start)
+
+	var warnings []string
+	if c.latencyThreshold > 0 && latency > c.latencyThreshold {
+		warnings = append(warnings, fmt.Sprintf("ping latency exceeds %s", c.latencyThreshold))
+	}
+
+	details := []string{fmt.Sprintf("ping: %s", latency)}
+	memory, err := c.memoryStatus(ctx)
+	if err != nil {
+		warnings = append(warnings, fmt.Sprintf("unable to read memory status: %s", err))
+	} else {
+		details = append(details, memory.String())
+
+		if c.memoryThreshold > 0 && memory.usage() > c.memoryThreshold {
+			warnings = append(warnings, fmt.Sprintf("memory usage exceeds %.0f%% of maxmemory", c.memoryThreshold*100))
+		}
+
+		if evicted := c.newlyEvicted(memory.evicted); evicted > 0 {
+			warnings = append(warnings, fmt.Sprintf("%d keys evicted since the last check", evicted))
+		}
+	}
+	details = append(details, poolMessage(c.PoolStats()))
+
+	if len(warnings) > 0 {
+		msg := fmt.Sprintf("%s: %s, %s", DegradedMessage, strings.Join(warnings, ", "), strings.Join(details, ", "))
+		return state.Update(health.StatusWarning, msg, 0)
+	}
+	// Success
+	return state.Update(health.StatusOK, fmt.Sprintf("%s, %s", HealthyMessage, strings.Join(details, ", ")), 0)
+}
+
+// memoryStatus - returns the memory status of the master closest to its maxmemory, along with the keys evicted across
+// every master
+func (c *ElasticacheClient) memoryStatus(ctx context.Context) (memoryStatus, error) {
+	var mu sync.Mutex
+	var status memoryStatus
+	err := c.forEachMaster(ctx, func(client RedisClienter) error {
+		info, err := client.Info(ctx).Result()
+		if err != nil {
+			return err
+		}
+
+		node, err := parseMemoryStatus(info)
+		if err != nil {
+			return err
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		evicted := status.evicted + node.evicted
+		if node.usage() >= status.usage() {
+			status = node
+		}
+		status.evicted = evicted
+		return nil
+	})
+	return status, err
+}
+
+// newlyEvicted - returns the number of keys evicted since the previous check, given the total evicted now. Nothing is
+// reported on the first check, or if the total has fallen because redis restarted.
+func (c *ElasticacheClient) newlyEvicted(evicted int64) int64 {
+	previous := atomic.SwapInt64(&c.lastEvicted, evicted)
+	if previous < 0 || evicted < previous {
+		return 0
+	}
+	return evicted - previous
+}
+
+// parseMemoryStatus - reads the used_memory, maxmemory and evicted_keys fields from the reply to INFO. A missing field
+// is zero.
+func parseMemoryStatus(info string) (memoryStatus, error) {
+	var status memoryStatus
+	fields := map[string]*int64{
+		"used_memory":  &status.used,
+		"maxmemory":    &status.max,
+		"evicted_keys": &status.evicted,
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(info))
+	for scanner.Scan() {
+		parts := strings.SplitN(strings.TrimSpace(scanner.Text()), ":", 2)
+		field, ok := fields[parts[0]]
+		if !ok || len(parts) != 2 {
+			continue
+		}
+
+		v, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil {
+			return memoryStatus{}, fmt.Errorf("invalid %s in redis info: %w", parts[0], err)
+		}
+		*field = v
+	}
+	return status, scanner.Err()
+}
+
+// PoolStats - returns the statistics of the connection pool, summed across every node in Cluster mode
+func (c *ElasticacheClient) PoolStats() *redis.PoolStats {
+	if c.pool == nil {
+		return &redis.PoolStats{}
+	}
+	return c.pool.PoolStats()
+}
+
+// poolMessage - describes the pool statistics, so the pool can be sized from the health check as well as from metrics
+func poolMessage(stats *redis.PoolStats) string {
+	return fmt.Sprintf("pool: %d connections, %d idle, %d hits, %d misses, %d timeouts",
+		stats.TotalConns, stats.IdleConns, stats.Hits, stats.Misses, stats.Timeouts)
+}
@@ -0,0 +1,154 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	health "github.com/ONSdigital/dp-healthcheck/healthcheck"
+	"github.com/go-redis/redis/v8"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestClient_Checker(t *testing.T) {
+	Convey("Given a healthy redis", t, func() {
+		mockRedisClient, sessionCache := setUpMocks(nil, nil, nil, nil)
+		client := sessionCache.(*ElasticacheClient)
+		client.latencyThreshold = time.Second
+		client.memoryThreshold = 0.9
+		client.lastEvicted = -1
+
+		info := redis.NewStringResult("# Memory\r\nused_memory:1048576\r\nmaxmemory:10485760\r\n# Stats\r\nevicted_keys:3\r\n", nil)
+		mockRedisClient.PingFunc = func(ctx context.Context) *redis.StatusCmd {
+			return redis.NewStatusResult("PONG", nil)
+		}
+		mockRedisClient.InfoFunc = func(ctx context.Context, section ...string) *redis.StringCmd {
+			return info
+		}
+
+		check := func() *health.CheckState {
+			state := health.NewCheckState("elasticache")
+			So(client.Checker(testCtx, state), ShouldBeNil)
+			return state
+		}
+
+		Convey("When the health check runs", func() {
+			state := check()
+
+			Convey("Then the status is OK and the latency and memory figures are reported", func() {
+				So(state.Status(), ShouldEqual, health.StatusOK)
+				So(state.Message(), ShouldStartWith, HealthyMessage+", ping: ")
+				So(state.Message(), ShouldContainSubstring, "memory: 1048576 of 10485760 bytes used (10%), evicted keys: 3")
+				So(state.Message(), ShouldContainSubstring, "pool: 0 connections")
+			})
+		})
+
+		Convey("When the ping is slower than the latency threshold", func() {
+			client.latencyThreshold = time.Millisecond
+			mockRedisClient.PingFunc = func(ctx context.Context) *redis.StatusCmd {
+				time.Sleep(5 * time.Millisecond)
+				return redis.NewStatusResult("PONG", nil)
+			}
+			state := check()
+
+			Convey("Then the status is WARNING", func() {
+				So(state.Status(), ShouldEqual, health.StatusWarning)
+				So(state.Message(), ShouldStartWith, DegradedMessage+": ping latency exceeds 1ms, ping: ")
+			})
+		})
+
+		Convey("When used memory is close to maxmemory", func() {
+			info = redis.NewStringResult("used_memory:9961472\r\nmaxmemory:10485760\r\nevicted_keys:0\r\n", nil)
+			state := check()
+
+			Convey("Then the status is WARNING", func() {
+				So(state.Status(), ShouldEqual, health.StatusWarning)
+				So(state.Message(), ShouldContainSubstring, "memory usage exceeds 90% of maxmemory")
+				So(state.Message(), ShouldContainSubstring, "memory: 9961472 of 10485760 bytes used (95%)")
+			})
+		})
+
+		Convey("When redis has no maxmemory", func() {
+			info = redis.NewStringResult("used_memory:9961472\r\nmaxmemory:0\r\n", nil)
+			state := check()
+
+			Convey("Then the memory threshold does not apply", func() {
+				So(state.Status(), ShouldEqual, health.StatusOK)
+				So(state.Message(), ShouldContainSubstring, "memory: 9961472 bytes used, no maxmemory")
+			})
+		})
+
+		Convey("When keys are evicted between checks", func() {
+			So(check().Status(), ShouldEqual, health.StatusOK)
+			info = redis.NewStringResult("used_memory:1048576\r\nmaxmemory:10485760\r\nevicted_keys:5\r\n", nil)
+			state := check()
+
+			Convey("Then the status is WARNING", func() {
+				So(state.Status(), ShouldEqual, health.StatusWarning)
+				So(state.Message(), ShouldContainSubstring, "2 keys evicted since the last check")
+			})
+
+			Convey("And the next check is OK if no more keys are evicted", func() {
+				So(check().Status(), ShouldEqual, health.StatusOK)
+			})
+		})
+
+		Convey("When the memory status cannot be read", func() {
+			info = redis.NewStringResult("", errors.New("ERR unknown command"))
+			state := check()
+
+			Convey("Then the status is WARNING", func() {
+				So(state.Status(), ShouldEqual, health.StatusWarning)
+				So(state.Message(), ShouldContainSubstring, "unable to read memory status: ERR unknown command")
+			})
+		})
+
+		Convey("When redis cannot be pinged", func() {
+			mockRedisClient.PingFunc = func(ctx context.Context) *redis.StatusCmd {
+				return redis.NewStatusResult("", errors.New("connection refused"))
+			}
+			state := check()
+
+			Convey("Then the status is CRITICAL", func() {
+				So(state.Status(), ShouldEqual, health.StatusCritical)
+				So(state.Message(), ShouldEqual, "connection refused")
+			})
+		})
+	})
+
+	Convey("Given a client configured with a circuit breaker and retries", t, func() {
+		c, err := New(Config{
+			Addr:                    "123.0.0.1",
+			Password:                testSessionID,
+			TTL:                     testTTL,
+			MaxRetries:              2,
+			BreakerFailureThreshold: 5,
+		})
+		So(err, ShouldBeNil)
+
+		Convey("Then the health check pings through the breaker without retrying, so retry backoff is not measured as latency", func() {
+			So(c.pinger, ShouldHaveSameTypeAs, &breakerClient{})
+			So(c.pinger.(*breakerClient).client, ShouldHaveSameTypeAs, &redis.Client{})
+		})
+	})
+
+	Convey("Given a memory threshold above 1", t, func() {
+		c, err := New(Config{Addr: "123.0.0.1", Password: testSessionID, TTL: testTTL, MemoryWarningThreshold: 1.5})
+
+		Convey("Then the client will not be created and the invalid health threshold error is returned", func() {
+			So(c, ShouldBeNil)
+			So(err, ShouldEqual, ErrInvalidHealthThreshold)
+		})
+	})
+}
+
+func TestParseMemoryStatus(t *testing.T) {
+	Convey("Given an INFO reply with an invalid used_memory", t, func() {
+		_, err := parseMemoryStatus("used_memory:lots\r\n")
+
+		Convey("Then an error is returned", func() {
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
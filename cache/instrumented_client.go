@@ -0,0 +1,108 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// CommandObserver - is notified of the duration of every command sent to redis
+type CommandObserver interface {
+	ObserveCommand(command string, duration time.Duration)
+}
+
+// instrumentedClient - a RedisClienter that reports the duration of each command to a CommandObserver. A transaction
+// is reported as a single TxPipelined command.
+type instrumentedClient struct {
+	client   RedisClienter
+	observer CommandObserver
+}
+
+// instrument - wraps client so the duration of each command is reported to observer. client is returned unchanged if
+// observer is nil.
+func instrument(client RedisClienter, observer CommandObserver) RedisClienter {
+	if observer == nil {
+		return client
+	}
+	return &instrumentedClient{client: client, observer: observer}
+}
+
+func (c *instrumentedClient) observe(command string, start time.Time) {
+	c.observer.ObserveCommand(command, time.Since(start))
+}
+
+func (c *instrumentedClient) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd {
+	defer c.observe("Set", time.Now())
+	return c.client.Set(ctx, key, value, expiration)
+}
+
+func (c *instrumentedClient) SetXX(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.BoolCmd {
+	defer c.observe("SetXX", time.Now())
+	return c.client.SetXX(ctx, key, value, expiration)
+}
+
+func (c *instrumentedClient) Get(ctx context.Context, key string) *redis.StringCmd {
+	defer c.observe("Get", time.Now())
+	return c.client.Get(ctx, key)
+}
+
+func (c *instrumentedClient) Expire(ctx context.Context, key string, expiration time.Duration) *redis.BoolCmd {
+	defer c.observe("Expire", time.Now())
+	return c.client.Expire(ctx, key, expiration)
+}
+
+func (c *instrumentedClient) Del(ctx context.Context, keys ...string) *redis.IntCmd {
+	defer c.observe("Del", time.Now())
+	return c.client.Del(ctx, keys...)
+}
+
+func (c *instrumentedClient) ZAdd(ctx context.Context, key string, members ...*redis.Z) *redis.IntCmd {
+	defer c.observe("ZAdd", time.Now())
+	return c.client.ZAdd(ctx, key, members...)
+}
+
+func (c *instrumentedClient) ZRange(ctx context.Context, key string, start, stop int64) *redis.StringSliceCmd {
+	defer c.observe("ZRange", time.Now())
+	return c.client.ZRange(ctx, key, start, stop)
+}
+
+func (c *instrumentedClient) ZRem(ctx context.Context, key string, members ...interface{}) *redis.IntCmd {
+	defer c.observe("ZRem", time.Now())
+	return c.client.ZRem(ctx, key, members...)
+}
+
+func (c *instrumentedClient) TxPipelined(ctx context.Context, fn func(redis.Pipeliner) error) ([]redis.Cmder, error) {
+	defer c.observe("TxPipelined", time.Now())
+	return c.client.TxPipelined(ctx, fn)
+}
+
+func (c *instrumentedClient) Eval(ctx context.Context, script string, keys []string, args ...interface{}) *redis.Cmd {
+	defer c.observe("Eval", time.Now())
+	return c.client.Eval(ctx, script, keys, args...)
+}
+
+func (c *instrumentedClient) EvalSha(ctx context.Context, sha1 string, keys []string, args ...interface{}) *redis.Cmd {
+	defer c.observe("EvalSha", time.Now())
+	return c.client.EvalSha(ctx, sha1, keys, args...)
+}
+
+func (c *instrumentedClient) Scan(ctx context.Context, cursor uint64, match string, count int64) *redis.ScanCmd {
+	defer c.observe("Scan", time.Now())
+	return c.client.Scan(ctx, cursor, match, count)
+}
+
+func (c *instrumentedClient) Ping(ctx context.Context) *redis.StatusCmd {
+	defer c.observe("Ping", time.Now())
+	return c.client.Ping(ctx)
+}
+
+func (c *instrumentedClient) Info(ctx context.Context, section ...string) *redis.StringCmd {
+	defer c.observe("Info", time.Now())
+	return c.client.Info(ctx, section...)
+}
+
+// Subscribe - is not observed as a subscription is held open for as long as messages are received
+func (c *instrumentedClient) Subscribe(ctx context.Context, channels ...string) *redis.PubSub {
+	return c.client.Subscribe(ctx, channels...)
+}
@@ -0,0 +1,83 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ONSdigital/dp-sessions-api/events"
+	"github.com/go-redis/redis/v8"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type observerStub struct {
+	commands []string
+}
+
+func (o *observerStub) ObserveCommand(command string, duration time.Duration) {
+	o.commands = append(o.commands, command)
+}
+
+func TestInstrumentedClient(t *testing.T) {
+	Convey("Given a client configured with a command observer", t, func() {
+		observer := &observerStub{}
+		c, err := New(Config{
+			Addr:     "123.0.0.1",
+			Password: testSessionID,
+			TTL:      testTTL,
+			Observer: observer,
+		})
+		So(err, ShouldBeNil)
+
+		Convey("Then the redis client is instrumented", func() {
+			So(c.client, ShouldHaveSameTypeAs, &instrumentedClient{})
+		})
+	})
+
+	Convey("Given an instrumented redis client", t, func() {
+		observer := &observerStub{}
+		mockRedisClient, _ := setUpMocks(nil, redis.NewStringResult(string(resp), nil), nil, redis.NewBoolResult(true, nil))
+		client := &ElasticacheClient{
+			lifetime:        lifetime{ttl: testTTL},
+			client:          &instrumentedClient{client: mockRedisClient, observer: observer},
+			keyPrefix:       DefaultKeyPrefix,
+			publisher:       events.NopPublisher{},
+			accessPublisher: events.NopPublisher{},
+			codec:           plainCodec{},
+		}
+
+		Convey("When a session is read", func() {
+			_, err := client.GetByID(testCtx, testSessionID)
+
+			Convey("Then each command sent to redis is observed", func() {
+				So(err, ShouldBeNil)
+				So(mockRedisClient.GetCalls(), ShouldHaveLength, 1)
+				So(observer.commands, ShouldResemble, []string{"Get", "EvalSha", "Expire"})
+			})
+		})
+	})
+}
+
+func TestClient_CountSessions(t *testing.T) {
+	Convey("Given there are sessions across several scan pages", t, func() {
+		mockRedisClient, client := setUpMocks(nil, nil, nil, nil)
+		mockRedisClient.ScanFunc = func(ctx context.Context, cursor uint64, match string, count int64) *redis.ScanCmd {
+			if cursor == 0 {
+				return redis.NewScanCmdResult([]string{testIDKey, "session:id:5678"}, 3, nil)
+			}
+			return redis.NewScanCmdResult([]string{"session:id:9999"}, 0, nil)
+		}
+
+		Convey("When CountSessions is called", func() {
+			count, err := client.(*ElasticacheClient).CountSessions(testCtx)
+
+			Convey("Then only session ID keys are counted", func() {
+				So(err, ShouldBeNil)
+				So(count, ShouldEqual, 3)
+				So(mockRedisClient.ScanCalls(), ShouldHaveLength, 2)
+				So(mockRedisClient.ScanCalls()[0].Match, ShouldEqual, "session:id:*")
+				So(mockRedisClient.ScanCalls()[1].Cursor, ShouldEqual, 3)
+			})
+		})
+	})
+}
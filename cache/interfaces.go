@@ -3,25 +3,57 @@ package cache
 //go:generate moq -out mock_redisclienter.go . RedisClienter
 
 import (
+	"context"
 	"time"
 
+	health "github.com/ONSdigital/dp-healthcheck/healthcheck"
 	"github.com/ONSdigital/dp-sessions-api/session"
-	"github.com/go-redis/redis"
+	"github.com/go-redis/redis/v8"
 )
 
-// SessionCache interface for storing and retrieving sessions
+// SessionCache interface for storing and retrieving sessions. Operations give up, returning the context's error, when
+// ctx is cancelled or its deadline passes.
 type SessionCache interface {
-	SetSession(s *session.Session) error
-	GetByID(ID string) (*session.Session, error)
-	GetByEmail(email string) (*session.Session, error)
-	DeleteAll() error
+	SetSession(ctx context.Context, s *session.Session) error
+	GetByID(ctx context.Context, ID string) (*session.Session, error)
+	GetByEmail(ctx context.Context, email string) (*session.Session, error)
+	Refresh(ctx context.Context, ID string) (time.Time, error)
+	UpdateAttributes(ctx context.Context, ID string, attributes map[string]interface{}) (*session.Session, error)
+	ListByEmail(ctx context.Context, email string) ([]*session.Session, error)
+	DeleteByID(ctx context.Context, ID string) error
+	Discard(ctx context.Context, ID string) error
+	DeleteByEmail(ctx context.Context, email string) error
+	RevokeByEmail(ctx context.Context, email string) (int, error)
+	DeleteAll(ctx context.Context) error
 }
 
+// Backend - a SessionCache the service can be run against, which can count its sessions and report its health
+type Backend interface {
+	SessionCache
+	CountSessions(ctx context.Context) (int, error)
+	Checker(ctx context.Context, state *health.CheckState) error
+}
+
+var (
+	_ Backend = (*ElasticacheClient)(nil)
+	_ Backend = (*InMemoryCache)(nil)
+)
+
 // RedisClienter - interface for redis
 type RedisClienter interface {
-	Set(key string, value interface{}, expiration time.Duration) *redis.StatusCmd
-	Get(key string) *redis.StringCmd
-	Expire(key string, expiration time.Duration) *redis.BoolCmd
-	FlushAll() *redis.StatusCmd
-	Ping() *redis.StatusCmd
+	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd
+	SetXX(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.BoolCmd
+	Get(ctx context.Context, key string) *redis.StringCmd
+	Expire(ctx context.Context, key string, expiration time.Duration) *redis.BoolCmd
+	Del(ctx context.Context, keys ...string) *redis.IntCmd
+	ZAdd(ctx context.Context, key string, members ...*redis.Z) *redis.IntCmd
+	ZRange(ctx context.Context, key string, start, stop int64) *redis.StringSliceCmd
+	ZRem(ctx context.Context, key string, members ...interface{}) *redis.IntCmd
+	TxPipelined(ctx context.Context, fn func(redis.Pipeliner) error) ([]redis.Cmder, error)
+	Eval(ctx context.Context, script string, keys []string, args ...interface{}) *redis.Cmd
+	EvalSha(ctx context.Context, sha1 string, keys []string, args ...interface{}) *redis.Cmd
+	Scan(ctx context.Context, cursor uint64, match string, count int64) *redis.ScanCmd
+	Ping(ctx context.Context) *redis.StatusCmd
+	Info(ctx context.Context, section ...string) *redis.StringCmd
+	Subscribe(ctx context.Context, channels ...string) *redis.PubSub
 }
@@ -0,0 +1,42 @@
+package cache
+
+import (
+	"time"
+
+	"github.com/ONSdigital/dp-sessions-api/session"
+)
+
+// lifetime - the sliding TTL and absolute max lifetime applied to sessions, shared by every SessionCache
+type lifetime struct {
+	ttl         time.Duration
+	maxLifetime time.Duration
+}
+
+// expiration - returns the TTL to apply to the session at time now. This is the sliding TTL, capped so the session is
+// never kept beyond its absolute deadline when a max lifetime is configured. A result of zero or less means the
+// session has exceeded its max lifetime.
+//
+// In redis the user's index is always given the full sliding TTL, which is never shorter than that of any session it
+// holds.
+func (l lifetime) expiration(s *session.Session, now time.Time) time.Duration {
+	if l.maxLifetime == 0 {
+		return l.ttl
+	}
+
+	remaining := s.Start.Add(l.maxLifetime).Sub(now).Truncate(time.Millisecond)
+	if remaining < l.ttl {
+		return remaining
+	}
+	return l.ttl
+}
+
+// setExpiry - sets the session's ExpiresAt, when it will expire if it is not accessed again, and Deadline, when it will
+// expire regardless of activity, from the configured TTL and max lifetime
+func (l lifetime) setExpiry(s *session.Session) {
+	s.ExpiresAt = s.LastAccessed.Add(l.expiration(s, s.LastAccessed))
+
+	s.Deadline = time.Time{}
+	if l.maxLifetime > 0 {
+		s.Deadline = s.Start.Add(l.maxLifetime)
+	}
+}
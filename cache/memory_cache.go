@@ -0,0 +1,424 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	health "github.com/ONSdigital/dp-healthcheck/healthcheck"
+	"github.com/ONSdigital/dp-sessions-api/events"
+	"github.com/ONSdigital/dp-sessions-api/session"
+	"github.com/ONSdigital/log.go/log"
+)
+
+// InMemoryHealthyMessage is the health check message reported by an InMemoryCache
+var InMemoryHealthyMessage = "in-memory cache is OK"
+
+// memoryEntry - a session stored by an InMemoryCache. The session is stored as JSON, as it is in redis, so callers
+// cannot change a stored session through the sessions returned to them.
+type memoryEntry struct {
+	email     string
+	json      []byte
+	expiresAt time.Time
+}
+
+// InMemoryCache - a SessionCache that holds sessions in memory, applying the same TTL, max lifetime and session limits
+// as the ElasticacheClient. Sessions are lost when the process exits and are not shared between instances, so it is
+// intended for local development and tests.
+//
+// Expired sessions are removed when they are next read, when a session is created and when the user's sessions are
+// listed, and an expired event is published for each.
+type InMemoryCache struct {
+	lifetime
+	maxSessions int
+	limitPolicy LimitPolicy
+	publisher   events.EventPublisher
+	accessed    events.EventPublisher
+	now         func() time.Time
+
+	mu       sync.Mutex
+	sessions map[string]*memoryEntry
+}
+
+// NewInMemory - creates an InMemoryCache with the session options in c. The redis connection options are ignored.
+func NewInMemory(c Config) (*InMemoryCache, error) {
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+
+	return &InMemoryCache{
+		lifetime:    lifetime{ttl: c.TTL, maxLifetime: c.MaxLifetime},
+		maxSessions: c.MaxSessionsPerUser,
+		limitPolicy: c.LimitPolicy,
+		publisher:   c.Publisher,
+		accessed:    c.AccessPublisher,
+		now:         time.Now,
+		sessions:    make(map[string]*memoryEntry),
+	}, nil
+}
+
+// SetSession - adds a session to the cache, evicting the user's oldest sessions or returning ErrTooManySessions if
+// they are at the session limit. A failure to publish the events for evicted sessions is only logged.
+func (c *InMemoryCache) SetSession(ctx context.Context, s *session.Session) error {
+	if s == nil {
+		return ErrEmptySession
+	}
+
+	now := c.now()
+	ttl := c.expiration(s, now)
+	if ttl <= 0 {
+		return ErrSessionExpired
+	}
+	c.setExpiry(s)
+
+	sJSON, err := s.MarshalJSON()
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	pending := c.removeExpired(now)
+
+	active, expired := c.userSessions(s.Email, now)
+	pending = append(pending, expired...)
+
+	if excess := len(active) - c.maxSessions + 1; c.maxSessions > 0 && excess > 0 {
+		if c.limitPolicy == RejectNew {
+			c.mu.Unlock()
+			c.publish(ctx, pending)
+			return ErrTooManySessions
+		}
+
+		for _, evicted := range active[:excess] {
+			delete(c.sessions, evicted.ID)
+			pending = append(pending, events.ForSession(events.Revoked, evicted))
+		}
+	}
+
+	c.sessions[s.ID] = &memoryEntry{email: s.Email, json: sJSON, expiresAt: now.Add(ttl)}
+	c.mu.Unlock()
+
+	c.publish(ctx, pending)
+	return nil
+}
+
+// GetByID - gets a session by ID, refreshing its TTL. Returns ErrSessionNotFound if the session does not exist or has
+// expired.
+func (c *InMemoryCache) GetByID(ctx context.Context, id string) (*session.Session, error) {
+	if id == "" {
+		return nil, ErrEmptySessionID
+	}
+
+	return c.access(ctx, func(now time.Time) (*session.Session, []events.Event, error) {
+		return c.get(id, now)
+	}, nil)
+}
+
+// GetByEmail - gets the most recently started session for the email address, refreshing its TTL. Returns
+// ErrSessionNotFound if the user has no active sessions.
+func (c *InMemoryCache) GetByEmail(ctx context.Context, email string) (*session.Session, error) {
+	if email == "" {
+		return nil, ErrEmptySessionEmail
+	}
+
+	return c.access(ctx, func(now time.Time) (*session.Session, []events.Event, error) {
+		return c.latest(email, now)
+	}, nil)
+}
+
+// Refresh - extends the TTL of the session with the specified ID and updates its LastAccessed time, returning the
+// time the session will now expire
+func (c *InMemoryCache) Refresh(ctx context.Context, id string) (time.Time, error) {
+	if id == "" {
+		return time.Time{}, ErrEmptySessionID
+	}
+
+	s, err := c.access(ctx, func(now time.Time) (*session.Session, []events.Event, error) {
+		return c.get(id, now)
+	}, nil)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return s.ExpiresAt, nil
+}
+
+// UpdateAttributes - merges attributes into the attributes of the session with the specified ID, as described by
+// session.MergeAttributes, refreshing its TTL
+func (c *InMemoryCache) UpdateAttributes(ctx context.Context, id string, attributes map[string]interface{}) (*session.Session, error) {
+	if id == "" {
+		return nil, ErrEmptySessionID
+	}
+
+	return c.access(ctx, func(now time.Time) (*session.Session, []events.Event, error) {
+		return c.get(id, now)
+	}, func(s *session.Session) error {
+		return s.MergeAttributes(attributes)
+	})
+}
+
+// ListByEmail - gets every active session for the email address, oldest first, without refreshing their TTLs
+func (c *InMemoryCache) ListByEmail(ctx context.Context, email string) ([]*session.Session, error) {
+	if email == "" {
+		return nil, ErrEmptySessionEmail
+	}
+
+	c.mu.Lock()
+	sessions, pending := c.userSessions(email, c.now())
+	c.mu.Unlock()
+
+	c.publish(ctx, pending)
+	return sessions, nil
+}
+
+// DeleteByID - removes the session with the specified ID. Returns ErrSessionNotFound if the session does not exist.
+func (c *InMemoryCache) DeleteByID(ctx context.Context, id string) error {
+	if id == "" {
+		return ErrEmptySessionID
+	}
+
+	return c.revoke(ctx, func(now time.Time) (*session.Session, []events.Event, error) {
+		return c.get(id, now)
+	})
+}
+
+// Discard - removes the session with the specified ID without publishing a revoked event
+func (c *InMemoryCache) Discard(ctx context.Context, id string) error {
+	if id == "" {
+		return ErrEmptySessionID
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.sessions[id]; !ok {
+		return ErrSessionNotFound
+	}
+	delete(c.sessions, id)
+	return nil
+}
+
+// DeleteByEmail - removes the most recently started session for the email address. Returns ErrSessionNotFound if the
+// user has no active sessions.
+func (c *InMemoryCache) DeleteByEmail(ctx context.Context, email string) error {
+	if email == "" {
+		return ErrEmptySessionEmail
+	}
+
+	return c.revoke(ctx, func(now time.Time) (*session.Session, []events.Event, error) {
+		return c.latest(email, now)
+	})
+}
+
+// RevokeByEmail - removes every session for the email address, returning the number of active sessions removed
+func (c *InMemoryCache) RevokeByEmail(ctx context.Context, email string) (int, error) {
+	if email == "" {
+		return 0, ErrEmptySessionEmail
+	}
+
+	c.mu.Lock()
+	sessions, pending := c.userSessions(email, c.now())
+	for _, s := range sessions {
+		delete(c.sessions, s.ID)
+		pending = append(pending, events.ForSession(events.Revoked, s))
+	}
+	c.mu.Unlock()
+
+	c.publish(ctx, pending)
+	return len(sessions), nil
+}
+
+// DeleteAll - removes every session. As with the ElasticacheClient, no revoked events are published.
+func (c *InMemoryCache) DeleteAll(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.sessions = make(map[string]*memoryEntry)
+	return nil
+}
+
+// CountSessions - returns the number of active sessions
+func (c *InMemoryCache) CountSessions(ctx context.Context) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	var count int
+	for _, e := range c.sessions {
+		if now.Before(e.expiresAt) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// Checker - reports the in-memory cache as healthy; it has no dependencies that can fail
+func (c *InMemoryCache) Checker(ctx context.Context, state *health.CheckState) error {
+	return state.Update(health.StatusOK, InMemoryHealthyMessage, 0)
+}
+
+// access - finds a session with find and refreshes it, applying update to it first if update is not nil
+func (c *InMemoryCache) access(ctx context.Context, find func(now time.Time) (*session.Session, []events.Event, error), update func(s *session.Session) error) (*session.Session, error) {
+	c.mu.Lock()
+	now := c.now()
+	s, pending, err := find(now)
+	if err == nil && update != nil {
+		err = update(s)
+	}
+	if err == nil {
+		reason := events.Accessed
+		if err = c.refresh(s, now); err == ErrSessionNotFound {
+			reason = events.Expired
+		}
+		if err == nil || err == ErrSessionNotFound {
+			pending = append(pending, events.ForSession(reason, s))
+		}
+	}
+	c.mu.Unlock()
+
+	c.publish(ctx, pending)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// revoke - finds a session with find and removes it
+func (c *InMemoryCache) revoke(ctx context.Context, find func(now time.Time) (*session.Session, []events.Event, error)) error {
+	c.mu.Lock()
+	s, pending, err := find(c.now())
+	if err == nil {
+		delete(c.sessions, s.ID)
+		pending = append(pending, events.ForSession(events.Revoked, s))
+	}
+	c.mu.Unlock()
+
+	c.publish(ctx, pending)
+	return err
+}
+
+// get - returns the session with the specified ID, removing it if it has expired. c.mu must be held.
+func (c *InMemoryCache) get(id string, now time.Time) (*session.Session, []events.Event, error) {
+	e, ok := c.sessions[id]
+	if !ok {
+		return nil, nil, ErrSessionNotFound
+	}
+
+	s, err := c.decode(e)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !now.Before(e.expiresAt) {
+		delete(c.sessions, id)
+		return nil, []events.Event{events.ForSession(events.Expired, s)}, ErrSessionNotFound
+	}
+
+	return s, nil, nil
+}
+
+// latest - returns the most recently started active session for email. c.mu must be held.
+func (c *InMemoryCache) latest(email string, now time.Time) (*session.Session, []events.Event, error) {
+	sessions, pending := c.userSessions(email, now)
+	if len(sessions) == 0 {
+		return nil, pending, ErrSessionNotFound
+	}
+	return sessions[len(sessions)-1], pending, nil
+}
+
+// userSessions - returns the active sessions for email, oldest first, removing any that have expired. c.mu must be held.
+func (c *InMemoryCache) userSessions(email string, now time.Time) ([]*session.Session, []events.Event) {
+	sessions := make([]*session.Session, 0)
+	var pending []events.Event
+	for id, e := range c.sessions {
+		if e.email != email {
+			continue
+		}
+
+		s, err := c.decode(e)
+		if err != nil {
+			continue
+		}
+
+		if !now.Before(e.expiresAt) {
+			delete(c.sessions, id)
+			pending = append(pending, events.ForSession(events.Expired, s))
+			continue
+		}
+		sessions = append(sessions, s)
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		if sessions[i].Start.Equal(sessions[j].Start) {
+			return sessions[i].ID < sessions[j].ID
+		}
+		return sessions[i].Start.Before(sessions[j].Start)
+	})
+	return sessions, pending
+}
+
+// removeExpired - removes every session that has expired. c.mu must be held.
+func (c *InMemoryCache) removeExpired(now time.Time) []events.Event {
+	var pending []events.Event
+	for id, e := range c.sessions {
+		if now.Before(e.expiresAt) {
+			continue
+		}
+
+		delete(c.sessions, id)
+		pending = append(pending, events.New(events.Expired, id, e.email))
+	}
+	return pending
+}
+
+// refresh - updates LastAccessed and stores the session with a new TTL. If the session has exceeded its max lifetime
+// it is removed and ErrSessionNotFound returned. c.mu must be held.
+func (c *InMemoryCache) refresh(s *session.Session, now time.Time) error {
+	ttl := c.expiration(s, now)
+	if ttl <= 0 {
+		delete(c.sessions, s.ID)
+		return ErrSessionNotFound
+	}
+
+	lastAccessed, err := session.FormatTime(now.UTC())
+	if err != nil {
+		return err
+	}
+	s.LastAccessed = lastAccessed
+	c.setExpiry(s)
+
+	sJSON, err := s.MarshalJSON()
+	if err != nil {
+		return err
+	}
+
+	c.sessions[s.ID] = &memoryEntry{email: s.Email, json: sJSON, expiresAt: now.Add(ttl)}
+	return nil
+}
+
+// decode - unmarshals the session stored in e
+func (c *InMemoryCache) decode(e *memoryEntry) (*session.Session, error) {
+	var s *session.Session
+	if err := json.Unmarshal(e.json, &s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// publish - publishes each of the pending events. As with the ElasticacheClient, the changes they report have already
+// been made, so a failure is logged and the remaining events are still published. Accessed events are published as
+// publishAccessed describes. c.mu must not be held so a slow publisher does not block other operations.
+func (c *InMemoryCache) publish(ctx context.Context, pending []events.Event) {
+	for _, e := range pending {
+		if e.Reason == events.Accessed {
+			publishAccessed(ctx, c.accessed, e)
+			continue
+		}
+
+		if err := c.publisher.Publish(ctx, e); err != nil {
+			log.Event(ctx, "failed to publish session event", log.ERROR, log.Error(err), log.Data{"session_id": e.SessionID, "reason": e.Reason})
+		}
+	}
+}
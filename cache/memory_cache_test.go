@@ -0,0 +1,343 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	health "github.com/ONSdigital/dp-healthcheck/healthcheck"
+	"github.com/ONSdigital/dp-sessions-api/events"
+	"github.com/ONSdigital/dp-sessions-api/session"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// clock - a time source for an InMemoryCache that only moves when it is advanced
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	return c.now
+}
+
+func (c *clock) advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func newTestInMemoryCache(cfg Config) (*InMemoryCache, *clock, *events.InMemoryPublisher) {
+	publisher := &events.InMemoryPublisher{}
+	cfg.TTL = testTTL
+	cfg.Publisher = publisher
+
+	c, err := NewInMemory(cfg)
+	So(err, ShouldBeNil)
+
+	clk := &clock{now: time.Now().UTC().Truncate(time.Millisecond)}
+	c.now = clk.Now
+	return c, clk, publisher
+}
+
+func newSessionAt(id string, start time.Time) *session.Session {
+	return &session.Session{ID: id, Email: testEmail, Start: start, LastAccessed: start}
+}
+
+func TestNewInMemory(t *testing.T) {
+	Convey("Given invalid session options", t, func() {
+		cases := map[error]Config{
+			ErrInvalidTTL:         {},
+			ErrInvalidLifetime:    {TTL: testTTL, MaxLifetime: -time.Hour},
+			ErrInvalidMaxSessions: {TTL: testTTL, MaxSessionsPerUser: -1},
+			ErrInvalidLimitPolicy: {TTL: testTTL, LimitPolicy: "ignore"},
+		}
+
+		for expected, cfg := range cases {
+			Convey("Then "+expected.Error()+" is returned", func() {
+				c, err := NewInMemory(cfg)
+				So(c, ShouldBeNil)
+				So(err, ShouldEqual, expected)
+			})
+		}
+	})
+
+	Convey("Given no redis options", t, func() {
+		c, err := NewInMemory(Config{TTL: testTTL})
+
+		Convey("Then the cache is created with the default limit policy", func() {
+			So(err, ShouldBeNil)
+			So(c.limitPolicy, ShouldEqual, EvictOldest)
+		})
+	})
+}
+
+func TestInMemoryCache(t *testing.T) {
+	Convey("Given a session in the in-memory cache", t, func() {
+		c, clk, publisher := newTestInMemoryCache(Config{})
+		So(c.SetSession(testCtx, newSessionAt(testSessionID, clk.now)), ShouldBeNil)
+
+		Convey("When it is read by ID", func() {
+			clk.advance(time.Minute)
+			s, err := c.GetByID(testCtx, testSessionID)
+
+			Convey("Then it is returned with its TTL refreshed", func() {
+				So(err, ShouldBeNil)
+				So(s.ID, ShouldEqual, testSessionID)
+				So(s.LastAccessed, ShouldEqual, clk.now)
+				So(s.ExpiresAt, ShouldEqual, clk.now.Add(testTTL))
+				So(publisher.Reasons(), ShouldResemble, []string{events.Accessed})
+			})
+		})
+
+		Convey("When it is read by email", func() {
+			s, err := c.GetByEmail(testCtx, testEmail)
+
+			Convey("Then it is returned", func() {
+				So(err, ShouldBeNil)
+				So(s.ID, ShouldEqual, testSessionID)
+			})
+		})
+
+		Convey("When it is read repeatedly within the TTL", func() {
+			for i := 0; i < 3; i++ {
+				clk.advance(testTTL - time.Minute)
+				_, err := c.GetByID(testCtx, testSessionID)
+				So(err, ShouldBeNil)
+			}
+
+			Convey("Then its TTL slides and it does not expire", func() {
+				expiresAt, err := c.Refresh(testCtx, testSessionID)
+				So(err, ShouldBeNil)
+				So(expiresAt, ShouldEqual, clk.now.Add(testTTL))
+			})
+		})
+
+		Convey("When its TTL passes without it being read", func() {
+			clk.advance(testTTL)
+
+			Convey("Then it is not found and an expired event is published", func() {
+				s, err := c.GetByID(testCtx, testSessionID)
+				So(s, ShouldBeNil)
+				So(err, ShouldEqual, ErrSessionNotFound)
+				So(publisher.Reasons(), ShouldResemble, []string{events.Expired})
+
+				count, err := c.CountSessions(testCtx)
+				So(err, ShouldBeNil)
+				So(count, ShouldEqual, 0)
+			})
+		})
+
+		Convey("When the returned session is changed by the caller", func() {
+			s, err := c.GetByID(testCtx, testSessionID)
+			So(err, ShouldBeNil)
+			s.Email = "changed@email.com"
+
+			Convey("Then the stored session is unchanged", func() {
+				stored, err := c.GetByID(testCtx, testSessionID)
+				So(err, ShouldBeNil)
+				So(stored.Email, ShouldEqual, testEmail)
+			})
+		})
+
+		Convey("When its attributes are updated", func() {
+			s, err := c.UpdateAttributes(testCtx, testSessionID, map[string]interface{}{"name": "Test User"})
+
+			Convey("Then the updated session is stored", func() {
+				So(err, ShouldBeNil)
+				So(s.Attributes["name"], ShouldEqual, "Test User")
+
+				stored, err := c.GetByID(testCtx, testSessionID)
+				So(err, ShouldBeNil)
+				So(stored.Attributes["name"], ShouldEqual, "Test User")
+			})
+		})
+
+		Convey("When it is deleted by ID", func() {
+			err := c.DeleteByID(testCtx, testSessionID)
+
+			Convey("Then it is removed and a revoked event is published", func() {
+				So(err, ShouldBeNil)
+				So(publisher.Reasons(), ShouldResemble, []string{events.Revoked})
+				So(c.DeleteByID(testCtx, testSessionID), ShouldEqual, ErrSessionNotFound)
+			})
+		})
+
+		Convey("When it is deleted and the revoked event cannot be published", func() {
+			publisher.Err = errors.New("broker unavailable")
+			err := c.DeleteByID(testCtx, testSessionID)
+
+			Convey("Then it is still removed and no error is returned", func() {
+				So(err, ShouldBeNil)
+				So(c.DeleteByID(testCtx, testSessionID), ShouldEqual, ErrSessionNotFound)
+			})
+		})
+
+		Convey("When it is read and the accessed event cannot be published", func() {
+			publisher.Err = errors.New("broker unavailable")
+			s, err := c.GetByID(testCtx, testSessionID)
+
+			Convey("Then the session is still returned", func() {
+				So(err, ShouldBeNil)
+				So(s.ID, ShouldEqual, testSessionID)
+			})
+		})
+
+		Convey("When it is discarded", func() {
+			err := c.Discard(testCtx, testSessionID)
+
+			Convey("Then it is removed without an event being published", func() {
+				So(err, ShouldBeNil)
+				So(publisher.Reasons(), ShouldBeEmpty)
+				So(c.Discard(testCtx, testSessionID), ShouldEqual, ErrSessionNotFound)
+			})
+		})
+
+		Convey("When every session is deleted", func() {
+			So(c.DeleteAll(testCtx), ShouldBeNil)
+
+			Convey("Then it is removed", func() {
+				_, err := c.GetByID(testCtx, testSessionID)
+				So(err, ShouldEqual, ErrSessionNotFound)
+			})
+		})
+
+		Convey("When the health is checked", func() {
+			state := health.NewCheckState("In-memory cache")
+			err := c.Checker(context.Background(), state)
+
+			Convey("Then it is healthy", func() {
+				So(err, ShouldBeNil)
+				So(state.Status(), ShouldEqual, health.StatusOK)
+				So(state.Message(), ShouldEqual, InMemoryHealthyMessage)
+			})
+		})
+	})
+
+	Convey("Given a user with several sessions", t, func() {
+		c, clk, publisher := newTestInMemoryCache(Config{})
+		So(c.SetSession(testCtx, newSessionAt("newest", clk.now)), ShouldBeNil)
+		So(c.SetSession(testCtx, newSessionAt("oldest", clk.now.Add(-2*time.Minute))), ShouldBeNil)
+		So(c.SetSession(testCtx, newSessionAt("middle", clk.now.Add(-time.Minute))), ShouldBeNil)
+		So(c.SetSession(testCtx, &session.Session{ID: "other", Email: "other@email.com", Start: clk.now, LastAccessed: clk.now}), ShouldBeNil)
+
+		Convey("When the user's sessions are listed", func() {
+			sessions, err := c.ListByEmail(testCtx, testEmail)
+
+			Convey("Then only the user's sessions are returned, oldest first", func() {
+				So(err, ShouldBeNil)
+				So(sessions, ShouldHaveLength, 3)
+				So(sessions[0].ID, ShouldEqual, "oldest")
+				So(sessions[1].ID, ShouldEqual, "middle")
+				So(sessions[2].ID, ShouldEqual, "newest")
+			})
+		})
+
+		Convey("When a session is read by email", func() {
+			s, err := c.GetByEmail(testCtx, testEmail)
+
+			Convey("Then the most recently started session is returned", func() {
+				So(err, ShouldBeNil)
+				So(s.ID, ShouldEqual, "newest")
+			})
+		})
+
+		Convey("When a session is deleted by email", func() {
+			So(c.DeleteByEmail(testCtx, testEmail), ShouldBeNil)
+
+			Convey("Then only the most recently started session is removed", func() {
+				sessions, err := c.ListByEmail(testCtx, testEmail)
+				So(err, ShouldBeNil)
+				So(sessions, ShouldHaveLength, 2)
+				So(sessions[1].ID, ShouldEqual, "middle")
+			})
+		})
+
+		Convey("When the user's sessions are revoked", func() {
+			revoked, err := c.RevokeByEmail(testCtx, testEmail)
+
+			Convey("Then every session for the user is removed and other users' sessions are untouched", func() {
+				So(err, ShouldBeNil)
+				So(revoked, ShouldEqual, 3)
+				So(publisher.Reasons(), ShouldResemble, []string{events.Revoked, events.Revoked, events.Revoked})
+
+				count, err := c.CountSessions(testCtx)
+				So(err, ShouldBeNil)
+				So(count, ShouldEqual, 1)
+			})
+		})
+	})
+
+	Convey("Given a max lifetime", t, func() {
+		c, clk, publisher := newTestInMemoryCache(Config{MaxLifetime: time.Hour})
+		So(c.SetSession(testCtx, newSessionAt(testSessionID, clk.now)), ShouldBeNil)
+
+		Convey("When the session is read regularly past its max lifetime", func() {
+			var err error
+			for i := 0; i < 5 && err == nil; i++ {
+				clk.advance(15 * time.Minute)
+				_, err = c.GetByID(testCtx, testSessionID)
+			}
+
+			Convey("Then it expires at its deadline despite the activity", func() {
+				So(err, ShouldEqual, ErrSessionNotFound)
+				So(publisher.Reasons(), ShouldResemble, []string{events.Accessed, events.Accessed, events.Accessed, events.Expired})
+			})
+		})
+
+		Convey("When a session that has exceeded its max lifetime is added", func() {
+			err := c.SetSession(testCtx, newSessionAt("old", clk.now.Add(-2*time.Hour)))
+
+			Convey("Then ErrSessionExpired is returned", func() {
+				So(err, ShouldEqual, ErrSessionExpired)
+			})
+		})
+	})
+
+	Convey("Given a user at the session limit", t, func() {
+		Convey("When the limit policy evicts the oldest session", func() {
+			c, clk, publisher := newTestInMemoryCache(Config{MaxSessionsPerUser: 2})
+			So(c.SetSession(testCtx, newSessionAt("oldest", clk.now.Add(-time.Minute))), ShouldBeNil)
+			So(c.SetSession(testCtx, newSessionAt("newer", clk.now)), ShouldBeNil)
+
+			err := c.SetSession(testCtx, newSessionAt("newest", clk.now))
+
+			Convey("Then the oldest session is revoked to make room", func() {
+				So(err, ShouldBeNil)
+				So(publisher.Reasons(), ShouldResemble, []string{events.Revoked})
+				So(publisher.Events()[0].SessionID, ShouldEqual, "oldest")
+
+				sessions, err := c.ListByEmail(testCtx, testEmail)
+				So(err, ShouldBeNil)
+				So(sessions, ShouldHaveLength, 2)
+			})
+		})
+
+		Convey("When the limit policy evicts the oldest session and the revoked event cannot be published", func() {
+			c, clk, publisher := newTestInMemoryCache(Config{MaxSessionsPerUser: 1})
+			So(c.SetSession(testCtx, newSessionAt("oldest", clk.now)), ShouldBeNil)
+			publisher.Err = errors.New("broker unavailable")
+
+			err := c.SetSession(testCtx, newSessionAt("newest", clk.now))
+
+			Convey("Then the new session is still added", func() {
+				So(err, ShouldBeNil)
+				sessions, err := c.ListByEmail(testCtx, testEmail)
+				So(err, ShouldBeNil)
+				So(sessions, ShouldHaveLength, 1)
+				So(sessions[0].ID, ShouldEqual, "newest")
+			})
+		})
+
+		Convey("When the limit policy rejects new sessions", func() {
+			c, clk, _ := newTestInMemoryCache(Config{MaxSessionsPerUser: 1, LimitPolicy: RejectNew})
+			So(c.SetSession(testCtx, newSessionAt(testSessionID, clk.now)), ShouldBeNil)
+
+			err := c.SetSession(testCtx, newSessionAt("new", clk.now))
+
+			Convey("Then ErrTooManySessions is returned and the new session is not stored", func() {
+				So(err, ShouldEqual, ErrTooManySessions)
+				_, err = c.GetByID(testCtx, "new")
+				So(err, ShouldEqual, ErrSessionNotFound)
+			})
+		})
+	})
+}
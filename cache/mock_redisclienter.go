@@ -4,17 +4,28 @@
 package cache
 
 import (
-	"github.com/go-redis/redis"
+	"context"
+	"github.com/go-redis/redis/v8"
 	"sync"
 	"time"
 )
 
 var (
-	lockRedisClienterMockExpire   sync.RWMutex
-	lockRedisClienterMockFlushAll sync.RWMutex
-	lockRedisClienterMockGet      sync.RWMutex
-	lockRedisClienterMockPing     sync.RWMutex
-	lockRedisClienterMockSet      sync.RWMutex
+	lockRedisClienterMockDel         sync.RWMutex
+	lockRedisClienterMockEval        sync.RWMutex
+	lockRedisClienterMockEvalSha     sync.RWMutex
+	lockRedisClienterMockExpire      sync.RWMutex
+	lockRedisClienterMockGet         sync.RWMutex
+	lockRedisClienterMockInfo        sync.RWMutex
+	lockRedisClienterMockPing        sync.RWMutex
+	lockRedisClienterMockScan        sync.RWMutex
+	lockRedisClienterMockSet         sync.RWMutex
+	lockRedisClienterMockSetXX       sync.RWMutex
+	lockRedisClienterMockSubscribe   sync.RWMutex
+	lockRedisClienterMockTxPipelined sync.RWMutex
+	lockRedisClienterMockZAdd        sync.RWMutex
+	lockRedisClienterMockZRange      sync.RWMutex
+	lockRedisClienterMockZRem        sync.RWMutex
 )
 
 // Ensure, that RedisClienterMock does implement RedisClienter.
@@ -27,21 +38,51 @@ var _ RedisClienter = &RedisClienterMock{}
 //
 //         // make and configure a mocked RedisClienter
 //         mockedRedisClienter := &RedisClienterMock{
-//             ExpireFunc: func(key string, expiration time.Duration) *redis.BoolCmd {
-// 	               panic("mock out the Expire method")
+//             DelFunc: func(ctx context.Context, keys ...string) *redis.IntCmd {
+// 	               panic("mock out the Del method")
+//             },
+//             EvalFunc: func(ctx context.Context, script string, keys []string, args ...interface{}) *redis.Cmd {
+// 	               panic("mock out the Eval method")
 //             },
-//             FlushAllFunc: func() *redis.StatusCmd {
-// 	               panic("mock out the FlushAll method")
+//             EvalShaFunc: func(ctx context.Context, sha1 string, keys []string, args ...interface{}) *redis.Cmd {
+// 	               panic("mock out the EvalSha method")
+//             },
+//             ExpireFunc: func(ctx context.Context, key string, expiration time.Duration) *redis.BoolCmd {
+// 	               panic("mock out the Expire method")
 //             },
-//             GetFunc: func(key string) *redis.StringCmd {
+//             GetFunc: func(ctx context.Context, key string) *redis.StringCmd {
 // 	               panic("mock out the Get method")
 //             },
-//             PingFunc: func() *redis.StatusCmd {
+//             InfoFunc: func(ctx context.Context, section ...string) *redis.StringCmd {
+// 	               panic("mock out the Info method")
+//             },
+//             PingFunc: func(ctx context.Context) *redis.StatusCmd {
 // 	               panic("mock out the Ping method")
 //             },
-//             SetFunc: func(key string, value interface{}, expiration time.Duration) *redis.StatusCmd {
+//             ScanFunc: func(ctx context.Context, cursor uint64, match string, count int64) *redis.ScanCmd {
+// 	               panic("mock out the Scan method")
+//             },
+//             SetFunc: func(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd {
 // 	               panic("mock out the Set method")
 //             },
+//             SetXXFunc: func(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.BoolCmd {
+// 	               panic("mock out the SetXX method")
+//             },
+//             SubscribeFunc: func(ctx context.Context, channels ...string) *redis.PubSub {
+// 	               panic("mock out the Subscribe method")
+//             },
+//             TxPipelinedFunc: func(ctx context.Context, fn func(redis.Pipeliner) error) ([]redis.Cmder, error) {
+// 	               panic("mock out the TxPipelined method")
+//             },
+//             ZAddFunc: func(ctx context.Context, key string, members ...*redis.Z) *redis.IntCmd {
+// 	               panic("mock out the ZAdd method")
+//             },
+//             ZRangeFunc: func(ctx context.Context, key string, start int64, stop int64) *redis.StringSliceCmd {
+// 	               panic("mock out the ZRange method")
+//             },
+//             ZRemFunc: func(ctx context.Context, key string, members ...interface{}) *redis.IntCmd {
+// 	               panic("mock out the ZRem method")
+//             },
 //         }
 //
 //         // use mockedRedisClienter in code that requires RedisClienter
@@ -49,43 +90,136 @@ var _ RedisClienter = &RedisClienterMock{}
 //
 //     }
 type RedisClienterMock struct {
-	// ExpireFunc mocks the Expire method.
-	ExpireFunc func(key string, expiration time.Duration) *redis.BoolCmd
+	// DelFunc mocks the Del method.
+	DelFunc func(ctx context.Context, keys ...string) *redis.IntCmd
+
+	// EvalFunc mocks the Eval method.
+	EvalFunc func(ctx context.Context, script string, keys []string, args ...interface{}) *redis.Cmd
 
-	// FlushAllFunc mocks the FlushAll method.
-	FlushAllFunc func() *redis.StatusCmd
+	// EvalShaFunc mocks the EvalSha method.
+	EvalShaFunc func(ctx context.Context, sha1 string, keys []string, args ...interface{}) *redis.Cmd
+
+	// ExpireFunc mocks the Expire method.
+	ExpireFunc func(ctx context.Context, key string, expiration time.Duration) *redis.BoolCmd
 
 	// GetFunc mocks the Get method.
-	GetFunc func(key string) *redis.StringCmd
+	GetFunc func(ctx context.Context, key string) *redis.StringCmd
+
+	// InfoFunc mocks the Info method.
+	InfoFunc func(ctx context.Context, section ...string) *redis.StringCmd
 
 	// PingFunc mocks the Ping method.
-	PingFunc func() *redis.StatusCmd
+	PingFunc func(ctx context.Context) *redis.StatusCmd
+
+	// ScanFunc mocks the Scan method.
+	ScanFunc func(ctx context.Context, cursor uint64, match string, count int64) *redis.ScanCmd
 
 	// SetFunc mocks the Set method.
-	SetFunc func(key string, value interface{}, expiration time.Duration) *redis.StatusCmd
+	SetFunc func(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd
+
+	// SetXXFunc mocks the SetXX method.
+	SetXXFunc func(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.BoolCmd
+
+	// SubscribeFunc mocks the Subscribe method.
+	SubscribeFunc func(ctx context.Context, channels ...string) *redis.PubSub
+
+	// TxPipelinedFunc mocks the TxPipelined method.
+	TxPipelinedFunc func(ctx context.Context, fn func(redis.Pipeliner) error) ([]redis.Cmder, error)
+
+	// ZAddFunc mocks the ZAdd method.
+	ZAddFunc func(ctx context.Context, key string, members ...*redis.Z) *redis.IntCmd
+
+	// ZRangeFunc mocks the ZRange method.
+	ZRangeFunc func(ctx context.Context, key string, start int64, stop int64) *redis.StringSliceCmd
+
+	// ZRemFunc mocks the ZRem method.
+	ZRemFunc func(ctx context.Context, key string, members ...interface{}) *redis.IntCmd
 
 	// calls tracks calls to the methods.
 	calls struct {
+		// Del holds details about calls to the Del method.
+		Del []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Keys is the keys argument value.
+			Keys []string
+		}
+		// Eval holds details about calls to the Eval method.
+		Eval []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Script is the script argument value.
+			Script string
+			// Keys is the keys argument value.
+			Keys []string
+			// Args is the args argument value.
+			Args []interface{}
+		}
+		// EvalSha holds details about calls to the EvalSha method.
+		EvalSha []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Sha1 is the sha1 argument value.
+			Sha1 string
+			// Keys is the keys argument value.
+			Keys []string
+			// Args is the args argument value.
+			Args []interface{}
+		}
 		// Expire holds details about calls to the Expire method.
 		Expire []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
 			// Key is the key argument value.
 			Key string
 			// Expiration is the expiration argument value.
 			Expiration time.Duration
 		}
-		// FlushAll holds details about calls to the FlushAll method.
-		FlushAll []struct {
-		}
 		// Get holds details about calls to the Get method.
 		Get []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
 			// Key is the key argument value.
 			Key string
 		}
+		// Info holds details about calls to the Info method.
+		Info []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Section is the section argument value.
+			Section []string
+		}
 		// Ping holds details about calls to the Ping method.
 		Ping []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+		}
+		// Scan holds details about calls to the Scan method.
+		Scan []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Cursor is the cursor argument value.
+			Cursor uint64
+			// Match is the match argument value.
+			Match string
+			// Count is the count argument value.
+			Count int64
 		}
 		// Set holds details about calls to the Set method.
 		Set []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Key is the key argument value.
+			Key string
+			// Value is the value argument value.
+			Value interface{}
+			// Expiration is the expiration argument value.
+			Expiration time.Duration
+		}
+		// SetXX holds details about calls to the SetXX method.
+		SetXX []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
 			// Key is the key argument value.
 			Key string
 			// Value is the value argument value.
@@ -93,35 +227,203 @@ type RedisClienterMock struct {
 			// Expiration is the expiration argument value.
 			Expiration time.Duration
 		}
+		// Subscribe holds details about calls to the Subscribe method.
+		Subscribe []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Channels is the channels argument value.
+			Channels []string
+		}
+		// TxPipelined holds details about calls to the TxPipelined method.
+		TxPipelined []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Fn is the fn argument value.
+			Fn func(redis.Pipeliner) error
+		}
+		// ZAdd holds details about calls to the ZAdd method.
+		ZAdd []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Key is the key argument value.
+			Key string
+			// Members is the members argument value.
+			Members []*redis.Z
+		}
+		// ZRange holds details about calls to the ZRange method.
+		ZRange []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Key is the key argument value.
+			Key string
+			// Start is the start argument value.
+			Start int64
+			// Stop is the stop argument value.
+			Stop int64
+		}
+		// ZRem holds details about calls to the ZRem method.
+		ZRem []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Key is the key argument value.
+			Key string
+			// Members is the members argument value.
+			Members []interface{}
+		}
+	}
+}
+
+// Del calls DelFunc.
+func (mock *RedisClienterMock) Del(ctx context.Context, keys ...string) *redis.IntCmd {
+	if mock.DelFunc == nil {
+		panic("RedisClienterMock.DelFunc: method is nil but RedisClienter.Del was just called")
+	}
+	callInfo := struct {
+		Ctx  context.Context
+		Keys []string
+	}{
+		Ctx:  ctx,
+		Keys: keys,
 	}
+	lockRedisClienterMockDel.Lock()
+	mock.calls.Del = append(mock.calls.Del, callInfo)
+	lockRedisClienterMockDel.Unlock()
+	return mock.DelFunc(ctx, keys...)
+}
+
+// DelCalls gets all the calls that were made to Del.
+// Check the length with:
+//     len(mockedRedisClienter.DelCalls())
+func (mock *RedisClienterMock) DelCalls() []struct {
+	Ctx  context.Context
+	Keys []string
+} {
+	var calls []struct {
+		Ctx  context.Context
+		Keys []string
+	}
+	lockRedisClienterMockDel.RLock()
+	calls = mock.calls.Del
+	lockRedisClienterMockDel.RUnlock()
+	return calls
+}
+
+// Eval calls EvalFunc.
+func (mock *RedisClienterMock) Eval(ctx context.Context, script string, keys []string, args ...interface{}) *redis.Cmd {
+	if mock.EvalFunc == nil {
+		panic("RedisClienterMock.EvalFunc: method is nil but RedisClienter.Eval was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Script string
+		Keys   []string
+		Args   []interface{}
+	}{
+		Ctx:    ctx,
+		Script: script,
+		Keys:   keys,
+		Args:   args,
+	}
+	lockRedisClienterMockEval.Lock()
+	mock.calls.Eval = append(mock.calls.Eval, callInfo)
+	lockRedisClienterMockEval.Unlock()
+	return mock.EvalFunc(ctx, script, keys, args...)
+}
+
+// EvalCalls gets all the calls that were made to Eval.
+// Check the length with:
+//     len(mockedRedisClienter.EvalCalls())
+func (mock *RedisClienterMock) EvalCalls() []struct {
+	Ctx    context.Context
+	Script string
+	Keys   []string
+	Args   []interface{}
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Script string
+		Keys   []string
+		Args   []interface{}
+	}
+	lockRedisClienterMockEval.RLock()
+	calls = mock.calls.Eval
+	lockRedisClienterMockEval.RUnlock()
+	return calls
+}
+
+// EvalSha calls EvalShaFunc.
+func (mock *RedisClienterMock) EvalSha(ctx context.Context, sha1 string, keys []string, args ...interface{}) *redis.Cmd {
+	if mock.EvalShaFunc == nil {
+		panic("RedisClienterMock.EvalShaFunc: method is nil but RedisClienter.EvalSha was just called")
+	}
+	callInfo := struct {
+		Ctx  context.Context
+		Sha1 string
+		Keys []string
+		Args []interface{}
+	}{
+		Ctx:  ctx,
+		Sha1: sha1,
+		Keys: keys,
+		Args: args,
+	}
+	lockRedisClienterMockEvalSha.Lock()
+	mock.calls.EvalSha = append(mock.calls.EvalSha, callInfo)
+	lockRedisClienterMockEvalSha.Unlock()
+	return mock.EvalShaFunc(ctx, sha1, keys, args...)
+}
+
+// EvalShaCalls gets all the calls that were made to EvalSha.
+// Check the length with:
+//     len(mockedRedisClienter.EvalShaCalls())
+func (mock *RedisClienterMock) EvalShaCalls() []struct {
+	Ctx  context.Context
+	Sha1 string
+	Keys []string
+	Args []interface{}
+} {
+	var calls []struct {
+		Ctx  context.Context
+		Sha1 string
+		Keys []string
+		Args []interface{}
+	}
+	lockRedisClienterMockEvalSha.RLock()
+	calls = mock.calls.EvalSha
+	lockRedisClienterMockEvalSha.RUnlock()
+	return calls
 }
 
 // Expire calls ExpireFunc.
-func (mock *RedisClienterMock) Expire(key string, expiration time.Duration) *redis.BoolCmd {
+func (mock *RedisClienterMock) Expire(ctx context.Context, key string, expiration time.Duration) *redis.BoolCmd {
 	if mock.ExpireFunc == nil {
 		panic("RedisClienterMock.ExpireFunc: method is nil but RedisClienter.Expire was just called")
 	}
 	callInfo := struct {
+		Ctx        context.Context
 		Key        string
 		Expiration time.Duration
 	}{
+		Ctx:        ctx,
 		Key:        key,
 		Expiration: expiration,
 	}
 	lockRedisClienterMockExpire.Lock()
 	mock.calls.Expire = append(mock.calls.Expire, callInfo)
 	lockRedisClienterMockExpire.Unlock()
-	return mock.ExpireFunc(key, expiration)
+	return mock.ExpireFunc(ctx, key, expiration)
 }
 
 // ExpireCalls gets all the calls that were made to Expire.
 // Check the length with:
 //     len(mockedRedisClienter.ExpireCalls())
 func (mock *RedisClienterMock) ExpireCalls() []struct {
+	Ctx        context.Context
 	Key        string
 	Expiration time.Duration
 } {
 	var calls []struct {
+		Ctx        context.Context
 		Key        string
 		Expiration time.Duration
 	}
@@ -131,55 +433,33 @@ func (mock *RedisClienterMock) ExpireCalls() []struct {
 	return calls
 }
 
-// FlushAll calls FlushAllFunc.
-func (mock *RedisClienterMock) FlushAll() *redis.StatusCmd {
-	if mock.FlushAllFunc == nil {
-		panic("RedisClienterMock.FlushAllFunc: method is nil but RedisClienter.FlushAll was just called")
-	}
-	callInfo := struct {
-	}{}
-	lockRedisClienterMockFlushAll.Lock()
-	mock.calls.FlushAll = append(mock.calls.FlushAll, callInfo)
-	lockRedisClienterMockFlushAll.Unlock()
-	return mock.FlushAllFunc()
-}
-
-// FlushAllCalls gets all the calls that were made to FlushAll.
-// Check the length with:
-//     len(mockedRedisClienter.FlushAllCalls())
-func (mock *RedisClienterMock) FlushAllCalls() []struct {
-} {
-	var calls []struct {
-	}
-	lockRedisClienterMockFlushAll.RLock()
-	calls = mock.calls.FlushAll
-	lockRedisClienterMockFlushAll.RUnlock()
-	return calls
-}
-
 // Get calls GetFunc.
-func (mock *RedisClienterMock) Get(key string) *redis.StringCmd {
+func (mock *RedisClienterMock) Get(ctx context.Context, key string) *redis.StringCmd {
 	if mock.GetFunc == nil {
 		panic("RedisClienterMock.GetFunc: method is nil but RedisClienter.Get was just called")
 	}
 	callInfo := struct {
+		Ctx context.Context
 		Key string
 	}{
+		Ctx: ctx,
 		Key: key,
 	}
 	lockRedisClienterMockGet.Lock()
 	mock.calls.Get = append(mock.calls.Get, callInfo)
 	lockRedisClienterMockGet.Unlock()
-	return mock.GetFunc(key)
+	return mock.GetFunc(ctx, key)
 }
 
 // GetCalls gets all the calls that were made to Get.
 // Check the length with:
 //     len(mockedRedisClienter.GetCalls())
 func (mock *RedisClienterMock) GetCalls() []struct {
+	Ctx context.Context
 	Key string
 } {
 	var calls []struct {
+		Ctx context.Context
 		Key string
 	}
 	lockRedisClienterMockGet.RLock()
@@ -188,25 +468,65 @@ func (mock *RedisClienterMock) GetCalls() []struct {
 	return calls
 }
 
+// Info calls InfoFunc.
+func (mock *RedisClienterMock) Info(ctx context.Context, section ...string) *redis.StringCmd {
+	if mock.InfoFunc == nil {
+		panic("RedisClienterMock.InfoFunc: method is nil but RedisClienter.Info was just called")
+	}
+	callInfo := struct {
+		Ctx     context.Context
+		Section []string
+	}{
+		Ctx:     ctx,
+		Section: section,
+	}
+	lockRedisClienterMockInfo.Lock()
+	mock.calls.Info = append(mock.calls.Info, callInfo)
+	lockRedisClienterMockInfo.Unlock()
+	return mock.InfoFunc(ctx, section...)
+}
+
+// InfoCalls gets all the calls that were made to Info.
+// Check the length with:
+//     len(mockedRedisClienter.InfoCalls())
+func (mock *RedisClienterMock) InfoCalls() []struct {
+	Ctx     context.Context
+	Section []string
+} {
+	var calls []struct {
+		Ctx     context.Context
+		Section []string
+	}
+	lockRedisClienterMockInfo.RLock()
+	calls = mock.calls.Info
+	lockRedisClienterMockInfo.RUnlock()
+	return calls
+}
+
 // Ping calls PingFunc.
-func (mock *RedisClienterMock) Ping() *redis.StatusCmd {
+func (mock *RedisClienterMock) Ping(ctx context.Context) *redis.StatusCmd {
 	if mock.PingFunc == nil {
 		panic("RedisClienterMock.PingFunc: method is nil but RedisClienter.Ping was just called")
 	}
 	callInfo := struct {
-	}{}
+		Ctx context.Context
+	}{
+		Ctx: ctx,
+	}
 	lockRedisClienterMockPing.Lock()
 	mock.calls.Ping = append(mock.calls.Ping, callInfo)
 	lockRedisClienterMockPing.Unlock()
-	return mock.PingFunc()
+	return mock.PingFunc(ctx)
 }
 
 // PingCalls gets all the calls that were made to Ping.
 // Check the length with:
 //     len(mockedRedisClienter.PingCalls())
 func (mock *RedisClienterMock) PingCalls() []struct {
+	Ctx context.Context
 } {
 	var calls []struct {
+		Ctx context.Context
 	}
 	lockRedisClienterMockPing.RLock()
 	calls = mock.calls.Ping
@@ -214,16 +534,61 @@ func (mock *RedisClienterMock) PingCalls() []struct {
 	return calls
 }
 
+// Scan calls ScanFunc.
+func (mock *RedisClienterMock) Scan(ctx context.Context, cursor uint64, match string, count int64) *redis.ScanCmd {
+	if mock.ScanFunc == nil {
+		panic("RedisClienterMock.ScanFunc: method is nil but RedisClienter.Scan was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Cursor uint64
+		Match  string
+		Count  int64
+	}{
+		Ctx:    ctx,
+		Cursor: cursor,
+		Match:  match,
+		Count:  count,
+	}
+	lockRedisClienterMockScan.Lock()
+	mock.calls.Scan = append(mock.calls.Scan, callInfo)
+	lockRedisClienterMockScan.Unlock()
+	return mock.ScanFunc(ctx, cursor, match, count)
+}
+
+// ScanCalls gets all the calls that were made to Scan.
+// Check the length with:
+//     len(mockedRedisClienter.ScanCalls())
+func (mock *RedisClienterMock) ScanCalls() []struct {
+	Ctx    context.Context
+	Cursor uint64
+	Match  string
+	Count  int64
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Cursor uint64
+		Match  string
+		Count  int64
+	}
+	lockRedisClienterMockScan.RLock()
+	calls = mock.calls.Scan
+	lockRedisClienterMockScan.RUnlock()
+	return calls
+}
+
 // Set calls SetFunc.
-func (mock *RedisClienterMock) Set(key string, value interface{}, expiration time.Duration) *redis.StatusCmd {
+func (mock *RedisClienterMock) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd {
 	if mock.SetFunc == nil {
 		panic("RedisClienterMock.SetFunc: method is nil but RedisClienter.Set was just called")
 	}
 	callInfo := struct {
+		Ctx        context.Context
 		Key        string
 		Value      interface{}
 		Expiration time.Duration
 	}{
+		Ctx:        ctx,
 		Key:        key,
 		Value:      value,
 		Expiration: expiration,
@@ -231,18 +596,20 @@ func (mock *RedisClienterMock) Set(key string, value interface{}, expiration tim
 	lockRedisClienterMockSet.Lock()
 	mock.calls.Set = append(mock.calls.Set, callInfo)
 	lockRedisClienterMockSet.Unlock()
-	return mock.SetFunc(key, value, expiration)
+	return mock.SetFunc(ctx, key, value, expiration)
 }
 
 // SetCalls gets all the calls that were made to Set.
 // Check the length with:
 //     len(mockedRedisClienter.SetCalls())
 func (mock *RedisClienterMock) SetCalls() []struct {
+	Ctx        context.Context
 	Key        string
 	Value      interface{}
 	Expiration time.Duration
 } {
 	var calls []struct {
+		Ctx        context.Context
 		Key        string
 		Value      interface{}
 		Expiration time.Duration
@@ -252,3 +619,237 @@ func (mock *RedisClienterMock) SetCalls() []struct {
 	lockRedisClienterMockSet.RUnlock()
 	return calls
 }
+
+// SetXX calls SetXXFunc.
+func (mock *RedisClienterMock) SetXX(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.BoolCmd {
+	if mock.SetXXFunc == nil {
+		panic("RedisClienterMock.SetXXFunc: method is nil but RedisClienter.SetXX was just called")
+	}
+	callInfo := struct {
+		Ctx        context.Context
+		Key        string
+		Value      interface{}
+		Expiration time.Duration
+	}{
+		Ctx:        ctx,
+		Key:        key,
+		Value:      value,
+		Expiration: expiration,
+	}
+	lockRedisClienterMockSetXX.Lock()
+	mock.calls.SetXX = append(mock.calls.SetXX, callInfo)
+	lockRedisClienterMockSetXX.Unlock()
+	return mock.SetXXFunc(ctx, key, value, expiration)
+}
+
+// SetXXCalls gets all the calls that were made to SetXX.
+// Check the length with:
+//     len(mockedRedisClienter.SetXXCalls())
+func (mock *RedisClienterMock) SetXXCalls() []struct {
+	Ctx        context.Context
+	Key        string
+	Value      interface{}
+	Expiration time.Duration
+} {
+	var calls []struct {
+		Ctx        context.Context
+		Key        string
+		Value      interface{}
+		Expiration time.Duration
+	}
+	lockRedisClienterMockSetXX.RLock()
+	calls = mock.calls.SetXX
+	lockRedisClienterMockSetXX.RUnlock()
+	return calls
+}
+
+// Subscribe calls SubscribeFunc.
+func (mock *RedisClienterMock) Subscribe(ctx context.Context, channels ...string) *redis.PubSub {
+	if mock.SubscribeFunc == nil {
+		panic("RedisClienterMock.SubscribeFunc: method is nil but RedisClienter.Subscribe was just called")
+	}
+	callInfo := struct {
+		Ctx      context.Context
+		Channels []string
+	}{
+		Ctx:      ctx,
+		Channels: channels,
+	}
+	lockRedisClienterMockSubscribe.Lock()
+	mock.calls.Subscribe = append(mock.calls.Subscribe, callInfo)
+	lockRedisClienterMockSubscribe.Unlock()
+	return mock.SubscribeFunc(ctx, channels...)
+}
+
+// SubscribeCalls gets all the calls that were made to Subscribe.
+// Check the length with:
+//     len(mockedRedisClienter.SubscribeCalls())
+func (mock *RedisClienterMock) SubscribeCalls() []struct {
+	Ctx      context.Context
+	Channels []string
+} {
+	var calls []struct {
+		Ctx      context.Context
+		Channels []string
+	}
+	lockRedisClienterMockSubscribe.RLock()
+	calls = mock.calls.Subscribe
+	lockRedisClienterMockSubscribe.RUnlock()
+	return calls
+}
+
+// TxPipelined calls TxPipelinedFunc.
+func (mock *RedisClienterMock) TxPipelined(ctx context.Context, fn func(redis.Pipeliner) error) ([]redis.Cmder, error) {
+	if mock.TxPipelinedFunc == nil {
+		panic("RedisClienterMock.TxPipelinedFunc: method is nil but RedisClienter.TxPipelined was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Fn  func(redis.Pipeliner) error
+	}{
+		Ctx: ctx,
+		Fn:  fn,
+	}
+	lockRedisClienterMockTxPipelined.Lock()
+	mock.calls.TxPipelined = append(mock.calls.TxPipelined, callInfo)
+	lockRedisClienterMockTxPipelined.Unlock()
+	return mock.TxPipelinedFunc(ctx, fn)
+}
+
+// TxPipelinedCalls gets all the calls that were made to TxPipelined.
+// Check the length with:
+//     len(mockedRedisClienter.TxPipelinedCalls())
+func (mock *RedisClienterMock) TxPipelinedCalls() []struct {
+	Ctx context.Context
+	Fn  func(redis.Pipeliner) error
+} {
+	var calls []struct {
+		Ctx context.Context
+		Fn  func(redis.Pipeliner) error
+	}
+	lockRedisClienterMockTxPipelined.RLock()
+	calls = mock.calls.TxPipelined
+	lockRedisClienterMockTxPipelined.RUnlock()
+	return calls
+}
+
+// ZAdd calls ZAddFunc.
+func (mock *RedisClienterMock) ZAdd(ctx context.Context, key string, members ...*redis.Z) *redis.IntCmd {
+	if mock.ZAddFunc == nil {
+		panic("RedisClienterMock.ZAddFunc: method is nil but RedisClienter.ZAdd was just called")
+	}
+	callInfo := struct {
+		Ctx     context.Context
+		Key     string
+		Members []*redis.Z
+	}{
+		Ctx:     ctx,
+		Key:     key,
+		Members: members,
+	}
+	lockRedisClienterMockZAdd.Lock()
+	mock.calls.ZAdd = append(mock.calls.ZAdd, callInfo)
+	lockRedisClienterMockZAdd.Unlock()
+	return mock.ZAddFunc(ctx, key, members...)
+}
+
+// ZAddCalls gets all the calls that were made to ZAdd.
+// Check the length with:
+//     len(mockedRedisClienter.ZAddCalls())
+func (mock *RedisClienterMock) ZAddCalls() []struct {
+	Ctx     context.Context
+	Key     string
+	Members []*redis.Z
+} {
+	var calls []struct {
+		Ctx     context.Context
+		Key     string
+		Members []*redis.Z
+	}
+	lockRedisClienterMockZAdd.RLock()
+	calls = mock.calls.ZAdd
+	lockRedisClienterMockZAdd.RUnlock()
+	return calls
+}
+
+// ZRange calls ZRangeFunc.
+func (mock *RedisClienterMock) ZRange(ctx context.Context, key string, start int64, stop int64) *redis.StringSliceCmd {
+	if mock.ZRangeFunc == nil {
+		panic("RedisClienterMock.ZRangeFunc: method is nil but RedisClienter.ZRange was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		Key   string
+		Start int64
+		Stop  int64
+	}{
+		Ctx:   ctx,
+		Key:   key,
+		Start: start,
+		Stop:  stop,
+	}
+	lockRedisClienterMockZRange.Lock()
+	mock.calls.ZRange = append(mock.calls.ZRange, callInfo)
+	lockRedisClienterMockZRange.Unlock()
+	return mock.ZRangeFunc(ctx, key, start, stop)
+}
+
+// ZRangeCalls gets all the calls that were made to ZRange.
+// Check the length with:
+//     len(mockedRedisClienter.ZRangeCalls())
+func (mock *RedisClienterMock) ZRangeCalls() []struct {
+	Ctx   context.Context
+	Key   string
+	Start int64
+	Stop  int64
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Key   string
+		Start int64
+		Stop  int64
+	}
+	lockRedisClienterMockZRange.RLock()
+	calls = mock.calls.ZRange
+	lockRedisClienterMockZRange.RUnlock()
+	return calls
+}
+
+// ZRem calls ZRemFunc.
+func (mock *RedisClienterMock) ZRem(ctx context.Context, key string, members ...interface{}) *redis.IntCmd {
+	if mock.ZRemFunc == nil {
+		panic("RedisClienterMock.ZRemFunc: method is nil but RedisClienter.ZRem was just called")
+	}
+	callInfo := struct {
+		Ctx     context.Context
+		Key     string
+		Members []interface{}
+	}{
+		Ctx:     ctx,
+		Key:     key,
+		Members: members,
+	}
+	lockRedisClienterMockZRem.Lock()
+	mock.calls.ZRem = append(mock.calls.ZRem, callInfo)
+	lockRedisClienterMockZRem.Unlock()
+	return mock.ZRemFunc(ctx, key, members...)
+}
+
+// ZRemCalls gets all the calls that were made to ZRem.
+// Check the length with:
+//     len(mockedRedisClienter.ZRemCalls())
+func (mock *RedisClienterMock) ZRemCalls() []struct {
+	Ctx     context.Context
+	Key     string
+	Members []interface{}
+} {
+	var calls []struct {
+		Ctx     context.Context
+		Key     string
+		Members []interface{}
+	}
+	lockRedisClienterMockZRem.RLock()
+	calls = mock.calls.ZRem
+	lockRedisClienterMockZRem.RUnlock()
+	return calls
+}
@@ -0,0 +1,139 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	// DefaultMinRetryBackoff is the wait before the first retry when Config.MinRetryBackoff is zero
+	DefaultMinRetryBackoff = 8 * time.Millisecond
+	// DefaultMaxRetryBackoff is the longest wait between retries when Config.MaxRetryBackoff is zero
+	DefaultMaxRetryBackoff = 512 * time.Millisecond
+)
+
+// failoverErrorPrefixes - the replies redis gives while a replica is being promoted or a cluster's slots are moving,
+// which succeed once the failover completes
+var failoverErrorPrefixes = []string{"LOADING ", "READONLY ", "CLUSTERDOWN ", "TRYAGAIN ", "MASTERDOWN "}
+
+// retryingClient - a RedisClienter that retries reads which fail because redis could not be reached, waiting an
+// exponentially increasing, jittered backoff between attempts. Reads are idempotent so can be safely repeated; every
+// other command is passed straight through.
+type retryingClient struct {
+	RedisClienter
+	maxRetries int
+	minBackoff time.Duration
+	maxBackoff time.Duration
+}
+
+func (c *retryingClient) Get(ctx context.Context, key string) *redis.StringCmd {
+	var cmd *redis.StringCmd
+	c.retry(ctx, func() error {
+		cmd = c.RedisClienter.Get(ctx, key)
+		return cmd.Err()
+	})
+	return cmd
+}
+
+func (c *retryingClient) ZRange(ctx context.Context, key string, start, stop int64) *redis.StringSliceCmd {
+	var cmd *redis.StringSliceCmd
+	c.retry(ctx, func() error {
+		cmd = c.RedisClienter.ZRange(ctx, key, start, stop)
+		return cmd.Err()
+	})
+	return cmd
+}
+
+func (c *retryingClient) Scan(ctx context.Context, cursor uint64, match string, count int64) *redis.ScanCmd {
+	var cmd *redis.ScanCmd
+	c.retry(ctx, func() error {
+		cmd = c.RedisClienter.Scan(ctx, cursor, match, count)
+		return cmd.Err()
+	})
+	return cmd
+}
+
+func (c *retryingClient) Ping(ctx context.Context) *redis.StatusCmd {
+	var cmd *redis.StatusCmd
+	c.retry(ctx, func() error {
+		cmd = c.RedisClienter.Ping(ctx)
+		return cmd.Err()
+	})
+	return cmd
+}
+
+func (c *retryingClient) Info(ctx context.Context, section ...string) *redis.StringCmd {
+	var cmd *redis.StringCmd
+	c.retry(ctx, func() error {
+		cmd = c.RedisClienter.Info(ctx, section...)
+		return cmd.Err()
+	})
+	return cmd
+}
+
+// retry - calls attempt until it succeeds, fails with an error that retrying will not fix, maxRetries retries have
+// been made or ctx is done
+func (c *retryingClient) retry(ctx context.Context, attempt func() error) {
+	for n := 0; ; n++ {
+		err := attempt()
+		if n == c.maxRetries || !retryable(err) {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(c.backoff(n)):
+		}
+	}
+}
+
+// backoff - returns the wait before retry n, counting from zero: a random duration of at least half the exponential
+// backoff for n, so concurrent retries spread out
+func (c *retryingClient) backoff(n int) time.Duration {
+	d := c.minBackoff << uint(n)
+	if d > c.maxBackoff || d <= 0 {
+		d = c.maxBackoff
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// failingOver - reports whether a reply from redis is one it gives while failing over
+func failingOver(err redis.Error) bool {
+	for _, prefix := range failoverErrorPrefixes {
+		if strings.HasPrefix(err.Error(), prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// retryable - reports whether err is caused by redis being unreachable or failing over, so the command may succeed if
+// it is retried. Timeouts are not retried as the operation's time has already been spent.
+func retryable(err error) bool {
+	switch {
+	case err == nil, err == redis.Nil, errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return false
+	case err == io.EOF, err == io.ErrUnexpectedEOF:
+		return true
+	}
+
+	var redisErr redis.Error
+	if errors.As(err, &redisErr) {
+		return failingOver(redisErr)
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return !netErr.Timeout()
+	}
+
+	return false
+}
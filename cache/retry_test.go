@@ -0,0 +1,145 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRetryingClient(t *testing.T) {
+	Convey("Given a retrying redis client", t, func() {
+		mockRedisClient, _ := setUpMocks(nil, nil, nil, nil)
+		client := &retryingClient{
+			RedisClienter: mockRedisClient,
+			maxRetries:    2,
+			minBackoff:    time.Millisecond,
+			maxBackoff:    2 * time.Millisecond,
+		}
+
+		Convey("When a read fails while redis is failing over and then succeeds", func() {
+			mockRedisClient.GetFunc = func(ctx context.Context, key string) *redis.StringCmd {
+				if len(mockRedisClient.GetCalls()) == 1 {
+					return redis.NewStringResult("", io.EOF)
+				}
+				return redis.NewStringResult(string(resp), nil)
+			}
+			val, err := client.Get(testCtx, testIDKey).Result()
+
+			Convey("Then the read is retried", func() {
+				So(err, ShouldBeNil)
+				So(val, ShouldEqual, string(resp))
+				So(mockRedisClient.GetCalls(), ShouldHaveLength, 2)
+			})
+		})
+
+		Convey("When a read keeps failing", func() {
+			mockRedisClient.GetFunc = func(ctx context.Context, key string) *redis.StringCmd {
+				return redis.NewStringResult("", io.EOF)
+			}
+			err := client.Get(testCtx, testIDKey).Err()
+
+			Convey("Then it is retried max retries times and the last error returned", func() {
+				So(err, ShouldEqual, io.EOF)
+				So(mockRedisClient.GetCalls(), ShouldHaveLength, 3)
+			})
+		})
+
+		Convey("When a key is missing", func() {
+			mockRedisClient.GetFunc = func(ctx context.Context, key string) *redis.StringCmd {
+				return redis.NewStringResult("", redis.Nil)
+			}
+			err := client.Get(testCtx, testIDKey).Err()
+
+			Convey("Then the read is not retried", func() {
+				So(err, ShouldEqual, redis.Nil)
+				So(mockRedisClient.GetCalls(), ShouldHaveLength, 1)
+			})
+		})
+
+		Convey("When a write fails", func() {
+			mockRedisClient.SetFunc = func(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd {
+				return redis.NewStatusResult("", io.EOF)
+			}
+			err := client.Set(testCtx, testIDKey, resp, testTTL).Err()
+
+			Convey("Then the write is not retried", func() {
+				So(err, ShouldEqual, io.EOF)
+				So(mockRedisClient.SetCalls(), ShouldHaveLength, 1)
+			})
+		})
+
+		Convey("When the caller's context is cancelled while waiting to retry", func() {
+			ctx, cancel := context.WithCancel(testCtx)
+			mockRedisClient.GetFunc = func(ctx context.Context, key string) *redis.StringCmd {
+				cancel()
+				return redis.NewStringResult("", io.EOF)
+			}
+			err := client.Get(ctx, testIDKey).Err()
+
+			Convey("Then no more retries are made", func() {
+				So(err, ShouldEqual, io.EOF)
+				So(mockRedisClient.GetCalls(), ShouldHaveLength, 1)
+			})
+		})
+	})
+
+	Convey("Given a negative max retries", t, func() {
+		c, err := New(Config{Addr: "123.0.0.1", Password: testSessionID, TTL: testTTL, MaxRetries: -1})
+
+		Convey("Then the client will not be created and the invalid retry policy error is returned", func() {
+			So(c, ShouldBeNil)
+			So(err, ShouldEqual, ErrInvalidRetryPolicy)
+		})
+	})
+}
+
+func TestRetryBackoff(t *testing.T) {
+	Convey("Given a retrying client with a backoff between 10ms and 40ms", t, func() {
+		client := &retryingClient{minBackoff: 10 * time.Millisecond, maxBackoff: 40 * time.Millisecond}
+
+		Convey("Then the backoff doubles with each retry, up to the max, and is jittered", func() {
+			for n, max := range []time.Duration{10, 20, 40, 40, 40} {
+				max *= time.Millisecond
+				backoff := client.backoff(n)
+				So(backoff, ShouldBeBetweenOrEqual, max/2, max)
+			}
+		})
+	})
+}
+
+func TestRetryable(t *testing.T) {
+	Convey("Given errors returned by redis commands", t, func() {
+		cases := map[error]bool{
+			io.EOF:    true,
+			redis.Nil: false,
+			replyErr("LOADING Redis is loading the dataset in memory"):                  true,
+			replyErr("READONLY You can't write against a read only replica."):           true,
+			replyErr("CLUSTERDOWN The cluster is down"):                                 true,
+			replyErr("WRONGTYPE Operation against a key holding the wrong kind"):        false,
+			&net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}: true,
+			&net.OpError{Op: "read", Net: "tcp", Err: timeoutErr{}}:                     false,
+			context.DeadlineExceeded:                                                    false,
+			context.Canceled:                                                            false,
+			ErrSessionNotFound:                                                          false,
+		}
+
+		for err, expected := range cases {
+			Convey(fmt.Sprintf("Then retryable(%q) is %v", err, expected), func() {
+				So(retryable(err), ShouldEqual, expected)
+			})
+		}
+	})
+}
+
+// replyErr - an error reply from the redis server
+type replyErr string
+
+func (e replyErr) Error() string { return string(e) }
+func (replyErr) RedisError()     {}
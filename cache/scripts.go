@@ -0,0 +1,105 @@
+package cache
+
+import (
+	"context"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	// startField and lastAccessedField are the fields of a session's access key, holding the times the session started
+	// and was last accessed in milliseconds since the epoch
+	startField        = "start"
+	lastAccessedField = "last_accessed"
+
+	// refreshNotFound is returned by refreshScript when the session's ID key does not exist
+	refreshNotFound = -1
+	// refreshUnknown is returned by refreshScript when the session's start time or owner are not stored and were not
+	// passed in, as the session was stored before its access key was introduced
+	refreshUnknown = -2
+
+	// admitRejected is returned by admitScript when the user is at the session limit and the policy rejects new sessions
+	admitRejected = 0
+)
+
+// refreshScript - extends the TTL of a session's keys and records when it was last accessed without reading or
+// rewriting the session itself. The TTL is capped at the session's max lifetime, measured from the start time in its
+// access key. Returns the TTL applied in milliseconds, zero if the session has exceeded its max lifetime, or
+// refreshNotFound or refreshUnknown, along with the value of its owner key.
+//
+// KEYS: ID key, access key, owner key
+// ARGV: now, TTL, max lifetime and owner key TTL in milliseconds, then the session's start time and encoded owner, which
+// may be empty if they are not known. An owner that is passed in replaces the stored one.
+var refreshScript = newScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return {-1, ''}
+end
+
+local start = redis.call('HGET', KEYS[2], 'start') or ARGV[5]
+local owner = ARGV[6]
+if owner == '' then
+	owner = redis.call('GET', KEYS[3]) or ''
+end
+if start == '' or owner == '' then
+	return {-2, ''}
+end
+
+local ttl = tonumber(ARGV[2])
+local maxLifetime = tonumber(ARGV[3])
+if maxLifetime > 0 then
+	ttl = math.min(ttl, tonumber(start) + maxLifetime - tonumber(ARGV[1]))
+end
+if ttl <= 0 then
+	return {0, owner}
+end
+
+redis.call('PEXPIRE', KEYS[1], ttl)
+redis.call('HSET', KEYS[2], 'start', start, 'last_accessed', ARGV[1])
+redis.call('PEXPIRE', KEYS[2], ttl)
+redis.call('SET', KEYS[3], owner, 'PX', ARGV[4])
+return {ttl, owner}
+`)
+
+// admitScript - adds a session to its user's index if the user is below the session limit, evicting the user's oldest
+// sessions to make room if the limit policy allows it. Counting, evicting and adding happen in a single script so that
+// sessions created concurrently for the same user cannot take the user over the limit. Returns admitRejected, or 1
+// followed by the IDs of the sessions removed from the index.
+//
+// KEYS: user key
+// ARGV: session ID, score, max sessions, 1 to evict the oldest sessions or 0 to reject, then the user key TTL in
+// milliseconds
+var admitScript = newScript(`
+local evicted = {}
+local excess = redis.call('ZCARD', KEYS[1]) - tonumber(ARGV[3]) + 1
+if excess > 0 then
+	if ARGV[4] ~= '1' then
+		return {0}
+	end
+	evicted = redis.call('ZRANGE', KEYS[1], 0, excess - 1)
+	redis.call('ZREM', KEYS[1], unpack(evicted))
+end
+
+redis.call('ZADD', KEYS[1], ARGV[2], ARGV[1])
+redis.call('PEXPIRE', KEYS[1], ARGV[5])
+return {1, unpack(evicted)}
+`)
+
+// script - a lua script that is run by its SHA1 hash once redis has cached it
+type script struct {
+	src  string
+	hash string
+}
+
+func newScript(src string) script {
+	return script{src: src, hash: redis.NewScript(src).Hash()}
+}
+
+// run - runs s with EVALSHA, falling back to EVAL, which caches the script, if redis does not have it yet
+func (s script) run(ctx context.Context, client RedisClienter, keys []string, args ...interface{}) *redis.Cmd {
+	cmd := client.EvalSha(ctx, s.hash, keys, args...)
+	if err := cmd.Err(); err != nil && strings.HasPrefix(err.Error(), "NOSCRIPT ") {
+		return client.Eval(ctx, s.src, keys, args...)
+	}
+	return cmd
+}
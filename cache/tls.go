@@ -0,0 +1,69 @@
+package cache
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+)
+
+var (
+	ErrInvalidCACert        = errors.New("CA bundle contains no PEM encoded certificates")
+	ErrIncompleteClientCert = errors.New("client certificate and key should both be provided for mutual TLS")
+)
+
+// TLSConfig - how the connection to redis is secured. The server's certificate is verified against the system's root
+// CAs unless a CA bundle is provided.
+type TLSConfig struct {
+	// CAFile is the path of a PEM encoded bundle of the CA certificates the server's certificate is verified against
+	CAFile string
+	// ServerName is the name the server's certificate is verified against. Defaults to the host of the address dialled.
+	ServerName string
+	// CertFile and KeyFile are the paths of the PEM encoded certificate and private key presented to the server for
+	// mutual TLS. Either both or neither should be provided.
+	CertFile string
+	KeyFile  string
+	// InsecureSkipVerify disables verification of the server's certificate, leaving the connection open to
+	// interception. It should only be used in development.
+	InsecureSkipVerify bool
+}
+
+// build - loads the files referenced by t and returns the tls.Config to dial redis with, or nil if t is nil
+func (t *TLSConfig) build() (*tls.Config, error) {
+	if t == nil {
+		return nil, nil
+	}
+
+	cfg := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         t.ServerName,
+		InsecureSkipVerify: t.InsecureSkipVerify,
+	}
+
+	if t.CAFile != "" {
+		pem, err := ioutil.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read redis CA bundle: %w", err)
+		}
+
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, ErrInvalidCACert
+		}
+	}
+
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		return nil, ErrIncompleteClientCert
+	}
+
+	if t.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load redis client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return cfg, nil
+}
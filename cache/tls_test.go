@@ -0,0 +1,182 @@
+package cache
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTLSConfig(t *testing.T) {
+	Convey("Given a CA, and server and client certificates it has issued", t, func() {
+		dir, err := ioutil.TempDir("", "redis-tls")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		ca, caKey := newCertificate(nil, nil, "Test CA")
+		server, serverKey := newCertificate(ca, caKey, "localhost")
+		client, clientKey := newCertificate(ca, caKey, "dp-sessions-api")
+
+		caFile := writePEM(dir, "ca.pem", "CERTIFICATE", ca.Raw)
+		certFile := writePEM(dir, "client.pem", "CERTIFICATE", client.Raw)
+		keyFile := writePEM(dir, "client-key.pem", "EC PRIVATE KEY", marshalKey(clientKey))
+
+		Convey("When no TLS config is provided", func() {
+			cfg, err := (*TLSConfig)(nil).build()
+
+			Convey("Then the connection is not secured", func() {
+				So(err, ShouldBeNil)
+				So(cfg, ShouldBeNil)
+			})
+		})
+
+		Convey("When the CA bundle and client certificate are provided", func() {
+			cfg, err := (&TLSConfig{CAFile: caFile, ServerName: "redis.internal", CertFile: certFile, KeyFile: keyFile}).build()
+
+			Convey("Then the server is verified against the CA and the client certificate is presented", func() {
+				So(err, ShouldBeNil)
+				So(cfg.RootCAs, ShouldNotBeNil)
+				So(cfg.ServerName, ShouldEqual, "redis.internal")
+				So(cfg.Certificates, ShouldHaveLength, 1)
+				So(cfg.InsecureSkipVerify, ShouldBeFalse)
+			})
+		})
+
+		Convey("When the CA bundle does not exist", func() {
+			_, err := (&TLSConfig{CAFile: filepath.Join(dir, "missing.pem")}).build()
+
+			Convey("Then an error is returned", func() {
+				So(errors.Is(err, os.ErrNotExist), ShouldBeTrue)
+			})
+		})
+
+		Convey("When the CA bundle contains no certificates", func() {
+			invalid := writePEM(dir, "invalid.pem", "EC PRIVATE KEY", marshalKey(caKey))
+			_, err := (&TLSConfig{CAFile: invalid}).build()
+
+			Convey("Then ErrInvalidCACert is returned", func() {
+				So(err, ShouldEqual, ErrInvalidCACert)
+			})
+		})
+
+		Convey("When a client certificate is provided without its key", func() {
+			_, err := (&TLSConfig{CertFile: certFile}).build()
+
+			Convey("Then ErrIncompleteClientCert is returned", func() {
+				So(err, ShouldEqual, ErrIncompleteClientCert)
+			})
+		})
+
+		Convey("When the client key does not match the certificate", func() {
+			otherKey := writePEM(dir, "other-key.pem", "EC PRIVATE KEY", marshalKey(serverKey))
+			_, err := (&TLSConfig{CertFile: certFile, KeyFile: otherKey}).build()
+
+			Convey("Then an error is returned", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+
+		Convey("When NewClient is called with a CA bundle that does not exist", func() {
+			c, err := New(Config{
+				Addr:     "123.0.0.1",
+				Password: testSessionID,
+				TTL:      testTTL,
+				TLS:      &TLSConfig{CAFile: filepath.Join(dir, "missing.pem")},
+			})
+
+			Convey("Then the client is not created", func() {
+				So(c, ShouldBeNil)
+				So(err, ShouldNotBeNil)
+			})
+		})
+
+		Convey("When redis requires mutual TLS", func() {
+			pool := x509.NewCertPool()
+			pool.AddCert(ca)
+			m, err := miniredis.RunTLS(&tls.Config{
+				Certificates: []tls.Certificate{{Certificate: [][]byte{server.Raw}, PrivateKey: serverKey}},
+				ClientCAs:    pool,
+				ClientAuth:   tls.RequireAndVerifyClientCert,
+			})
+			So(err, ShouldBeNil)
+			defer m.Close()
+			m.RequireAuth("password")
+
+			newClient := func(t *TLSConfig) *ElasticacheClient {
+				c, err := New(Config{Addr: m.Addr(), Password: "password", TTL: testTTL, TLS: t})
+				So(err, ShouldBeNil)
+				return c
+			}
+
+			Convey("Then a client that trusts the CA and presents its certificate can connect", func() {
+				c := newClient(&TLSConfig{CAFile: caFile, ServerName: "localhost", CertFile: certFile, KeyFile: keyFile})
+				So(c.Ping(testCtx), ShouldBeNil)
+			})
+
+			Convey("And a client that does not trust the CA cannot", func() {
+				c := newClient(&TLSConfig{ServerName: "localhost", CertFile: certFile, KeyFile: keyFile})
+				So(c.Ping(testCtx), ShouldNotBeNil)
+			})
+
+			Convey("And a client that does not present a certificate cannot", func() {
+				c := newClient(&TLSConfig{CAFile: caFile, ServerName: "localhost"})
+				So(c.Ping(testCtx), ShouldNotBeNil)
+			})
+		})
+	})
+}
+
+// newCertificate - creates a certificate for name issued by parent, or a self-signed CA certificate if parent is nil
+func newCertificate(parent *x509.Certificate, parentKey *ecdsa.PrivateKey, name string) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	So(err, ShouldBeNil)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{name},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		parent, parentKey = template, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	So(err, ShouldBeNil)
+
+	cert, err := x509.ParseCertificate(der)
+	So(err, ShouldBeNil)
+	return cert, key
+}
+
+func marshalKey(key *ecdsa.PrivateKey) []byte {
+	der, err := x509.MarshalECPrivateKey(key)
+	So(err, ShouldBeNil)
+	return der
+}
+
+func writePEM(dir, name, blockType string, der []byte) string {
+	path := filepath.Join(dir, name)
+	So(ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600), ShouldBeNil)
+	return path
+}
@@ -7,17 +7,57 @@ import (
 
 // Config represents service configuration for dp-sessions-api
 type Config struct {
-	BindAddr                   string        `envconfig:"BIND_ADDR"`
-	GracefulShutdownTimeout    time.Duration `envconfig:"GRACEFUL_SHUTDOWN_TIMEOUT"`
-	HealthCheckInterval        time.Duration `envconfig:"HEALTHCHECK_INTERVAL"`
-	HealthCheckCriticalTimeout time.Duration `envconfig:"HEALTHCHECK_CRITICAL_TIMEOUT"`
-	ZebedeeURL                 string        `envconfig:"ZEBEDEE_URL"`
-	ServiceAuthToken           string        `envconfig:"SERVICE_AUTH_TOKEN"				json:"-"`
-	ElasticacheAddr            string        `envconfig:"ELASTICACHE_ADDR"`
-	ElasticachePassword        string        `envconfig:"ELASTICACHE_PASSWORD"				json:"-"`
-	ElasticacheDatabase        int           `envconfig:"ELASTICACHE_DATABASE"`
-	ElasticacheTTL             time.Duration `envconfig:"ELASTICACHE_TTL"`
-	EnableRedisTLSConfig       bool          `envconfig:"ENABLE_REDIS_TLS_CONFIG"`
+	BindAddr                        string            `envconfig:"BIND_ADDR"`
+	GracefulShutdownTimeout         time.Duration     `envconfig:"GRACEFUL_SHUTDOWN_TIMEOUT"`
+	HealthCheckInterval             time.Duration     `envconfig:"HEALTHCHECK_INTERVAL"`
+	HealthCheckCriticalTimeout      time.Duration     `envconfig:"HEALTHCHECK_CRITICAL_TIMEOUT"`
+	ActiveSessionsCountInterval     time.Duration     `envconfig:"ACTIVE_SESSIONS_COUNT_INTERVAL"`
+	ZebedeeURL                      string            `envconfig:"ZEBEDEE_URL"`
+	ServiceAuthToken                string            `envconfig:"SERVICE_AUTH_TOKEN"				json:"-"`
+	ElasticacheMode                 string            `envconfig:"ELASTICACHE_MODE"`
+	ElasticacheAddr                 string            `envconfig:"ELASTICACHE_ADDR"`
+	ElasticacheSeedAddrs            []string          `envconfig:"ELASTICACHE_SEED_ADDRS"`
+	ElasticacheMasterName           string            `envconfig:"ELASTICACHE_MASTER_NAME"`
+	ElasticachePassword             string            `envconfig:"ELASTICACHE_PASSWORD"				json:"-"`
+	ElasticacheDatabase             int               `envconfig:"ELASTICACHE_DATABASE"`
+	ElasticacheTTL                  time.Duration     `envconfig:"ELASTICACHE_TTL"`
+	ElasticacheTimeout              time.Duration     `envconfig:"ELASTICACHE_TIMEOUT"`
+	ElasticacheDialTimeout          time.Duration     `envconfig:"ELASTICACHE_DIAL_TIMEOUT"`
+	ElasticacheReadTimeout          time.Duration     `envconfig:"ELASTICACHE_READ_TIMEOUT"`
+	ElasticacheWriteTimeout         time.Duration     `envconfig:"ELASTICACHE_WRITE_TIMEOUT"`
+	ElasticachePoolSize             int               `envconfig:"ELASTICACHE_POOL_SIZE"`
+	ElasticacheMinIdleConns         int               `envconfig:"ELASTICACHE_MIN_IDLE_CONNS"`
+	ElasticacheMaxConnAge           time.Duration     `envconfig:"ELASTICACHE_MAX_CONN_AGE"`
+	ElasticachePoolTimeout          time.Duration     `envconfig:"ELASTICACHE_POOL_TIMEOUT"`
+	ElasticacheMaxRetries           int               `envconfig:"ELASTICACHE_MAX_RETRIES"`
+	ElasticacheLatencyWarning       time.Duration     `envconfig:"ELASTICACHE_LATENCY_WARNING_THRESHOLD"`
+	ElasticacheMemoryWarning        float64           `envconfig:"ELASTICACHE_MEMORY_WARNING_THRESHOLD"`
+	ElasticacheMinRetryBackoff      time.Duration     `envconfig:"ELASTICACHE_MIN_RETRY_BACKOFF"`
+	ElasticacheMaxRetryBackoff      time.Duration     `envconfig:"ELASTICACHE_MAX_RETRY_BACKOFF"`
+	BreakerFailureThreshold         int               `envconfig:"ELASTICACHE_BREAKER_FAILURE_THRESHOLD"`
+	BreakerOpenTimeout              time.Duration     `envconfig:"ELASTICACHE_BREAKER_OPEN_TIMEOUT"`
+	EnableRedisTLSConfig            bool              `envconfig:"ENABLE_REDIS_TLS_CONFIG"`
+	RedisTLSCAFile                  string            `envconfig:"REDIS_TLS_CA_FILE"`
+	RedisTLSServerName              string            `envconfig:"REDIS_TLS_SERVER_NAME"`
+	RedisTLSCertFile                string            `envconfig:"REDIS_TLS_CERT_FILE"`
+	RedisTLSKeyFile                 string            `envconfig:"REDIS_TLS_KEY_FILE"`
+	RedisTLSInsecureSkipVerify      bool              `envconfig:"REDIS_TLS_INSECURE_SKIP_VERIFY"`
+	ElasticacheKeyPrefix            string            `envconfig:"ELASTICACHE_KEY_PREFIX"`
+	SessionMaxLifetime              time.Duration     `envconfig:"SESSION_MAX_LIFETIME"`
+	SessionIDFormat                 string            `envconfig:"SESSION_ID_FORMAT"`
+	SessionEncryptionKeys           map[string]string `envconfig:"SESSION_ENCRYPTION_KEYS" json:"-"`
+	SessionEncryptionKeyID          string            `envconfig:"SESSION_ENCRYPTION_KEY_ID"`
+	SessionIndexKey                 string            `envconfig:"SESSION_INDEX_KEY" json:"-"`
+	SessionEncryptionAllowPlaintext bool              `envconfig:"SESSION_ENCRYPTION_ALLOW_PLAINTEXT"`
+	MaxSessionsPerUser              int               `envconfig:"MAX_SESSIONS_PER_USER"`
+	SessionLimitPolicy              string            `envconfig:"SESSION_LIMIT_POLICY"`
+	ReadAllowedCallers              []string          `envconfig:"READ_ALLOWED_CALLERS"`
+	SessionEventsEnabled            bool              `envconfig:"SESSION_EVENTS_ENABLED"`
+	SessionEventsTopic              string            `envconfig:"SESSION_EVENTS_TOPIC"`
+	SessionEventsFailurePolicy      string            `envconfig:"SESSION_EVENTS_FAILURE_POLICY"`
+	KafkaAddr                       []string          `envconfig:"KAFKA_ADDR"`
+	KafkaSecProtocol                string            `envconfig:"KAFKA_SEC_PROTO"`
+	CacheBackend                    string            `envconfig:"CACHE_BACKEND"`
 }
 
 var cfg *Config
@@ -30,17 +70,46 @@ func Get() (*Config, error) {
 	}
 
 	cfg := &Config{
-		BindAddr:                   ":24400",
-		GracefulShutdownTimeout:    5 * time.Second,
-		HealthCheckInterval:        30 * time.Second,
-		HealthCheckCriticalTimeout: 90 * time.Second,
-		ZebedeeURL:                 "http://localhost:8082",
-		ServiceAuthToken:           "",
-		ElasticacheAddr:            "localhost:6379",
-		ElasticachePassword:        "default",
-		ElasticacheDatabase:        0,
-		ElasticacheTTL:             30 * time.Minute,
-		EnableRedisTLSConfig:       false,
+		BindAddr:                        ":24400",
+		GracefulShutdownTimeout:         5 * time.Second,
+		HealthCheckInterval:             30 * time.Second,
+		HealthCheckCriticalTimeout:      90 * time.Second,
+		ActiveSessionsCountInterval:     time.Minute,
+		ZebedeeURL:                      "http://localhost:8082",
+		ServiceAuthToken:                "",
+		ElasticacheMode:                 "standalone",
+		ElasticacheAddr:                 "localhost:6379",
+		ElasticachePassword:             "default",
+		ElasticacheDatabase:             0,
+		ElasticacheTTL:                  30 * time.Minute,
+		ElasticacheTimeout:              2 * time.Second,
+		ElasticacheDialTimeout:          5 * time.Second,
+		ElasticacheReadTimeout:          time.Second,
+		ElasticacheWriteTimeout:         time.Second,
+		ElasticachePoolSize:             0,
+		ElasticacheMinIdleConns:         0,
+		ElasticacheMaxConnAge:           0,
+		ElasticachePoolTimeout:          0,
+		ElasticacheMaxRetries:           2,
+		ElasticacheLatencyWarning:       100 * time.Millisecond,
+		ElasticacheMemoryWarning:        0.9,
+		ElasticacheMinRetryBackoff:      50 * time.Millisecond,
+		ElasticacheMaxRetryBackoff:      500 * time.Millisecond,
+		BreakerFailureThreshold:         5,
+		BreakerOpenTimeout:              10 * time.Second,
+		EnableRedisTLSConfig:            false,
+		RedisTLSInsecureSkipVerify:      false,
+		ElasticacheKeyPrefix:            "session:",
+		SessionMaxLifetime:              12 * time.Hour,
+		SessionIDFormat:                 "random",
+		SessionEncryptionAllowPlaintext: false,
+		MaxSessionsPerUser:              0,
+		SessionLimitPolicy:              "evict",
+		SessionEventsEnabled:            false,
+		SessionEventsTopic:              "session-events",
+		SessionEventsFailurePolicy:      "open",
+		KafkaAddr:                       []string{"localhost:9092"},
+		CacheBackend:                    "redis",
 	}
 
 	return cfg, envconfig.Process("", cfg)
@@ -20,6 +20,41 @@ func TestConfig(t *testing.T) {
 			Convey("Then the values should be set to the expected defaults", func() {
 				So(cfg.GracefulShutdownTimeout, ShouldEqual, 5*time.Second)
 				So(cfg.HealthCheckCriticalTimeout, ShouldEqual, 90*time.Second)
+				So(cfg.ActiveSessionsCountInterval, ShouldEqual, time.Minute)
+				So(cfg.SessionMaxLifetime, ShouldEqual, 12*time.Hour)
+				So(cfg.SessionIDFormat, ShouldEqual, "random")
+				So(cfg.SessionEncryptionKeys, ShouldBeEmpty)
+				So(cfg.SessionEncryptionKeyID, ShouldBeEmpty)
+				So(cfg.SessionIndexKey, ShouldBeEmpty)
+				So(cfg.SessionEncryptionAllowPlaintext, ShouldBeFalse)
+				So(cfg.MaxSessionsPerUser, ShouldEqual, 0)
+				So(cfg.SessionLimitPolicy, ShouldEqual, "evict")
+				So(cfg.ReadAllowedCallers, ShouldBeEmpty)
+				So(cfg.SessionEventsEnabled, ShouldBeFalse)
+				So(cfg.SessionEventsTopic, ShouldEqual, "session-events")
+				So(cfg.SessionEventsFailurePolicy, ShouldEqual, "open")
+				So(cfg.KafkaAddr, ShouldResemble, []string{"localhost:9092"})
+				So(cfg.CacheBackend, ShouldEqual, "redis")
+				So(cfg.ElasticacheMode, ShouldEqual, "standalone")
+				So(cfg.ElasticacheSeedAddrs, ShouldBeEmpty)
+				So(cfg.ElasticacheMasterName, ShouldBeEmpty)
+				So(cfg.EnableRedisTLSConfig, ShouldBeFalse)
+				So(cfg.RedisTLSInsecureSkipVerify, ShouldBeFalse)
+				So(cfg.ElasticacheTimeout, ShouldEqual, 2*time.Second)
+				So(cfg.ElasticacheDialTimeout, ShouldEqual, 5*time.Second)
+				So(cfg.ElasticacheReadTimeout, ShouldEqual, time.Second)
+				So(cfg.ElasticacheWriteTimeout, ShouldEqual, time.Second)
+				So(cfg.ElasticachePoolSize, ShouldEqual, 0)
+				So(cfg.ElasticacheMinIdleConns, ShouldEqual, 0)
+				So(cfg.ElasticacheMaxConnAge, ShouldEqual, 0)
+				So(cfg.ElasticachePoolTimeout, ShouldEqual, 0)
+				So(cfg.ElasticacheMaxRetries, ShouldEqual, 2)
+				So(cfg.ElasticacheLatencyWarning, ShouldEqual, 100*time.Millisecond)
+				So(cfg.ElasticacheMemoryWarning, ShouldEqual, 0.9)
+				So(cfg.ElasticacheMinRetryBackoff, ShouldEqual, 50*time.Millisecond)
+				So(cfg.ElasticacheMaxRetryBackoff, ShouldEqual, 500*time.Millisecond)
+				So(cfg.BreakerFailureThreshold, ShouldEqual, 5)
+				So(cfg.BreakerOpenTimeout, ShouldEqual, 10*time.Second)
 			})
 
 			Convey("Then a second call to config should return the same config", func() {
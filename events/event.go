@@ -0,0 +1,65 @@
+package events
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/ONSdigital/dp-sessions-api/session"
+)
+
+// Reasons an event is published for
+const (
+	Created  = "created"
+	Accessed = "accessed"
+	Expired  = "expired"
+	Revoked  = "revoked"
+)
+
+// Event - a change in the lifecycle of a session. The email is hashed so consumers can correlate a user's sessions
+// without reading the address, but the hash is unsalted so a known address can be matched to its events: events still
+// carry personal data and should be handled as such.
+type Event struct {
+	SessionID string `avro:"session_id" json:"session_id"`
+	EmailHash string `avro:"email_hash" json:"email_hash"`
+	Timestamp string `avro:"timestamp" json:"timestamp"`
+	Reason    string `avro:"reason" json:"reason"`
+}
+
+// EventPublisher - publishes session lifecycle events
+type EventPublisher interface {
+	Publish(ctx context.Context, e Event) error
+}
+
+// New - creates an event for the session with the ID and email provided, timestamped with the current time
+func New(reason, ID, email string) Event {
+	return Event{
+		SessionID: ID,
+		EmailHash: HashEmail(email),
+		Timestamp: time.Now().UTC().Format(session.DateTimeFMT),
+		Reason:    reason,
+	}
+}
+
+// ForSession - creates an event for s, timestamped with the current time
+func ForSession(reason string, s *session.Session) Event {
+	return New(reason, s.ID, s.Email)
+}
+
+// HashEmail - returns the hex encoded SHA-256 hash of an email address. Email addresses are case insensitive so the
+// address is lower cased first, giving every spelling of an address the same hash. The hash is not keyed, so anyone
+// with a list of addresses can recover the address an event is for; it is a pseudonym, not an anonymisation.
+func HashEmail(email string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(email))))
+	return hex.EncodeToString(sum[:])
+}
+
+// NopPublisher - an EventPublisher that discards every event, used when event publishing is disabled
+type NopPublisher struct{}
+
+// Publish - discards e
+func (NopPublisher) Publish(ctx context.Context, e Event) error {
+	return nil
+}
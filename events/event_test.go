@@ -0,0 +1,66 @@
+package events
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ONSdigital/dp-sessions-api/session"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestNew(t *testing.T) {
+	Convey("Given a session", t, func() {
+		s := &session.Session{ID: "1234", Email: "user@email.com"}
+
+		Convey("When an event is created for the session", func() {
+			before := time.Now().UTC().Truncate(time.Millisecond)
+			e := ForSession(Created, s)
+
+			Convey("Then the event carries the session ID, hashed email, reason and current time", func() {
+				So(e.SessionID, ShouldEqual, "1234")
+				So(e.EmailHash, ShouldEqual, HashEmail("user@email.com"))
+				So(e.EmailHash, ShouldNotContainSubstring, "user")
+				So(e.Reason, ShouldEqual, Created)
+
+				timestamp, err := time.Parse(session.DateTimeFMT, e.Timestamp)
+				So(err, ShouldBeNil)
+				So(timestamp, ShouldHappenOnOrAfter, before)
+			})
+		})
+	})
+}
+
+func TestHashEmail(t *testing.T) {
+	Convey("Given an email address", t, func() {
+		hash := HashEmail("user@email.com")
+
+		Convey("Then it is hashed with SHA-256", func() {
+			So(hash, ShouldEqual, "0925f997eb0d742678f66d2da134d15d842d57722af5f7605c4785cb5358831b")
+		})
+
+		Convey("Then the same address in a different case has the same hash", func() {
+			So(HashEmail(" User@Email.com"), ShouldEqual, hash)
+		})
+
+		Convey("Then a different address has a different hash", func() {
+			So(HashEmail("other@email.com"), ShouldNotEqual, hash)
+		})
+	})
+}
+
+func TestInMemoryPublisher(t *testing.T) {
+	Convey("Given an in memory publisher", t, func() {
+		p := &InMemoryPublisher{}
+
+		Convey("When events are published", func() {
+			So(p.Publish(context.Background(), New(Created, "1", "user@email.com")), ShouldBeNil)
+			So(p.Publish(context.Background(), New(Revoked, "1", "user@email.com")), ShouldBeNil)
+
+			Convey("Then they are kept in order", func() {
+				So(p.Events(), ShouldHaveLength, 2)
+				So(p.Reasons(), ShouldResemble, []string{Created, Revoked})
+			})
+		})
+	})
+}
@@ -0,0 +1,83 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ONSdigital/log.go/log"
+)
+
+var (
+	ErrPublishFailed        = errors.New("failed to publish session event")
+	ErrInvalidFailurePolicy = errors.New("event failure policy should be open or closed")
+)
+
+// FailurePolicy - determines what happens to the operation that triggered an event when the event cannot be published
+type FailurePolicy string
+
+const (
+	// FailOpen logs the failure and lets the operation succeed
+	FailOpen FailurePolicy = "open"
+	// FailClosed fails the operation
+	FailClosed FailurePolicy = "closed"
+)
+
+// failurePolicyPublisher - applies a FailurePolicy to the errors returned by the EventPublisher it wraps
+type failurePolicyPublisher struct {
+	publisher EventPublisher
+	policy    FailurePolicy
+}
+
+// WithFailurePolicy - wraps publisher so a failure to publish is logged and, when policy is FailClosed, returned as an
+// error wrapping ErrPublishFailed. With FailOpen a failure to publish is never returned.
+func WithFailurePolicy(publisher EventPublisher, policy FailurePolicy) (EventPublisher, error) {
+	switch policy {
+	case FailOpen, FailClosed:
+	default:
+		return nil, ErrInvalidFailurePolicy
+	}
+
+	return &failurePolicyPublisher{publisher: publisher, policy: policy}, nil
+}
+
+// Publish - publishes e, applying the failure policy to any error
+func (p *failurePolicyPublisher) Publish(ctx context.Context, e Event) error {
+	err := p.publisher.Publish(ctx, e)
+	if err == nil {
+		return nil
+	}
+
+	logData := log.Data{"session_id": e.SessionID, "reason": e.Reason, "failure_policy": p.policy}
+	if p.policy == FailOpen {
+		log.Event(ctx, "failed to publish session event, continuing", log.WARN, log.Error(err), logData)
+		return nil
+	}
+
+	log.Event(ctx, "failed to publish session event, failing operation", log.ERROR, log.Error(err), logData)
+	return fmt.Errorf("%w: %v", ErrPublishFailed, err)
+}
+
+// AsyncPublisher - an EventPublisher that can also publish events without waiting for them to be sent
+type AsyncPublisher interface {
+	EventPublisher
+	PublishAsync(ctx context.Context, e Event)
+}
+
+// bestEffortPublisher - publishes events through an AsyncPublisher without waiting for them
+type bestEffortPublisher struct {
+	publisher AsyncPublisher
+}
+
+// BestEffort - wraps publisher so events are handed to it without waiting for them to be sent. A failure to publish is
+// only logged by publisher, so Publish never returns one and no failure policy applies. It is used for events, such
+// as accessed events, published on every read, which must neither slow reads down nor fail them.
+func BestEffort(publisher AsyncPublisher) EventPublisher {
+	return &bestEffortPublisher{publisher: publisher}
+}
+
+// Publish - hands e to the publisher without waiting for it to be sent
+func (p *bestEffortPublisher) Publish(ctx context.Context, e Event) error {
+	p.publisher.PublishAsync(ctx, e)
+	return nil
+}
@@ -0,0 +1,59 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestWithFailurePolicy(t *testing.T) {
+	Convey("Given a publisher that fails", t, func() {
+		publisher := &InMemoryPublisher{Err: errors.New("broker unavailable")}
+		e := New(Created, "1234", "user@email.com")
+
+		Convey("When it fails open", func() {
+			p, err := WithFailurePolicy(publisher, FailOpen)
+			So(err, ShouldBeNil)
+
+			Convey("Then the failure is not returned", func() {
+				So(p.Publish(context.Background(), e), ShouldBeNil)
+			})
+		})
+
+		Convey("When it fails closed", func() {
+			p, err := WithFailurePolicy(publisher, FailClosed)
+			So(err, ShouldBeNil)
+
+			Convey("Then the failure is returned as ErrPublishFailed", func() {
+				err = p.Publish(context.Background(), e)
+				So(errors.Is(err, ErrPublishFailed), ShouldBeTrue)
+				So(err.Error(), ShouldContainSubstring, "broker unavailable")
+			})
+		})
+	})
+
+	Convey("Given a publisher that succeeds", t, func() {
+		publisher := &InMemoryPublisher{}
+
+		Convey("When it fails closed", func() {
+			p, err := WithFailurePolicy(publisher, FailClosed)
+			So(err, ShouldBeNil)
+
+			Convey("Then events are published", func() {
+				So(p.Publish(context.Background(), New(Created, "1234", "user@email.com")), ShouldBeNil)
+				So(publisher.Events(), ShouldHaveLength, 1)
+			})
+		})
+	})
+
+	Convey("Given an unknown failure policy", t, func() {
+		p, err := WithFailurePolicy(&InMemoryPublisher{}, "ignore")
+
+		Convey("Then ErrInvalidFailurePolicy is returned", func() {
+			So(p, ShouldBeNil)
+			So(err, ShouldEqual, ErrInvalidFailurePolicy)
+		})
+	})
+}
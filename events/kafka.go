@@ -0,0 +1,182 @@
+package events
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"sync"
+
+	"github.com/ONSdigital/log.go/log"
+	"github.com/Shopify/sarama"
+)
+
+var (
+	// ErrPublisherClosed is returned when an event is published after the KafkaPublisher has been closed
+	ErrPublisherClosed = errors.New("kafka publisher is closed")
+	// ErrProducerBusy is returned when an event published without waiting is dropped because the producer's input is full
+	ErrProducerBusy = errors.New("kafka producer input is full, event dropped")
+)
+
+type avroMarshaller func(s interface{}) ([]byte, error)
+
+// KafkaPublisher - publishes avro encoded events to a kafka topic through an asynchronous producer. Messages are keyed
+// by session ID so a session's events are consumed in order. Publish waits for an event to be acknowledged, so a
+// failure to publish is reported to the caller, while PublishAsync hands the event to the producer and returns, dropping
+// it if the producer cannot accept it straight away.
+type KafkaPublisher struct {
+	producer      sarama.AsyncProducer
+	topic         string
+	marshalToAvro avroMarshaller
+	mu            sync.RWMutex
+	closed        bool
+	done          chan struct{}
+}
+
+// NewKafkaPublisher - creates a KafkaPublisher sending events to topic on the brokers provided. tlsConfig may be nil
+// for brokers that do not use TLS.
+func NewKafkaPublisher(brokers []string, topic string, tlsConfig *tls.Config) (*KafkaPublisher, error) {
+	cfg := sarama.NewConfig()
+	cfg.Producer.RequiredAcks = sarama.WaitForAll
+	cfg.Producer.Return.Successes = true
+	cfg.Producer.Return.Errors = true
+	if tlsConfig != nil {
+		cfg.Net.TLS.Enable = true
+		cfg.Net.TLS.Config = tlsConfig
+	}
+
+	producer, err := sarama.NewAsyncProducer(brokers, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewKafkaPublisherWithProducer(producer, topic), nil
+}
+
+// NewKafkaPublisherWithProducer - creates a KafkaPublisher sending events to topic through producer, which must return
+// both successes and errors
+func NewKafkaPublisherWithProducer(producer sarama.AsyncProducer, topic string) *KafkaPublisher {
+	p := &KafkaPublisher{
+		producer:      producer,
+		topic:         topic,
+		marshalToAvro: SessionEventSchema.Marshal,
+		done:          make(chan struct{}),
+	}
+	go p.dispatch()
+	return p
+}
+
+// Publish - sends e to the topic, returning an error if it could not be encoded or was not acknowledged
+func (p *KafkaPublisher) Publish(ctx context.Context, e Event) error {
+	result := make(chan error, 1)
+	if err := p.send(ctx, e, result); err != nil {
+		return err
+	}
+
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// PublishAsync - hands e to the producer without waiting for it to be sent. It never blocks: if the producer's input is
+// full, for example because kafka is unreachable, e is dropped. A failure to send it is logged.
+func (p *KafkaPublisher) PublishAsync(ctx context.Context, e Event) {
+	if err := p.send(ctx, e, nil); err != nil {
+		log.Event(ctx, "failed to publish session event", log.ERROR, log.Error(err), log.Data{"session_id": e.SessionID, "reason": e.Reason})
+	}
+}
+
+// send - encodes e and hands it to the producer. If result is not nil the outcome of sending it is written to result and
+// send waits for the producer to accept e until ctx is done, otherwise e is dropped if the producer cannot accept it.
+func (p *KafkaPublisher) send(ctx context.Context, e Event, result chan error) error {
+	avroBytes, err := p.marshalToAvro(e)
+	if err != nil {
+		return err
+	}
+
+	msg := &sarama.ProducerMessage{
+		Topic: p.topic,
+		Key:   sarama.StringEncoder(e.SessionID),
+		Value: sarama.ByteEncoder(avroBytes),
+	}
+	if result != nil {
+		msg.Metadata = result
+	}
+
+	// the producer's input is closed along with the producer, so it must not be closed while a message is being sent
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		return ErrPublisherClosed
+	}
+
+	if result == nil {
+		select {
+		case p.producer.Input() <- msg:
+			return nil
+		default:
+			return ErrProducerBusy
+		}
+	}
+
+	select {
+	case p.producer.Input() <- msg:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// dispatch - reports the outcome of each message sent to the caller waiting for it, or logs the failure of a message
+// nobody is waiting for, until the producer is closed
+func (p *KafkaPublisher) dispatch() {
+	defer close(p.done)
+
+	successes, errs := p.producer.Successes(), p.producer.Errors()
+	for successes != nil || errs != nil {
+		select {
+		case msg, ok := <-successes:
+			if !ok {
+				successes = nil
+				continue
+			}
+			p.report(msg, nil)
+		case perr, ok := <-errs:
+			if !ok {
+				errs = nil
+				continue
+			}
+			p.report(perr.Msg, perr.Err)
+		}
+	}
+}
+
+// report - writes err to the result channel of msg, if it has one, otherwise logs err
+func (p *KafkaPublisher) report(msg *sarama.ProducerMessage, err error) {
+	if result, ok := msg.Metadata.(chan error); ok {
+		result <- err
+		return
+	}
+
+	if err != nil {
+		log.Event(context.Background(), "failed to publish session event", log.ERROR, log.Error(err))
+	}
+}
+
+// Close - stops accepting events and closes the producer, waiting for any messages in flight
+func (p *KafkaPublisher) Close(ctx context.Context) error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	p.mu.Unlock()
+
+	err := p.producer.Close()
+	<-p.done
+	return err
+}
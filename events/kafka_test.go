@@ -0,0 +1,156 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/Shopify/sarama/mocks"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestKafkaPublisher(t *testing.T) {
+	Convey("Given a kafka publisher", t, func() {
+		cfg := sarama.NewConfig()
+		cfg.Producer.Return.Successes = true
+		producer := mocks.NewAsyncProducer(t, cfg)
+		p := NewKafkaPublisherWithProducer(producer, "session-events")
+		e := New(Created, "1234", "user@email.com")
+
+		Convey("When an event is published", func() {
+			producer.ExpectInputWithCheckerFunctionAndSucceed(func(val []byte) error {
+				var decoded Event
+				if err := SessionEventSchema.Unmarshal(val, &decoded); err != nil {
+					return err
+				}
+				if decoded != e {
+					return errors.New("decoded event does not match the event published")
+				}
+				return nil
+			})
+			err := p.Publish(context.Background(), e)
+
+			Convey("Then it is sent avro encoded to the topic", func() {
+				So(err, ShouldBeNil)
+				So(p.Close(context.Background()), ShouldBeNil)
+			})
+		})
+
+		Convey("When the broker does not acknowledge the event", func() {
+			producer.ExpectInputAndFail(sarama.ErrNotEnoughReplicas)
+			err := p.Publish(context.Background(), e)
+
+			Convey("Then the error is returned", func() {
+				So(err, ShouldEqual, sarama.ErrNotEnoughReplicas)
+				So(p.Close(context.Background()), ShouldBeNil)
+			})
+		})
+
+		Convey("When an event is published without waiting and the broker does not acknowledge it", func() {
+			producer.ExpectInputAndFail(sarama.ErrNotEnoughReplicas)
+			p.PublishAsync(context.Background(), e)
+
+			Convey("Then the event is handed to the producer and the failure is only logged", func() {
+				So(p.Close(context.Background()), ShouldBeNil)
+			})
+		})
+
+		Convey("When an event is published through a best effort publisher", func() {
+			producer.ExpectInputAndFail(sarama.ErrNotEnoughReplicas)
+			err := BestEffort(p).Publish(context.Background(), e)
+
+			Convey("Then no error is returned", func() {
+				So(err, ShouldBeNil)
+				So(p.Close(context.Background()), ShouldBeNil)
+			})
+		})
+
+		Convey("When an event is published once the publisher is closed", func() {
+			So(p.Close(context.Background()), ShouldBeNil)
+			err := p.Publish(context.Background(), e)
+
+			Convey("Then ErrPublisherClosed is returned", func() {
+				So(err, ShouldEqual, ErrPublisherClosed)
+			})
+		})
+	})
+}
+
+// stalledProducer - an AsyncProducer whose input is never read, as when kafka is unreachable and its buffer is full
+type stalledProducer struct {
+	input     chan *sarama.ProducerMessage
+	successes chan *sarama.ProducerMessage
+	errors    chan *sarama.ProducerError
+}
+
+func newStalledProducer() *stalledProducer {
+	return &stalledProducer{
+		input:     make(chan *sarama.ProducerMessage, 1),
+		successes: make(chan *sarama.ProducerMessage),
+		errors:    make(chan *sarama.ProducerError),
+	}
+}
+
+func (p *stalledProducer) AsyncClose() { _ = p.Close() }
+
+func (p *stalledProducer) Close() error {
+	close(p.successes)
+	close(p.errors)
+	return nil
+}
+
+func (p *stalledProducer) Input() chan<- *sarama.ProducerMessage     { return p.input }
+func (p *stalledProducer) Successes() <-chan *sarama.ProducerMessage { return p.successes }
+func (p *stalledProducer) Errors() <-chan *sarama.ProducerError      { return p.errors }
+
+func TestKafkaPublisherProducerInputFull(t *testing.T) {
+	Convey("Given a kafka publisher whose producer's input is full", t, func() {
+		producer := newStalledProducer()
+		producer.input <- &sarama.ProducerMessage{}
+		p := NewKafkaPublisherWithProducer(producer, "session-events")
+		e := New(Accessed, "1234", "user@email.com")
+
+		Convey("When an event is published without waiting", func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			start := time.Now()
+			p.PublishAsync(ctx, e)
+			elapsed := time.Since(start)
+
+			Convey("Then it returns at once and the event is dropped", func() {
+				So(elapsed, ShouldBeLessThan, time.Second)
+				So(producer.input, ShouldHaveLength, 1)
+				So(p.Close(context.Background()), ShouldBeNil)
+			})
+		})
+
+		Convey("When an event is published through a best effort publisher", func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			start := time.Now()
+			err := BestEffort(p).Publish(ctx, e)
+			elapsed := time.Since(start)
+
+			Convey("Then it returns at once without an error", func() {
+				So(err, ShouldBeNil)
+				So(elapsed, ShouldBeLessThan, time.Second)
+				So(p.Close(context.Background()), ShouldBeNil)
+			})
+		})
+
+		Convey("When an event is published and waited for", func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			err := p.Publish(ctx, e)
+
+			Convey("Then it waits for the producer until the context is done", func() {
+				So(err, ShouldResemble, context.DeadlineExceeded)
+				So(p.Close(context.Background()), ShouldBeNil)
+			})
+		})
+	})
+}
@@ -0,0 +1,44 @@
+package events
+
+import (
+	"context"
+	"sync"
+)
+
+// InMemoryPublisher - an EventPublisher that keeps the events published to it, for use in tests
+type InMemoryPublisher struct {
+	mu     sync.Mutex
+	events []Event
+	// Err, if set, is returned by Publish and the event is not kept
+	Err error
+}
+
+// Publish - keeps e, or returns Err if it is set
+func (p *InMemoryPublisher) Publish(ctx context.Context, e Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.Err != nil {
+		return p.Err
+	}
+	p.events = append(p.events, e)
+	return nil
+}
+
+// Events - returns the events published so far, in the order they were published
+func (p *InMemoryPublisher) Events() []Event {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]Event(nil), p.events...)
+}
+
+// Reasons - returns the reason of each event published so far, in the order they were published
+func (p *InMemoryPublisher) Reasons() []string {
+	events := p.Events()
+	reasons := make([]string, len(events))
+	for i, e := range events {
+		reasons[i] = e.Reason
+	}
+	return reasons
+}
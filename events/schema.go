@@ -0,0 +1,20 @@
+package events
+
+import "github.com/ONSdigital/go-ns/avro"
+
+var sessionEvent = `{
+  "type": "record",
+  "name": "session-event",
+  "namespace": "",
+  "fields": [
+    {"name": "session_id", "type": "string", "default": ""},
+    {"name": "email_hash", "type": "string", "default": ""},
+    {"name": "timestamp", "type": "string", "default": ""},
+    {"name": "reason", "type": "string", "default": ""}
+  ]
+}`
+
+// SessionEventSchema defines the avro schema for a session event.
+var SessionEventSchema = &avro.Schema{
+	Definition: sessionEvent,
+}
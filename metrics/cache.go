@@ -0,0 +1,78 @@
+package metrics
+
+import (
+	"context"
+
+	"github.com/ONSdigital/dp-sessions-api/cache"
+	"github.com/ONSdigital/dp-sessions-api/session"
+)
+
+// Cache - a cache.SessionCache that counts the sessions looked up and deleted through the SessionCache it wraps. Methods
+// that are not counted are passed straight through. Sessions created are counted by Publisher.
+type Cache struct {
+	cache.SessionCache
+	metrics *Metrics
+}
+
+// InstrumentCache - wraps sessionCache so its operations are counted
+func (m *Metrics) InstrumentCache(sessionCache cache.SessionCache) *Cache {
+	return &Cache{SessionCache: sessionCache, metrics: m}
+}
+
+// GetByID - gets a session by ID, counting the outcome of the lookup
+func (c *Cache) GetByID(ctx context.Context, ID string) (*session.Session, error) {
+	s, err := c.SessionCache.GetByID(ctx, ID)
+	c.lookup(KeyID, err)
+	return s, err
+}
+
+// GetByEmail - gets a session by email, counting the outcome of the lookup
+func (c *Cache) GetByEmail(ctx context.Context, email string) (*session.Session, error) {
+	s, err := c.SessionCache.GetByEmail(ctx, email)
+	c.lookup(KeyEmail, err)
+	return s, err
+}
+
+// DeleteByID - deletes a session by ID, counting it if it is deleted
+func (c *Cache) DeleteByID(ctx context.Context, ID string) error {
+	err := c.SessionCache.DeleteByID(ctx, ID)
+	c.deletion(KeyID, err)
+	return err
+}
+
+// DeleteByEmail - deletes a session by email, counting it if it is deleted
+func (c *Cache) DeleteByEmail(ctx context.Context, email string) error {
+	err := c.SessionCache.DeleteByEmail(ctx, email)
+	c.deletion(KeyEmail, err)
+	return err
+}
+
+// RevokeByEmail - deletes every session for a user, counting the deletion if it succeeds
+func (c *Cache) RevokeByEmail(ctx context.Context, email string) (int, error) {
+	revoked, err := c.SessionCache.RevokeByEmail(ctx, email)
+	c.deletion(KeyUser, err)
+	return revoked, err
+}
+
+// DeleteAll - deletes every session, counting the deletion if it succeeds
+func (c *Cache) DeleteAll(ctx context.Context) error {
+	err := c.SessionCache.DeleteAll(ctx)
+	c.deletion(KeyAll, err)
+	return err
+}
+
+func (c *Cache) lookup(keyType string, err error) {
+	outcome := Hit
+	if err == cache.ErrSessionNotFound {
+		outcome = Miss
+	} else if err != nil {
+		outcome = Error
+	}
+	c.metrics.lookups.WithLabelValues(keyType, outcome).Inc()
+}
+
+func (c *Cache) deletion(keyType string, err error) {
+	if err == nil {
+		c.metrics.deletions.WithLabelValues(keyType).Inc()
+	}
+}
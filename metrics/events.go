@@ -0,0 +1,29 @@
+package metrics
+
+import (
+	"context"
+
+	"github.com/ONSdigital/dp-sessions-api/events"
+)
+
+// Publisher - an events.EventPublisher that counts the sessions created as their created events are published through
+// the EventPublisher it wraps. A session whose created event fails to publish under the closed failure policy is
+// discarded rather than returned, so it is only counted once its event has been published or the failure tolerated.
+type Publisher struct {
+	events.EventPublisher
+	metrics *Metrics
+}
+
+// InstrumentPublisher - wraps publisher so the sessions created are counted
+func (m *Metrics) InstrumentPublisher(publisher events.EventPublisher) *Publisher {
+	return &Publisher{EventPublisher: publisher, metrics: m}
+}
+
+// Publish - publishes e, counting a created session if e is a created event that was published
+func (p *Publisher) Publish(ctx context.Context, e events.Event) error {
+	err := p.EventPublisher.Publish(ctx, e)
+	if err == nil && e.Reason == events.Created {
+		p.metrics.sessionsCreated.Inc()
+	}
+	return err
+}
@@ -0,0 +1,51 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// unmatchedRoute is the route label for requests that did not match a route, keeping the label's cardinality bounded
+const unmatchedRoute = "unmatched"
+
+// statusRecorder - captures the status written to a http.ResponseWriter
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Middleware - wraps router, recording the duration of every request it serves labelled with the matched route's path
+// template rather than the request path, so IDs and emails do not become label values. Requests that match no route,
+// including those with a method the route does not allow, are recorded against the unmatched route.
+func (m *Metrics) Middleware(router *mux.Router) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		router.ServeHTTP(rec, r)
+
+		m.requestDuration.WithLabelValues(routeTemplate(router, r), r.Method, strconv.Itoa(rec.status)).Observe(time.Since(start).Seconds())
+	})
+}
+
+// routeTemplate returns the path template of the route in router that r matches, or unmatchedRoute if there is none
+func routeTemplate(router *mux.Router, r *http.Request) string {
+	var match mux.RouteMatch
+	if !router.Match(r, &match) || match.MatchErr != nil || match.Route == nil {
+		return unmatchedRoute
+	}
+
+	tmpl, err := match.Route.GetPathTemplate()
+	if err != nil {
+		return unmatchedRoute
+	}
+	return tmpl
+}
@@ -0,0 +1,193 @@
+package metrics
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ONSdigital/log.go/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "dp_sessions_api"
+
+// Lookup outcomes
+const (
+	Hit   = "hit"
+	Miss  = "miss"
+	Error = "error"
+)
+
+// Key types sessions are looked up and deleted by
+const (
+	KeyID    = "id"
+	KeyEmail = "email"
+	KeyUser  = "user"
+	KeyAll   = "all"
+)
+
+// SessionCounter - returns the number of active sessions
+type SessionCounter func() (int, error)
+
+// Metrics - the prometheus collectors for session, cache and HTTP operations
+type Metrics struct {
+	gatherer        prometheus.Gatherer
+	registerer      prometheus.Registerer
+	sessionsCreated prometheus.Counter
+	sessionsExpired prometheus.Counter
+	lookups         *prometheus.CounterVec
+	deletions       *prometheus.CounterVec
+	commandDuration *prometheus.HistogramVec
+	requestDuration *prometheus.HistogramVec
+	activeSessions  prometheus.Gauge
+
+	countSessions SessionCounter
+	counting      bool
+	stop          chan struct{}
+	stopOnce      sync.Once
+	done          chan struct{}
+}
+
+// New creates the collectors and registers them with a new registry, along with a gauge of the active sessions
+// reported by countSessions. Counting the sessions may scan the whole cache, so it is not done when metrics are
+// collected but by StartCountingSessions, and the gauge reports NaN until the first count.
+func New(countSessions SessionCounter) (*Metrics, error) {
+	reg := prometheus.NewRegistry()
+
+	m := &Metrics{
+		gatherer:      reg,
+		registerer:    reg,
+		countSessions: countSessions,
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+		sessionsCreated: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "sessions_created_total",
+			Help:      "Number of sessions created.",
+		}),
+		sessionsExpired: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "sessions_expired_total",
+			Help:      "Number of sessions that expired through their TTL.",
+		}),
+		lookups: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "session_lookups_total",
+			Help:      "Number of session lookups by the key type looked up and outcome.",
+		}, []string{"key_type", "outcome"}),
+		deletions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "session_deletions_total",
+			Help:      "Number of successful session deletions by the key type deleted.",
+		}, []string{"key_type"}),
+		commandDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "redis_command_duration_seconds",
+			Help:      "Duration of redis commands by RedisClienter method.",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+		}, []string{"command"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Duration of HTTP requests by route, method and response status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+		activeSessions: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "active_sessions",
+			Help:      "Number of sessions in the cache, as of the last time they were counted.",
+		}),
+	}
+	m.activeSessions.Set(math.NaN())
+
+	collectors := []prometheus.Collector{
+		m.sessionsCreated,
+		m.sessionsExpired,
+		m.lookups,
+		m.deletions,
+		m.commandDuration,
+		m.requestDuration,
+		m.activeSessions,
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+	}
+	for _, c := range collectors {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+// StartCountingSessions - counts the active sessions straight away and then every interval, in the background, until
+// Close is called. The active sessions gauge reports the latest count, or NaN if the sessions could not be counted. An
+// interval of zero disables counting.
+func (m *Metrics) StartCountingSessions(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	m.counting = true
+	go func() {
+		defer close(m.done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			m.updateActiveSessions()
+
+			select {
+			case <-ticker.C:
+			case <-m.stop:
+				return
+			}
+		}
+	}()
+}
+
+// updateActiveSessions - sets the active sessions gauge to the number of sessions counted now
+func (m *Metrics) updateActiveSessions() {
+	count, err := m.countSessions()
+	if err != nil {
+		log.Event(context.Background(), "failed to count active sessions", log.WARN, log.Error(err))
+		m.activeSessions.Set(math.NaN())
+		return
+	}
+	m.activeSessions.Set(float64(count))
+}
+
+// Close - stops the sessions being counted, waiting for a count in progress to finish unless ctx is done first. It is
+// safe to call whether or not StartCountingSessions was called.
+func (m *Metrics) Close(ctx context.Context) error {
+	m.stopOnce.Do(func() { close(m.stop) })
+	if !m.counting {
+		return nil
+	}
+
+	select {
+	case <-m.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Handler - returns a HTTP handler exposing the metrics in the prometheus text format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.gatherer, promhttp.HandlerOpts{})
+}
+
+// ObserveExpiry - counts a session that expired through its TTL, implementing cache.ExpiryObserver
+func (m *Metrics) ObserveExpiry() {
+	m.sessionsExpired.Inc()
+}
+
+// ObserveCommand - records the duration of a redis command, implementing cache.CommandObserver
+func (m *Metrics) ObserveCommand(command string, duration time.Duration) {
+	m.commandDuration.WithLabelValues(command).Observe(duration.Seconds())
+}
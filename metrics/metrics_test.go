@@ -0,0 +1,338 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	apiMock "github.com/ONSdigital/dp-sessions-api/api/mock"
+	"github.com/ONSdigital/dp-sessions-api/cache"
+	"github.com/ONSdigital/dp-sessions-api/events"
+	"github.com/ONSdigital/dp-sessions-api/session"
+	"github.com/go-redis/redis/v8"
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func countOf(n int) SessionCounter {
+	return func() (int, error) {
+		return n, nil
+	}
+}
+
+func TestCache(t *testing.T) {
+	Convey("Given a cache instrumented with metrics", t, func() {
+		m, err := New(countOf(0))
+		So(err, ShouldBeNil)
+
+		mockCache := &apiMock.CacheMock{
+			SetSessionFunc: func(ctx context.Context, s *session.Session) error {
+				return nil
+			},
+			GetByIDFunc: func(ctx context.Context, ID string) (*session.Session, error) {
+				switch ID {
+				case "hit":
+					return &session.Session{ID: ID}, nil
+				case "miss":
+					return nil, cache.ErrSessionNotFound
+				}
+				return nil, errors.New("unexpected error")
+			},
+			GetByEmailFunc: func(ctx context.Context, email string) (*session.Session, error) {
+				return &session.Session{Email: email}, nil
+			},
+			DeleteByIDFunc: func(ctx context.Context, ID string) error {
+				return cache.ErrSessionNotFound
+			},
+			RevokeByEmailFunc: func(ctx context.Context, email string) (int, error) {
+				return 2, nil
+			},
+			DiscardFunc: func(ctx context.Context, ID string) error {
+				return nil
+			},
+		}
+		c := m.InstrumentCache(mockCache)
+		ctx := context.Background()
+
+		Convey("When a session is added", func() {
+			err := c.SetSession(ctx, &session.Session{ID: "123"})
+
+			Convey("Then it is not counted until its created event is published", func() {
+				So(err, ShouldBeNil)
+				So(mockCache.SetSessionCalls(), ShouldHaveLength, 1)
+				So(testutil.ToFloat64(m.sessionsCreated), ShouldEqual, 0)
+			})
+		})
+
+		Convey("When sessions are looked up", func() {
+			c.GetByID(ctx, "hit")
+			c.GetByID(ctx, "hit")
+			c.GetByID(ctx, "miss")
+			c.GetByID(ctx, "error")
+			c.GetByEmail(ctx, "user@test.com")
+
+			Convey("Then the lookups are counted by key type and outcome", func() {
+				So(testutil.ToFloat64(m.lookups.WithLabelValues(KeyID, Hit)), ShouldEqual, 2)
+				So(testutil.ToFloat64(m.lookups.WithLabelValues(KeyID, Miss)), ShouldEqual, 1)
+				So(testutil.ToFloat64(m.lookups.WithLabelValues(KeyID, Error)), ShouldEqual, 1)
+				So(testutil.ToFloat64(m.lookups.WithLabelValues(KeyEmail, Hit)), ShouldEqual, 1)
+			})
+		})
+
+		Convey("When sessions are deleted", func() {
+			revoked, err := c.RevokeByEmail(ctx, "user@test.com")
+			So(err, ShouldBeNil)
+			So(revoked, ShouldEqual, 2)
+
+			err = c.DeleteByID(ctx, "123")
+			So(err, ShouldEqual, cache.ErrSessionNotFound)
+
+			Convey("Then only successful deletions are counted", func() {
+				So(testutil.ToFloat64(m.deletions.WithLabelValues(KeyUser)), ShouldEqual, 1)
+				So(testutil.ToFloat64(m.deletions.WithLabelValues(KeyID)), ShouldEqual, 0)
+			})
+		})
+
+		Convey("When a session is discarded", func() {
+			So(c.Discard(ctx, "123"), ShouldBeNil)
+
+			Convey("Then it is not counted as a deletion", func() {
+				So(mockCache.DiscardCalls(), ShouldHaveLength, 1)
+				So(testutil.CollectAndCount(m.deletions), ShouldEqual, 0)
+			})
+		})
+	})
+}
+
+func TestPublisher(t *testing.T) {
+	Convey("Given a publisher instrumented with metrics", t, func() {
+		m, err := New(countOf(0))
+		So(err, ShouldBeNil)
+
+		publishErr := errors.New("broker unavailable")
+		publisher := &failingPublisher{fail: map[string]bool{"failed": true}, err: publishErr}
+		p := m.InstrumentPublisher(publisher)
+		ctx := context.Background()
+
+		Convey("When a session's created event is published", func() {
+			err := p.Publish(ctx, events.New(events.Created, "123", "user@test.com"))
+
+			Convey("Then the sessions created counter is incremented", func() {
+				So(err, ShouldBeNil)
+				So(testutil.ToFloat64(m.sessionsCreated), ShouldEqual, 1)
+			})
+		})
+
+		Convey("When a session's created event cannot be published", func() {
+			err := p.Publish(ctx, events.New(events.Created, "failed", "user@test.com"))
+
+			Convey("Then the error is returned and the session is not counted", func() {
+				So(err, ShouldEqual, publishErr)
+				So(testutil.ToFloat64(m.sessionsCreated), ShouldEqual, 0)
+			})
+		})
+
+		Convey("When another event is published", func() {
+			err := p.Publish(ctx, events.New(events.Revoked, "123", "user@test.com"))
+
+			Convey("Then no session is counted as created", func() {
+				So(err, ShouldBeNil)
+				So(testutil.ToFloat64(m.sessionsCreated), ShouldEqual, 0)
+			})
+		})
+	})
+}
+
+// failingPublisher - an events.EventPublisher that fails to publish the events for the session IDs in fail
+type failingPublisher struct {
+	fail map[string]bool
+	err  error
+}
+
+func (p *failingPublisher) Publish(ctx context.Context, e events.Event) error {
+	if p.fail[e.SessionID] {
+		return p.err
+	}
+	return nil
+}
+
+func TestMiddleware(t *testing.T) {
+	Convey("Given a router instrumented with metrics", t, func() {
+		m, err := New(countOf(0))
+		So(err, ShouldBeNil)
+
+		r := mux.NewRouter()
+		r.HandleFunc("/sessions/{ID}", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		}).Methods(http.MethodGet)
+		handler := m.Middleware(r)
+
+		Convey("When a request is routed", func() {
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/sessions/123", nil))
+
+			Convey("Then its duration is recorded against the route template and status", func() {
+				So(testutil.CollectAndCount(m.requestDuration), ShouldEqual, 1)
+
+				body := scrape(m)
+				So(body, ShouldContainSubstring, `dp_sessions_api_http_request_duration_seconds_count{method="GET",route="/sessions/{ID}",status="404"} 1`)
+				So(body, ShouldNotContainSubstring, `route="/sessions/123"`)
+			})
+		})
+
+		Convey("When a request for an unknown path is received", func() {
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/unknown/123", nil))
+
+			Convey("Then its duration is recorded against the unmatched route", func() {
+				body := scrape(m)
+				So(body, ShouldContainSubstring, `dp_sessions_api_http_request_duration_seconds_count{method="GET",route="unmatched",status="404"} 1`)
+				So(body, ShouldNotContainSubstring, `route="/unknown/123"`)
+			})
+		})
+
+		Convey("When a request with a method the route does not allow is received", func() {
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/sessions/123", nil))
+
+			Convey("Then its duration is recorded against the unmatched route", func() {
+				So(scrape(m), ShouldContainSubstring, `dp_sessions_api_http_request_duration_seconds_count{method="POST",route="unmatched",status="405"} 1`)
+			})
+		})
+	})
+}
+
+func TestMetrics(t *testing.T) {
+	Convey("Given metrics with a number of active sessions", t, func() {
+		m, err := New(countOf(42))
+		So(err, ShouldBeNil)
+
+		Convey("When a redis command is observed", func() {
+			m.ObserveCommand("Get", 3*time.Millisecond)
+
+			Convey("Then its duration is recorded against the command", func() {
+				So(scrape(m), ShouldContainSubstring, `dp_sessions_api_redis_command_duration_seconds_count{command="Get"} 1`)
+			})
+		})
+
+		Convey("When a session expires", func() {
+			m.ObserveExpiry()
+
+			Convey("Then the sessions expired counter is incremented", func() {
+				So(testutil.ToFloat64(m.sessionsExpired), ShouldEqual, 1)
+			})
+		})
+
+		Convey("When the metrics are scraped before the sessions have been counted", func() {
+			body := scrape(m)
+
+			Convey("Then the active sessions gauge is reported as NaN", func() {
+				So(body, ShouldContainSubstring, "dp_sessions_api_active_sessions NaN")
+			})
+		})
+
+		Convey("When the metrics are scraped after the sessions have been counted", func() {
+			m.updateActiveSessions()
+			body := scrape(m)
+
+			Convey("Then the active sessions gauge is reported", func() {
+				So(body, ShouldContainSubstring, "dp_sessions_api_active_sessions 42")
+			})
+		})
+	})
+
+	Convey("Given metrics counting the active sessions on an interval", t, func() {
+		var counts int32
+		m, err := New(func() (int, error) {
+			return int(atomic.AddInt32(&counts, 1)), nil
+		})
+		So(err, ShouldBeNil)
+		m.StartCountingSessions(10 * time.Millisecond)
+
+		Convey("When the interval passes", func() {
+			time.Sleep(50 * time.Millisecond)
+
+			Convey("Then the sessions are counted again", func() {
+				So(atomic.LoadInt32(&counts), ShouldBeGreaterThan, 1)
+			})
+		})
+
+		Convey("When the metrics are scraped", func() {
+			So(m.Close(context.Background()), ShouldBeNil)
+			before := atomic.LoadInt32(&counts)
+			scrape(m)
+			scrape(m)
+
+			Convey("Then the sessions are not counted by the scrapes", func() {
+				So(atomic.LoadInt32(&counts), ShouldEqual, before)
+			})
+		})
+
+		Convey("When the metrics are closed", func() {
+			So(m.Close(context.Background()), ShouldBeNil)
+			before := atomic.LoadInt32(&counts)
+			time.Sleep(30 * time.Millisecond)
+
+			Convey("Then the sessions are no longer counted", func() {
+				So(atomic.LoadInt32(&counts), ShouldEqual, before)
+			})
+		})
+
+		Reset(func() {
+			So(m.Close(context.Background()), ShouldBeNil)
+		})
+	})
+
+	Convey("Given metrics for a redis connection pool", t, func() {
+		m, err := New(countOf(0))
+		So(err, ShouldBeNil)
+		So(m.RegisterPool(poolStub{Hits: 10, Misses: 2, Timeouts: 1, TotalConns: 5, IdleConns: 3}), ShouldBeNil)
+
+		Convey("When the metrics are scraped", func() {
+			body := scrape(m)
+
+			Convey("Then the pool statistics are reported", func() {
+				So(body, ShouldContainSubstring, "dp_sessions_api_redis_pool_hits_total 10")
+				So(body, ShouldContainSubstring, "dp_sessions_api_redis_pool_misses_total 2")
+				So(body, ShouldContainSubstring, "dp_sessions_api_redis_pool_timeouts_total 1")
+				So(body, ShouldContainSubstring, `dp_sessions_api_redis_pool_connections{state="total"} 5`)
+				So(body, ShouldContainSubstring, `dp_sessions_api_redis_pool_connections{state="idle"} 3`)
+			})
+		})
+	})
+
+	Convey("Given the active sessions cannot be counted", t, func() {
+		m, err := New(func() (int, error) {
+			return 0, errors.New("connection refused")
+		})
+		So(err, ShouldBeNil)
+
+		Convey("When the sessions are counted and the metrics scraped", func() {
+			m.updateActiveSessions()
+			body := scrape(m)
+
+			Convey("Then the active sessions gauge is reported as NaN", func() {
+				So(body, ShouldContainSubstring, "dp_sessions_api_active_sessions NaN")
+			})
+		})
+	})
+}
+
+func scrape(m *Metrics) string {
+	resp := httptest.NewRecorder()
+	m.Handler().ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	b, _ := ioutil.ReadAll(resp.Body)
+	return strings.TrimSpace(string(b))
+}
+
+// poolStub - a PoolStatser reporting fixed statistics
+type poolStub redis.PoolStats
+
+func (p poolStub) PoolStats() *redis.PoolStats {
+	stats := redis.PoolStats(p)
+	return &stats
+}
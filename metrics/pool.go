@@ -0,0 +1,52 @@
+package metrics
+
+import (
+	"github.com/go-redis/redis/v8"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// PoolStatser - reports the statistics of a redis connection pool, such as a cache.ElasticacheClient
+type PoolStatser interface {
+	PoolStats() *redis.PoolStats
+}
+
+// poolCollector - collects the statistics of a redis connection pool each time metrics are gathered
+type poolCollector struct {
+	pool        PoolStatser
+	hits        *prometheus.Desc
+	misses      *prometheus.Desc
+	timeouts    *prometheus.Desc
+	connections *prometheus.Desc
+}
+
+// RegisterPool - registers metrics for the connection pool of pool, read each time metrics are collected
+func (m *Metrics) RegisterPool(pool PoolStatser) error {
+	return m.registerer.Register(&poolCollector{
+		pool: pool,
+		hits: prometheus.NewDesc(prometheus.BuildFQName(namespace, "redis_pool", "hits_total"),
+			"Number of times a free connection was found in the redis connection pool.", nil, nil),
+		misses: prometheus.NewDesc(prometheus.BuildFQName(namespace, "redis_pool", "misses_total"),
+			"Number of times a free connection was not found in the redis connection pool.", nil, nil),
+		timeouts: prometheus.NewDesc(prometheus.BuildFQName(namespace, "redis_pool", "timeouts_total"),
+			"Number of times waiting for a connection from the redis connection pool timed out.", nil, nil),
+		connections: prometheus.NewDesc(prometheus.BuildFQName(namespace, "redis_pool", "connections"),
+			"Number of connections in the redis connection pool by state.", []string{"state"}, nil),
+	})
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.hits
+	ch <- c.misses
+	ch <- c.timeouts
+	ch <- c.connections
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.pool.PoolStats()
+	ch <- prometheus.MustNewConstMetric(c.hits, prometheus.CounterValue, float64(stats.Hits))
+	ch <- prometheus.MustNewConstMetric(c.misses, prometheus.CounterValue, float64(stats.Misses))
+	ch <- prometheus.MustNewConstMetric(c.timeouts, prometheus.CounterValue, float64(stats.Timeouts))
+	ch <- prometheus.MustNewConstMetric(c.connections, prometheus.GaugeValue, float64(stats.TotalConns), "total")
+	ch <- prometheus.MustNewConstMetric(c.connections, prometheus.GaugeValue, float64(stats.IdleConns), "idle")
+	ch <- prometheus.MustNewConstMetric(c.connections, prometheus.GaugeValue, float64(stats.StaleConns), "stale")
+}
@@ -3,26 +3,40 @@ package service
 import (
 	"context"
 	"crypto/tls"
+	"encoding/base64"
 
 	"github.com/ONSdigital/dp-api-clients-go/zebedee"
 	"github.com/ONSdigital/dp-authorisation/auth"
 	"github.com/ONSdigital/dp-healthcheck/healthcheck"
+	dphandlers "github.com/ONSdigital/dp-net/handlers"
 	rchttp "github.com/ONSdigital/dp-net/http"
 	"github.com/ONSdigital/dp-sessions-api/api"
 	"github.com/ONSdigital/dp-sessions-api/cache"
 	"github.com/ONSdigital/dp-sessions-api/config"
+	"github.com/ONSdigital/dp-sessions-api/events"
+	"github.com/ONSdigital/dp-sessions-api/metrics"
+	"github.com/ONSdigital/dp-sessions-api/session"
 	"github.com/ONSdigital/go-ns/server"
 	"github.com/ONSdigital/log.go/log"
 	"github.com/gorilla/mux"
 	"github.com/pkg/errors"
 )
 
+// Session cache backends selected by config.CacheBackend
+const (
+	redisBackend  = "redis"
+	memoryBackend = "memory"
+)
+
 type Service struct {
 	Config      *config.Config
 	server      *server.Server
 	Router      *mux.Router
 	API         *api.API
 	HealthCheck *healthcheck.HealthCheck
+	kafka       *events.KafkaPublisher
+	expiry      *cache.ExpirySubscriber
+	metrics     *metrics.Metrics
 }
 
 // Run the service
@@ -36,10 +50,13 @@ func Run(buildTime, gitCommit, version string, svcErrors chan error) (*Service,
 	}
 	log.Event(ctx, "got service configuration", log.Data{"config": cfg}, log.INFO)
 
-	r := mux.NewRouter()
+	idGenerator, err := session.NewIDGenerator(cfg.SessionIDFormat)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to create session ID generator")
+	}
+	session.SetIDGenerator(idGenerator)
 
-	s := server.New(cfg.BindAddr, r)
-	s.HandleOSSignals = false
+	r := mux.NewRouter()
 
 	permissions := getAuthorisationHandlers(cfg)
 
@@ -55,38 +72,119 @@ func Run(buildTime, gitCommit, version string, svcErrors chan error) (*Service,
 	hc := healthcheck.New(versionInfo, cfg.HealthCheckCriticalTimeout, cfg.HealthCheckInterval)
 	zebedeeClient := zebedee.New(cfg.ZebedeeURL)
 
-	var elasticacheClient *cache.ElasticacheClient
-	if cfg.EnableRedisTLSConfig {
-		elasticacheClient, err = cache.New(cache.Config{
-			Addr:     cfg.ElasticacheAddr,
-			Password: cfg.ElasticachePassword,
-			Database: cfg.ElasticacheDatabase,
-			TTL:      cfg.ElasticacheTTL,
-			TLS: &tls.Config{
-				InsecureSkipVerify: true,
-			},
-		})
-	} else {
-		elasticacheClient, err = cache.New(cache.Config{
-			Addr:     cfg.ElasticacheAddr,
-			Password: cfg.ElasticachePassword,
-			Database: cfg.ElasticacheDatabase,
-			TTL:      cfg.ElasticacheTTL,
-		})
+	var sessionCache cache.Backend
+	m, err := metrics.New(func() (int, error) {
+		return sessionCache.CountSessions(context.Background())
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to create metrics")
 	}
 
+	// wrap the router rather than adding a router middleware, so requests matching no route are recorded too
+	s := server.New(cfg.BindAddr, m.Middleware(r))
+	s.HandleOSSignals = false
+
+	kafkaPublisher, publisher, err := getEventPublisher(cfg)
 	if err != nil {
-		return nil, errors.Wrap(err, "unable to create elasticache client")
+		return nil, errors.Wrap(err, "unable to create session event publisher")
 	}
 
-	if err := registerCheckers(ctx, &hc, zebedeeClient, elasticacheClient); err != nil {
+	cacheConfig := cache.Config{
+		Mode:                    cache.Mode(cfg.ElasticacheMode),
+		Addr:                    cfg.ElasticacheAddr,
+		SeedAddrs:               cfg.ElasticacheSeedAddrs,
+		MasterName:              cfg.ElasticacheMasterName,
+		Password:                cfg.ElasticachePassword,
+		Database:                cfg.ElasticacheDatabase,
+		TTL:                     cfg.ElasticacheTTL,
+		OperationTimeout:        cfg.ElasticacheTimeout,
+		DialTimeout:             cfg.ElasticacheDialTimeout,
+		ReadTimeout:             cfg.ElasticacheReadTimeout,
+		WriteTimeout:            cfg.ElasticacheWriteTimeout,
+		PoolSize:                cfg.ElasticachePoolSize,
+		MinIdleConns:            cfg.ElasticacheMinIdleConns,
+		MaxConnAge:              cfg.ElasticacheMaxConnAge,
+		PoolTimeout:             cfg.ElasticachePoolTimeout,
+		LatencyWarningThreshold: cfg.ElasticacheLatencyWarning,
+		MemoryWarningThreshold:  cfg.ElasticacheMemoryWarning,
+		MaxRetries:              cfg.ElasticacheMaxRetries,
+		MinRetryBackoff:         cfg.ElasticacheMinRetryBackoff,
+		MaxRetryBackoff:         cfg.ElasticacheMaxRetryBackoff,
+		BreakerFailureThreshold: cfg.BreakerFailureThreshold,
+		BreakerOpenTimeout:      cfg.BreakerOpenTimeout,
+		KeyPrefix:               cfg.ElasticacheKeyPrefix,
+		MaxLifetime:             cfg.SessionMaxLifetime,
+		MaxSessionsPerUser:      cfg.MaxSessionsPerUser,
+		LimitPolicy:             cache.LimitPolicy(cfg.SessionLimitPolicy),
+		Observer:                m,
+		Publisher:               publisher,
+	}
+	if kafkaPublisher != nil {
+		// accessed events are published on every read, so reads do not wait for them whatever the failure policy
+		cacheConfig.AccessPublisher = events.BestEffort(kafkaPublisher)
+	}
+	if len(cfg.SessionEncryptionKeys) > 0 {
+		cacheConfig.Codec, err = getSessionCodec(cfg)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to create session encryption codec")
+		}
+		if cfg.SessionEncryptionAllowPlaintext {
+			log.Event(ctx, "unencrypted sessions can be read, this should only be allowed while migrating to encrypted sessions", log.WARN)
+		}
+	}
+	if cfg.EnableRedisTLSConfig {
+		cacheConfig.TLS = &cache.TLSConfig{
+			CAFile:             cfg.RedisTLSCAFile,
+			ServerName:         cfg.RedisTLSServerName,
+			CertFile:           cfg.RedisTLSCertFile,
+			KeyFile:            cfg.RedisTLSKeyFile,
+			InsecureSkipVerify: cfg.RedisTLSInsecureSkipVerify,
+		}
+		if cfg.RedisTLSInsecureSkipVerify {
+			log.Event(ctx, "redis TLS certificate verification is disabled, the connection is open to interception", log.WARN)
+		}
+	}
+
+	var expirySubscriber *cache.ExpirySubscriber
+	cacheCheckName := "Elasticache"
+	switch cfg.CacheBackend {
+	case redisBackend:
+		elasticacheClient, err := cache.New(cacheConfig)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to create elasticache client")
+		}
+		sessionCache = elasticacheClient
+		if err := m.RegisterPool(elasticacheClient); err != nil {
+			return nil, errors.Wrap(err, "unable to register redis pool metrics")
+		}
+		expirySubscriber = cache.NewExpirySubscriber(elasticacheClient, m)
+	case memoryBackend:
+		log.Event(ctx, "using in-memory session cache, sessions are lost when the service stops", log.WARN)
+		sessionCache, err = cache.NewInMemory(cacheConfig)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to create in-memory cache")
+		}
+		cacheCheckName = "In-memory cache"
+	default:
+		return nil, errors.Errorf("unknown cache backend %q, should be redis or memory", cfg.CacheBackend)
+	}
+
+	if err := registerCheckers(ctx, &hc, zebedeeClient, cacheCheckName, sessionCache.Checker); err != nil {
 		return nil, errors.Wrap(err, "unable to register checkers")
 	}
 	r.StrictSlash(true).Path("/health").HandlerFunc(hc.Handler)
+	r.Path("/metrics").Handler(m.Handler())
 
 	hc.Start(ctx)
+	m.StartCountingSessions(cfg.ActiveSessionsCountInterval)
+
+	if expirySubscriber != nil {
+		if err := expirySubscriber.Start(ctx); err != nil {
+			return nil, errors.Wrap(err, "unable to subscribe to session expiry notifications")
+		}
+	}
 
-	a := api.Setup(ctx, r, permissions, elasticacheClient)
+	a := api.Setup(ctx, r, permissions, m.InstrumentCache(sessionCache), m.InstrumentPublisher(publisher))
 
 	go func() {
 		if err := s.ListenAndServe(); err != nil {
@@ -100,6 +198,9 @@ func Run(buildTime, gitCommit, version string, svcErrors chan error) (*Service,
 		API:         a,
 		HealthCheck: &hc,
 		server:      s,
+		kafka:       kafkaPublisher,
+		expiry:      expirySubscriber,
+		metrics:     m,
 	}, nil
 }
 
@@ -119,10 +220,26 @@ func (svc *Service) Close(ctx context.Context) {
 		log.Event(ctx, "error closing API", log.Error(err), log.ERROR)
 	}
 
+	if svc.expiry != nil {
+		if err := svc.expiry.Close(ctx); err != nil {
+			log.Event(ctx, "error closing session expiry subscriber", log.Error(err), log.ERROR)
+		}
+	}
+
+	if err := svc.metrics.Close(ctx); err != nil {
+		log.Event(ctx, "error stopping active session counts", log.Error(err), log.ERROR)
+	}
+
+	if svc.kafka != nil {
+		if err := svc.kafka.Close(ctx); err != nil {
+			log.Event(ctx, "error closing session event producer", log.Error(err), log.ERROR)
+		}
+	}
+
 	log.Event(ctx, "graceful shutdown complete", log.INFO)
 }
 
-func registerCheckers(ctx context.Context, hc *healthcheck.HealthCheck, zebedeeClient *zebedee.Client, elasticacheClient *cache.ElasticacheClient) (err error) {
+func registerCheckers(ctx context.Context, hc *healthcheck.HealthCheck, zebedeeClient *zebedee.Client, cacheCheckName string, cacheChecker healthcheck.Checker) (err error) {
 	hasErrors := false
 
 	if err = hc.AddCheck("Zebedee", zebedeeClient.Checker); err != nil {
@@ -130,9 +247,9 @@ func registerCheckers(ctx context.Context, hc *healthcheck.HealthCheck, zebedeeC
 		log.Event(ctx, "error adding check for zebedeee", log.ERROR, log.Error(err))
 	}
 
-	if err = hc.AddCheck("Elasticache", elasticacheClient.Checker); err != nil {
+	if err = hc.AddCheck(cacheCheckName, cacheChecker); err != nil {
 		hasErrors = true
-		log.Event(ctx, "error adding check for elasticache", log.ERROR, log.Error(err))
+		log.Event(ctx, "error adding check for session cache", log.ERROR, log.Error(err), log.Data{"check": cacheCheckName})
 	}
 
 	if hasErrors {
@@ -141,6 +258,58 @@ func registerCheckers(ctx context.Context, hc *healthcheck.HealthCheck, zebedeeC
 	return nil
 }
 
+// getEventPublisher returns the publisher for session lifecycle events with the configured failure policy applied,
+// along with the kafka publisher it wraps so it can be closed on shutdown. When events are disabled they are discarded
+// and the kafka publisher is nil.
+func getEventPublisher(cfg *config.Config) (*events.KafkaPublisher, events.EventPublisher, error) {
+	if !cfg.SessionEventsEnabled {
+		return nil, events.NopPublisher{}, nil
+	}
+
+	var tlsConfig *tls.Config
+	if cfg.KafkaSecProtocol == "TLS" {
+		tlsConfig = &tls.Config{}
+	}
+
+	kafkaPublisher, err := events.NewKafkaPublisher(cfg.KafkaAddr, cfg.SessionEventsTopic, tlsConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	publisher, err := events.WithFailurePolicy(kafkaPublisher, events.FailurePolicy(cfg.SessionEventsFailurePolicy))
+	if err != nil {
+		kafkaPublisher.Close(context.Background())
+		return nil, nil, err
+	}
+
+	return kafkaPublisher, publisher, nil
+}
+
+// getSessionCodec returns the codec encrypting sessions and hashing emails with the configured keys, which are base64
+// encoded
+func getSessionCodec(cfg *config.Config) (*cache.AESGCMCodec, error) {
+	keys := make(map[string][]byte, len(cfg.SessionEncryptionKeys))
+	for id, encoded := range cfg.SessionEncryptionKeys {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to decode session encryption key %q", id)
+		}
+		keys[id] = key
+	}
+
+	indexKey, err := base64.StdEncoding.DecodeString(cfg.SessionIndexKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to decode session index key")
+	}
+
+	return cache.NewAESGCMCodec(cache.EncryptionConfig{
+		Keys:           keys,
+		CurrentKeyID:   cfg.SessionEncryptionKeyID,
+		IndexKey:       indexKey,
+		AllowPlaintext: cfg.SessionEncryptionAllowPlaintext,
+	})
+}
+
 func getAuthorisationHandlers(cfg *config.Config) api.AuthHandler {
 	auth.LoggerNamespace("dp-sessions-api-auth")
 
@@ -154,5 +323,6 @@ func getAuthorisationHandlers(cfg *config.Config) api.AuthHandler {
 		authVerifier,
 	)
 
-	return permissions
+	// restrict reads and updates to the allowed calling services, identified from their service token
+	return api.NewCallerAllowList(permissions, dphandlers.Identity(cfg.ZebedeeURL), cfg.ReadAllowedCallers)
 }
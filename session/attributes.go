@@ -0,0 +1,83 @@
+package session
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// MaxAttributes is the maximum number of attributes a session may hold
+	MaxAttributes = 64
+
+	// MaxAttributesSize is the maximum size in bytes of a session's attributes once encoded as JSON
+	MaxAttributesSize = 8 * 1024
+)
+
+var (
+	EmptyAttributeKeyErr  = errors.New("session attribute keys must not be empty")
+	TooManyAttributesErr  = errors.New("session attributes exceed the maximum number allowed")
+	AttributesTooLargeErr = errors.New("session attributes exceed the maximum size allowed")
+)
+
+// MergeAttributes applies update to the session's attributes. Keys in update overwrite existing keys of the same name
+// and keys with a null value are removed; other existing attributes are kept. Values are replaced rather than merged,
+// so nested objects must be sent in full. The session is left unchanged if the merged attributes would exceed
+// MaxAttributes or MaxAttributesSize.
+func (s *Session) MergeAttributes(update map[string]interface{}) error {
+	merged := make(map[string]interface{}, len(s.Attributes)+len(update))
+	for k, v := range s.Attributes {
+		merged[k] = v
+	}
+
+	for k, v := range update {
+		if len(k) == 0 {
+			return EmptyAttributeKeyErr
+		}
+
+		if v == nil {
+			delete(merged, k)
+			continue
+		}
+		merged[k] = v
+	}
+
+	if err := ValidateAttributes(merged); err != nil {
+		return err
+	}
+
+	if len(merged) == 0 {
+		merged = nil
+	}
+	s.Attributes = merged
+	return nil
+}
+
+// ValidateAttributes returns an error if attributes exceed MaxAttributes or MaxAttributesSize, or if any key is empty
+func ValidateAttributes(attributes map[string]interface{}) error {
+	if len(attributes) > MaxAttributes {
+		return TooManyAttributesErr
+	}
+
+	for k := range attributes {
+		if len(k) == 0 {
+			return EmptyAttributeKeyErr
+		}
+	}
+
+	b, err := json.Marshal(attributes)
+	if err != nil {
+		return err
+	}
+
+	if len(b) > MaxAttributesSize {
+		return AttributesTooLargeErr
+	}
+
+	return nil
+}
+
+// IsAttributesErr returns true if err was returned because session attributes were invalid
+func IsAttributesErr(err error) bool {
+	return err == EmptyAttributeKeyErr || err == TooManyAttributesErr || err == AttributesTooLargeErr
+}
@@ -0,0 +1,113 @@
+package session
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSession_MergeAttributes(t *testing.T) {
+	Convey("Given a session with attributes", t, func() {
+		s := &Session{
+			ID:    "123",
+			Email: "test@test.com",
+			Attributes: map[string]interface{}{
+				"name":       "Test User",
+				"collection": "abc",
+			},
+		}
+
+		Convey("When attributes are merged", func() {
+			err := s.MergeAttributes(map[string]interface{}{
+				"collection": "xyz",
+				"roles":      []interface{}{"publisher"},
+			})
+
+			Convey("Then new attributes are added, existing attributes are overwritten and others are kept", func() {
+				So(err, ShouldBeNil)
+				So(s.Attributes, ShouldResemble, map[string]interface{}{
+					"name":       "Test User",
+					"collection": "xyz",
+					"roles":      []interface{}{"publisher"},
+				})
+			})
+		})
+
+		Convey("When an attribute is merged with a null value", func() {
+			err := s.MergeAttributes(map[string]interface{}{"collection": nil})
+
+			Convey("Then the attribute is removed", func() {
+				So(err, ShouldBeNil)
+				So(s.Attributes, ShouldResemble, map[string]interface{}{"name": "Test User"})
+			})
+		})
+
+		Convey("When every attribute is removed", func() {
+			err := s.MergeAttributes(map[string]interface{}{"name": nil, "collection": nil})
+
+			Convey("Then the session has no attributes", func() {
+				So(err, ShouldBeNil)
+				So(s.Attributes, ShouldBeNil)
+			})
+		})
+
+		Convey("When an attribute with an empty key is merged", func() {
+			err := s.MergeAttributes(map[string]interface{}{"": "value"})
+
+			Convey("Then EmptyAttributeKeyErr is returned and the session is unchanged", func() {
+				So(err, ShouldEqual, EmptyAttributeKeyErr)
+				So(s.Attributes, ShouldHaveLength, 2)
+			})
+		})
+
+		Convey("When the merged attributes would exceed MaxAttributes", func() {
+			update := make(map[string]interface{})
+			for i := 0; i < MaxAttributes-1; i++ {
+				update[fmt.Sprintf("key-%d", i)] = i
+			}
+			err := s.MergeAttributes(update)
+
+			Convey("Then TooManyAttributesErr is returned and the session is unchanged", func() {
+				So(err, ShouldEqual, TooManyAttributesErr)
+				So(s.Attributes, ShouldHaveLength, 2)
+			})
+		})
+
+		Convey("When the merged attributes would exceed MaxAttributesSize", func() {
+			err := s.MergeAttributes(map[string]interface{}{"csrf": strings.Repeat("a", MaxAttributesSize)})
+
+			Convey("Then AttributesTooLargeErr is returned and the session is unchanged", func() {
+				So(err, ShouldEqual, AttributesTooLargeErr)
+				So(s.Attributes, ShouldNotContainKey, "csrf")
+			})
+		})
+	})
+
+	Convey("Given a session without attributes", t, func() {
+		s := &Session{ID: "123", Email: "test@test.com"}
+
+		Convey("When no attributes are merged", func() {
+			err := s.MergeAttributes(nil)
+
+			Convey("Then the session still has no attributes", func() {
+				So(err, ShouldBeNil)
+				So(s.Attributes, ShouldBeNil)
+			})
+		})
+	})
+}
+
+func TestIsAttributesErr(t *testing.T) {
+	Convey("IsAttributesErr should return true for attribute validation errors", t, func() {
+		So(IsAttributesErr(EmptyAttributeKeyErr), ShouldBeTrue)
+		So(IsAttributesErr(TooManyAttributesErr), ShouldBeTrue)
+		So(IsAttributesErr(AttributesTooLargeErr), ShouldBeTrue)
+	})
+
+	Convey("IsAttributesErr should return false for other errors", t, func() {
+		So(IsAttributesErr(EmailEmptyErr), ShouldBeFalse)
+		So(IsAttributesErr(nil), ShouldBeFalse)
+	})
+}
@@ -0,0 +1,73 @@
+package session
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+const (
+	// RandomIDFormat is the ID format for opaque 256-bit random tokens
+	RandomIDFormat = "random"
+	// UUIDFormat is the ID format for version 4 UUIDs
+	UUIDFormat = "uuid"
+
+	randomIDBytes = 32
+)
+
+var (
+	UnknownIDFormatErr = errors.New("unknown session ID format")
+
+	idGenerator IDGenerator = RandomIDGenerator{}
+)
+
+// IDGenerator defines the behaviour required to generate new session IDs. Session IDs are bearer credentials so
+// implementations must be unpredictable.
+type IDGenerator interface {
+	Generate() (string, error)
+}
+
+// RandomIDGenerator generates 256-bit tokens read from crypto/rand, encoded as unpadded base64url
+type RandomIDGenerator struct{}
+
+// Generate returns a new random session ID
+func (RandomIDGenerator) Generate() (string, error) {
+	b := make([]byte, randomIDBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// UUIDGenerator generates version 4 (random) UUIDs
+type UUIDGenerator struct{}
+
+// Generate returns a new UUIDv4 session ID
+func (UUIDGenerator) Generate() (string, error) {
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return "", err
+	}
+	return id.String(), nil
+}
+
+// NewIDGenerator returns the IDGenerator for the named format. Returns session.UnknownIDFormatErr if the format is not
+// recognised.
+func NewIDGenerator(format string) (IDGenerator, error) {
+	switch format {
+	case RandomIDFormat:
+		return RandomIDGenerator{}, nil
+	case UUIDFormat:
+		return UUIDGenerator{}, nil
+	default:
+		return nil, errors.WithMessage(UnknownIDFormatErr, format)
+	}
+}
+
+// SetIDGenerator replaces the IDGenerator used by New. Existing sessions keep their IDs, so lookups continue to work
+// for IDs issued in any earlier format.
+func SetIDGenerator(g IDGenerator) {
+	idGenerator = g
+}
@@ -0,0 +1,122 @@
+package session
+
+import (
+	"encoding/base64"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/google/uuid"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var base64URLPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{43}$`)
+
+func TestRandomIDGenerator_Generate(t *testing.T) {
+	Convey("Given a RandomIDGenerator", t, func() {
+		g := RandomIDGenerator{}
+
+		Convey("When an ID is generated", func() {
+			id, err := g.Generate()
+			So(err, ShouldBeNil)
+
+			Convey("Then it is unpadded base64url encoding 256 bits", func() {
+				So(base64URLPattern.MatchString(id), ShouldBeTrue)
+
+				b, err := base64.RawURLEncoding.DecodeString(id)
+				So(err, ShouldBeNil)
+				So(b, ShouldHaveLength, 32)
+			})
+		})
+
+		Convey("When many IDs are generated", func() {
+			ids := make(map[string]bool)
+			for i := 0; i < 10000; i++ {
+				id, err := g.Generate()
+				So(err, ShouldBeNil)
+				ids[id] = true
+			}
+
+			Convey("Then every ID is unique", func() {
+				So(ids, ShouldHaveLength, 10000)
+			})
+		})
+	})
+}
+
+func TestUUIDGenerator_Generate(t *testing.T) {
+	Convey("Given a UUIDGenerator", t, func() {
+		g := UUIDGenerator{}
+
+		Convey("When an ID is generated", func() {
+			id, err := g.Generate()
+			So(err, ShouldBeNil)
+
+			Convey("Then it is a version 4 UUID", func() {
+				parsed, err := uuid.Parse(id)
+				So(err, ShouldBeNil)
+				So(parsed.Version(), ShouldEqual, uuid.Version(4))
+			})
+		})
+
+		Convey("When many IDs are generated", func() {
+			ids := make(map[string]bool)
+			for i := 0; i < 10000; i++ {
+				id, err := g.Generate()
+				So(err, ShouldBeNil)
+				ids[id] = true
+			}
+
+			Convey("Then every ID is unique", func() {
+				So(ids, ShouldHaveLength, 10000)
+			})
+		})
+	})
+}
+
+func TestNewIDGenerator(t *testing.T) {
+	Convey("NewIDGenerator should return the generator for a known format", t, func() {
+		g, err := NewIDGenerator(RandomIDFormat)
+		So(err, ShouldBeNil)
+		So(g, ShouldHaveSameTypeAs, RandomIDGenerator{})
+
+		g, err = NewIDGenerator(UUIDFormat)
+		So(err, ShouldBeNil)
+		So(g, ShouldHaveSameTypeAs, UUIDGenerator{})
+	})
+
+	Convey("NewIDGenerator should return the expected error for an unknown format", t, func() {
+		g, err := NewIDGenerator("uuidv1")
+		So(g, ShouldBeNil)
+		So(errors.Is(err, UnknownIDFormatErr), ShouldBeTrue)
+	})
+}
+
+type stubIDGenerator struct {
+	id  string
+	err error
+}
+
+func (g stubIDGenerator) Generate() (string, error) {
+	return g.id, g.err
+}
+
+func TestNew_IDGenerator(t *testing.T) {
+	defer SetIDGenerator(RandomIDGenerator{})
+
+	Convey("New should use the configured IDGenerator", t, func() {
+		SetIDGenerator(stubIDGenerator{id: "abc"})
+
+		s, err := New("test@test.com")
+		So(err, ShouldBeNil)
+		So(s.ID, ShouldEqual, "abc")
+	})
+
+	Convey("New should return an error if an ID cannot be generated", t, func() {
+		SetIDGenerator(stubIDGenerator{err: errors.New("entropy exhausted")})
+
+		s, err := New("test@test.com")
+		So(s, ShouldBeNil)
+		So(err.Error(), ShouldEqual, "error generating new session ID: entropy exhausted")
+	})
+}
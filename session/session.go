@@ -3,7 +3,6 @@ package session
 import (
 "encoding/json"
 
-"github.com/google/uuid"
 "github.com/pkg/errors"
 
 "time"
@@ -19,24 +18,33 @@ var (
 	LastAccessedEmptyErr = errors.New("error unmarshalling session last accessed field required but was missing/empty")
 )
 
-// Session defines the structure required for a session
+// Session defines the structure required for a session. ExpiresAt is when the session will expire if it is not accessed
+// again and Deadline is when it will expire regardless of activity; both are set by the cache and Deadline is zero if
+// sessions have no max lifetime. Attributes holds arbitrary data services attach to the session.
 type Session struct {
-	ID           string    `json:"id"`
-	Email        string    `json:"email"`
-	Start        time.Time `json:"start"`
-	LastAccessed time.Time `json:"last_accessed"`
+	ID           string                 `json:"id"`
+	Email        string                 `json:"email"`
+	Start        time.Time              `json:"start"`
+	LastAccessed time.Time              `json:"last_accessed"`
+	ExpiresAt    time.Time              `json:"expires_at,omitempty"`
+	Deadline     time.Time              `json:"deadline,omitempty"`
+	Attributes   map[string]interface{} `json:"attributes,omitempty"`
 }
 
 // NewSessionDetails is the create HTTP request body required to creating new session
 type NewSessionDetails struct {
-	Email string `json:"email"`
+	Email      string                 `json:"email"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
 }
 
 type jsonModel struct {
-	ID           string `json:"id"`
-	Email        string `json:"email"`
-	Start        string `json:"start"`
-	LastAccessed string `json:"last_accessed"`
+	ID           string                 `json:"id"`
+	Email        string                 `json:"email"`
+	Start        string                 `json:"start"`
+	LastAccessed string                 `json:"last_accessed"`
+	ExpiresAt    string                 `json:"expires_at,omitempty"`
+	Deadline     string                 `json:"deadline,omitempty"`
+	Attributes   map[string]interface{} `json:"attributes,omitempty"`
 }
 
 //New construct a new fully populated session object for the provided email. Returns session.EmailEmptyErr if the email
@@ -46,7 +54,7 @@ func New(email string) (*Session, error) {
 		return nil, EmailEmptyErr
 	}
 
-	id, err := uuid.NewUUID()
+	id, err := idGenerator.Generate()
 	if err != nil {
 		return nil, errors.WithMessage(err, "error generating new session ID")
 	}
@@ -58,7 +66,7 @@ func New(email string) (*Session, error) {
 	}
 
 	return &Session{
-		ID:           id.String(),
+		ID:           id,
 		Email:        email,
 		Start:        createdAt,
 		LastAccessed: createdAt,
@@ -72,6 +80,9 @@ func (s *Session) MarshalJSON() ([]byte, error) {
 		Email:        s.Email,
 		Start:        s.Start.Format(DateTimeFMT),
 		LastAccessed: s.LastAccessed.Format(DateTimeFMT),
+		ExpiresAt:    formatOptionalTime(s.ExpiresAt),
+		Deadline:     formatOptionalTime(s.Deadline),
+		Attributes:   s.Attributes,
 	})
 }
 
@@ -103,10 +114,25 @@ func (s *Session) UnmarshalJSON(data []byte) error {
 		return errors.WithMessage(err, "error parsing session.LastAccessed as time.Time value")
 	}
 
+	var expiresAtT time.Time
+	expiresAtT, err = parseOptionalTime(raw.ExpiresAt)
+	if err != nil {
+		return errors.WithMessage(err, "error parsing session.ExpiresAt as time.Time value")
+	}
+
+	var deadlineT time.Time
+	deadlineT, err = parseOptionalTime(raw.Deadline)
+	if err != nil {
+		return errors.WithMessage(err, "error parsing session.Deadline as time.Time value")
+	}
+
 	s.ID = raw.ID
 	s.Email = raw.Email
 	s.Start = startT
 	s.LastAccessed = lastAccessedT
+	s.ExpiresAt = expiresAtT
+	s.Deadline = deadlineT
+	s.Attributes = raw.Attributes
 	return nil
 }
 
@@ -114,3 +140,19 @@ func FormatTime(t time.Time) (time.Time, error) {
 	// Format time t with the desired layout then parse it back to a time.Time object.
 	return time.Parse(DateTimeFMT, t.Format(DateTimeFMT))
 }
+
+// formatOptionalTime formats t in DateTimeFMT, returning an empty string for the zero time so it is omitted from JSON
+func formatOptionalTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(DateTimeFMT)
+}
+
+// parseOptionalTime parses a DateTimeFMT value, returning the zero time if the value is empty
+func parseOptionalTime(value string) (time.Time, error) {
+	if len(value) == 0 {
+		return time.Time{}, nil
+	}
+	return time.Parse(DateTimeFMT, value)
+}
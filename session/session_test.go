@@ -63,6 +63,57 @@ func TestSession_MarshalJSON(t *testing.T) {
 	})
 }
 
+func TestSession_MarshalJSON_Expiry(t *testing.T) {
+	Convey("Given a session with an expiry time and deadline", t, func() {
+		now := time.Now().UTC()
+		s := &Session{
+			ID:           "123",
+			Email:        "test@test.com",
+			Start:        now,
+			LastAccessed: now,
+			ExpiresAt:    now.Add(30 * time.Minute),
+			Deadline:     now.Add(12 * time.Hour),
+		}
+
+		Convey("When MarshalJSON is invoked", func() {
+			jsonBytes, err := s.MarshalJSON()
+			So(err, ShouldBeNil)
+
+			var jsonMap map[string]interface{}
+			err = json.Unmarshal(jsonBytes, &jsonMap)
+			So(err, ShouldBeNil)
+
+			Convey("Then the expiry fields are formatted in the expected date time format", func() {
+				assertJSONFieldValue("expires_at", now.Add(30*time.Minute).Format(DateTimeFMT), jsonMap)
+				assertJSONFieldValue("deadline", now.Add(12*time.Hour).Format(DateTimeFMT), jsonMap)
+			})
+		})
+	})
+
+	Convey("Given a session without an expiry time or deadline", t, func() {
+		s := &Session{
+			ID:           "123",
+			Email:        "test@test.com",
+			Start:        time.Now(),
+			LastAccessed: time.Now(),
+		}
+
+		Convey("When MarshalJSON is invoked", func() {
+			jsonBytes, err := s.MarshalJSON()
+			So(err, ShouldBeNil)
+
+			var jsonMap map[string]interface{}
+			err = json.Unmarshal(jsonBytes, &jsonMap)
+			So(err, ShouldBeNil)
+
+			Convey("Then the expiry fields are omitted", func() {
+				So(jsonMap, ShouldNotContainKey, "expires_at")
+				So(jsonMap, ShouldNotContainKey, "deadline")
+			})
+		})
+	})
+}
+
 func TestSession_UnmarshalJSON(t *testing.T) {
 	Convey("Should unmarshal valid session from JSON", t, func() {
 		input, err := New("test@ons.gov.uk")
@@ -78,6 +129,22 @@ func TestSession_UnmarshalJSON(t *testing.T) {
 		So(input, ShouldResemble, &output)
 	})
 
+	Convey("Should unmarshal the expiry fields of a session from JSON", t, func() {
+		input, err := New("test@ons.gov.uk")
+		So(err, ShouldBeNil)
+		input.ExpiresAt = input.LastAccessed.Add(30 * time.Minute)
+		input.Deadline = input.Start.Add(12 * time.Hour)
+
+		jsonBytes, err := json.Marshal(input)
+		So(err, ShouldBeNil)
+
+		var output Session
+		err = json.Unmarshal(jsonBytes, &output)
+		So(err, ShouldBeNil)
+
+		So(input, ShouldResemble, &output)
+	})
+
 	Convey("Should return expected error is session.Start is blank/empty ", t, func() {
 		input := `{"id":"123","email":"test@ons.gov.uk","last_accessed":"2021-02-02T11:51:48.300Z"}`
 